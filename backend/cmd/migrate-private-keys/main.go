@@ -0,0 +1,35 @@
+// Lệnh chạy một lần: mã hóa các private key còn lưu bản rõ (users.private_key_pem)
+// bằng DEK riêng + KEK hệ thống, rồi xóa bản rõ.
+//
+//	go run ./cmd/migrate-private-keys
+package main
+
+import (
+	"log"
+
+	"github.com/joho/godotenv"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/keystore"
+	"github.com/vnkmasc/KmaERM/backend/pkg/database"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found)")
+	}
+	gormDB, err := database.ConnectPostgres()
+	if err != nil {
+		log.Fatal("LỖI: Không thể kết nối CSDL:", err)
+	}
+
+	ks, err := keystore.New(gormDB)
+	if err != nil {
+		log.Fatal("LỖI: Không khởi tạo được keystore:", err)
+	}
+
+	n, err := ks.MigratePlaintext()
+	if err != nil {
+		log.Fatalf("LỖI: Đã mã hóa %d khóa trước khi gặp lỗi: %v", n, err)
+	}
+	log.Printf("Hoàn tất: đã mã hóa %d private key.", n)
+}
@@ -0,0 +1,35 @@
+// Lệnh xoay vòng KEK: sinh KEK mới, bọc lại toàn bộ DEK (khóa cán bộ, khóa CA) bằng KEK mới
+// và ngừng dùng KEK cũ. Private key không bị giải mã.
+//
+//	go run ./cmd/rotate-kek
+package main
+
+import (
+	"log"
+
+	"github.com/joho/godotenv"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/keystore"
+	"github.com/vnkmasc/KmaERM/backend/pkg/database"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found)")
+	}
+	gormDB, err := database.ConnectPostgres()
+	if err != nil {
+		log.Fatal("LỖI: Không thể kết nối CSDL:", err)
+	}
+
+	ks, err := keystore.New(gormDB)
+	if err != nil {
+		log.Fatal("LỖI: Không khởi tạo được keystore:", err)
+	}
+
+	version, n, err := ks.RotateKEK()
+	if err != nil {
+		log.Fatal("LỖI: Xoay vòng KEK thất bại, dữ liệu không thay đổi:", err)
+	}
+	log.Printf("Hoàn tất: KEK phiên bản %d đang hoạt động, đã bọc lại %d DEK.", version, n)
+}
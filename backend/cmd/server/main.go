@@ -12,10 +12,15 @@ import (
 	"github.com/joho/godotenv"
 
 	benchmark "github.com/vnkmasc/KmaERM/backend/internal/benchmark"
+	"github.com/vnkmasc/KmaERM/backend/internal/ca"
 	handler "github.com/vnkmasc/KmaERM/backend/internal/handlers"
+	"github.com/vnkmasc/KmaERM/backend/internal/keystore"
 	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
 	"github.com/vnkmasc/KmaERM/backend/internal/repository"
+	"github.com/vnkmasc/KmaERM/backend/internal/router"
 	"github.com/vnkmasc/KmaERM/backend/internal/service"
+	"github.com/vnkmasc/KmaERM/backend/internal/signer"
+	"github.com/vnkmasc/KmaERM/backend/internal/tsa"
 	"github.com/vnkmasc/KmaERM/backend/pkg/blockchain"
 	"github.com/vnkmasc/KmaERM/backend/pkg/database"
 )
@@ -86,17 +91,65 @@ func main() {
 	dnRepo := repository.NewDoanhNghiepRepository(gormDB)
 	hosoRepo := repository.NewHoSoRepository()
 	tailieuRepo := repository.NewTaiLieuRepository()
+	lichSuRepo := repository.NewHoSoLichSuRepository()
+	yeuCauBoSungRepo := repository.NewYeuCauBoSungRepository()
 	gpRepo := repository.NewGiayPhepRepository()
 	userRepo := repository.NewUserRepo(gormDB)
+	sessionRepo := repository.NewSessionRepository(gormDB)
+	throttleRepo := repository.NewThrottleRepository(gormDB)
+	pheDuyetRepo := repository.NewPheDuyetRepository()
+	lichLamViecRepo := repository.NewLichLamViecRepository()
+	thongKeRepo := repository.NewThongKeRepository()
+	phanCongRepo := repository.NewPhanCongRepository()
+	thuTucRepo := repository.NewThuTucRepository()
+	boDemSoHieuRepo := repository.NewBoDemSoHieuRepository()
+
+	// Kho khóa ký (mã hóa phong bì private key của cán bộ)
+	keyStore, err := keystore.New(gormDB)
+	if err != nil {
+		log.Fatal("LỖI: Không khởi tạo được keystore:", err)
+	}
+	if err := keyStore.EnsureKEK(); err != nil {
+		log.Fatal("LỖI: Không khởi tạo được KEK:", err)
+	}
+
+	// Signer: khóa trong CSDL hoặc trong HSM (PKCS#11) tùy cấu hình từng cán bộ
+	signerProvider := signer.NewProvider(keyStore, signer.PKCS11ConfigFromEnv())
+	defer signerProvider.Close()
+
+	// CA nội bộ cấp chứng thư X.509 cho khóa ký của cán bộ
+	caAuthority := ca.New(gormDB, keyStore)
+	if err := caAuthority.EnsureRoot(); err != nil {
+		log.Fatal("LỖI: Không khởi tạo được CA nội bộ:", err)
+	}
+
+	// Dấu thời gian RFC 3161 cho chữ ký số và lần đẩy lên blockchain (TSA nội bộ hoặc TSA_URL)
+	stamper, err := tsa.NewFromEnv(caAuthority)
+	if err != nil {
+		log.Fatal("LỖI: Không khởi tạo được dịch vụ dấu thời gian:", err)
+	}
 
 	// Service
+	// danhSo cấp mã hồ sơ, số giấy phép theo mẫu MAU_MA_HO_SO, MAU_SO_GIAY_PHEP
+	danhSo, err := service.NewDanhSoService(boDemSoHieuRepo)
+	if err != nil {
+		log.Fatal("LỖI: Mẫu số hiệu không hợp lệ:", err)
+	}
 	dnService := service.NewDoanhNghiepService(dnRepo, userRepo, gormDB)
-	hosoService := service.NewHoSoService(gormDB, hosoRepo, tailieuRepo)
+	// lichLamViecService tính ngày hẹn trả theo ngày làm việc khi tiếp nhận/nộp lại hồ sơ
+	lichLamViecService := service.NewLichLamViecService(gormDB, lichLamViecRepo, thuTucRepo)
+	hosoService := service.NewHoSoService(gormDB, hosoRepo, tailieuRepo, lichSuRepo, yeuCauBoSungRepo, lichLamViecService, phanCongRepo, thuTucRepo, danhSo)
+	thuTucService := service.NewThuTucService(gormDB, thuTucRepo, tailieuRepo)
+	thongKeService := service.NewThongKeService(gormDB, thongKeRepo, lichLamViecService)
 
-	// [THAY ĐỔI 1]: Thêm userRepo vào hàm khởi tạo GiayPhepService
-	gpService := service.NewGiayPhepService(gormDB, gpRepo, hosoRepo, userRepo, fabricClient)
+	chungThuService := service.NewChungThuService(gormDB, userRepo, signerProvider, caAuthority)
+	userService := service.NewUserService(gormDB, userRepo, sessionRepo, throttleRepo, keyStore, signerProvider, caAuthority)
 
-	userService := service.NewUserService(userRepo)
+	// [THAY ĐỔI 1]: Thêm userRepo vào hàm khởi tạo GiayPhepService
+	// userService xác nhận TOTP (step-up) trước khi ký số
+	// pheDuyetService chặn ký số khi giấy phép chưa qua đủ quy trình phê duyệt
+	pheDuyetService := service.NewPheDuyetService(gormDB, pheDuyetRepo, gpRepo, userRepo)
+	gpService := service.NewGiayPhepService(gormDB, gpRepo, hosoRepo, lichSuRepo, userRepo, fabricClient, userService, pheDuyetService, signerProvider, caAuthority, stamper, danhSo, thuTucRepo)
 
 	// Handler
 	dnHandler := handler.NewDoanhNghiepHandler(dnService)
@@ -104,23 +157,35 @@ func main() {
 	gpHandler := handler.NewGiayPhepHandler(gpService)
 	authHandler := handler.NewAuthHandler(userService)
 	canBoHandler := handler.NewCanBoHandler(userService)
-
-	apiGroup := r.Group("/api/v1")
+	chungThuHandler := handler.NewChungThuHandler(chungThuService)
+	pheDuyetHandler := handler.NewPheDuyetHandler(pheDuyetService)
+	lichLamViecHandler := handler.NewLichLamViecHandler(lichLamViecService)
+	thongKeHandler := handler.NewThongKeHandler(thongKeService)
+	thuTucHandler := handler.NewThuTucHandler(thuTucService)
 
 	// Middleware Auth được khởi tạo ở đây để tái sử dụng
 	authMiddleware := middleware.AuthMiddleware()
-
-	protectedGroup := apiGroup.Group("/")
-	protectedGroup.Use(authMiddleware)
-	{
-		authHandler.RegisterRoutes(apiGroup, protectedGroup)
-		dnHandler.RegisterRoutes(apiGroup)
-		hosoHandler.RegisterRoutes(apiGroup)
-
-		// [THAY ĐỔI 2]: Truyền thêm authMiddleware vào đây để route Ký số sử dụng
-		gpHandler.RegisterRoutes(apiGroup, authMiddleware)
-
-		canBoHandler.RegisterRoutes(apiGroup)
+	// Cache tên role cho middleware.RequireRole
+	middleware.InitRoleResolver(gormDB)
+	// Kiểm tra phiên/thu hồi token cho AuthMiddleware
+	middleware.InitSessionValidator(gormDB)
+
+	// Toàn bộ endpoint /api/v1 được khai báo (kèm mức truy cập) trong route registry
+	apiRoutes := router.NewRegistry("/api/v1")
+	apiRoutes.Add(authHandler.Routes()...)
+	apiRoutes.Add(dnHandler.Routes()...)
+	apiRoutes.Add(hosoHandler.Routes()...)
+	apiRoutes.Add(gpHandler.Routes()...)
+	apiRoutes.Add(canBoHandler.Routes()...)
+	apiRoutes.Add(chungThuHandler.Routes()...)
+	apiRoutes.Add(pheDuyetHandler.Routes()...)
+	apiRoutes.Add(lichLamViecHandler.Routes()...)
+	apiRoutes.Add(thongKeHandler.Routes()...)
+	apiRoutes.Add(thuTucHandler.Routes()...)
+
+	// Self-check: dừng server nếu có endpoint ghi nào không yêu cầu đăng nhập
+	if err := apiRoutes.Mount(r, authMiddleware); err != nil {
+		log.Fatalf("LỖI: Cấu hình route không an toàn:\n%v", err)
 	}
 
 	r.GET("/", func(c *gin.Context) {
@@ -24,4 +24,5 @@ func main() {
 
 	fmt.Println("--- COPY CÁI NÀY VÀO SQL ---")
 	fmt.Printf("UPDATE users SET private_key_pem = '%s', public_key_pem = '%s' WHERE email = 'admin@system.com';\n", string(privPEM), string(pubPEM))
+	fmt.Println("--- SAU ĐÓ CHẠY: go run ./cmd/migrate-private-keys (mã hóa và xóa bản rõ) ---")
 }
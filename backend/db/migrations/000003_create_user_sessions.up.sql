@@ -0,0 +1,18 @@
+-- Phiên đăng nhập: mỗi refresh token (đã băm) ứng với một thiết bị
+CREATE TABLE user_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA-256 hex, không lưu token gốc
+    user_agent TEXT,
+    ip_address VARCHAR(64),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    replaced_by UUID NULL REFERENCES user_sessions(id) ON DELETE SET NULL, -- Phiên mới sau khi xoay vòng token
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_us_user_id ON user_sessions(user_id);
+
+-- Mọi access token phát hành TRƯỚC mốc này đều bị từ chối (đổi mật khẩu, khóa tài khoản, đăng xuất mọi thiết bị)
+ALTER TABLE users
+ADD COLUMN tokens_valid_after TIMESTAMPTZ;
@@ -0,0 +1,15 @@
+-- Xác thực 2 lớp (TOTP - RFC 6238). Secret được mã hóa AES-GCM bằng master key trước khi lưu.
+ALTER TABLE users
+ADD COLUMN totp_secret_enc TEXT,
+ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN totp_last_step BIGINT; -- Bước thời gian của mã đã dùng gần nhất, chống dùng lại mã
+
+-- Mã khôi phục dùng một lần (chỉ lưu hash SHA-256)
+CREATE TABLE user_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE users
+DROP COLUMN IF EXISTS otp_failed_count;
+
+DROP TABLE IF EXISTS auth_throttles CASCADE;
@@ -0,0 +1,14 @@
+-- Bộ đếm đăng nhập/OTP sai để chống dò mật khẩu (brute-force)
+-- scope: 'login' | 'otp' (subject = email) hoặc 'ip' (subject = địa chỉ IP)
+CREATE TABLE auth_throttles (
+    scope VARCHAR(20) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    fail_count INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ,
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (scope, subject)
+);
+
+-- Số lần nhập sai OTP quên mật khẩu hiện tại; vượt ngưỡng thì OTP bị hủy
+ALTER TABLE users
+ADD COLUMN otp_failed_count INT NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+-- Lưu ý: chạy down sau khi đã migrate sẽ MẤT khóa ký (bản rõ đã bị xóa)
+ALTER TABLE users
+DROP COLUMN IF EXISTS kek_version,
+DROP COLUMN IF EXISTS dek_enc,
+DROP COLUMN IF EXISTS private_key_enc;
+
+DROP TABLE IF EXISTS system_keks CASCADE;
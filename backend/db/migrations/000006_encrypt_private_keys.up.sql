@@ -0,0 +1,22 @@
+-- Mã hóa phong bì (envelope encryption) cho khóa ký của cán bộ:
+--   private key --(AES-GCM, DEK riêng từng user)--> users.private_key_enc
+--   DEK         --(AES-GCM, KEK)------------------> users.dek_enc
+--   KEK         --(AES-GCM, APP_MASTER_KEY)--------> system_keks.encrypted_kek
+-- Xoay vòng KEK chỉ cần mã hóa lại DEK, không đụng tới private key.
+CREATE TABLE system_keks (
+    version SERIAL PRIMARY KEY,
+    encrypted_kek TEXT NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    retired_at TIMESTAMPTZ NULL
+);
+
+-- Luôn chỉ có tối đa 1 KEK đang hoạt động
+CREATE UNIQUE INDEX idx_system_keks_active ON system_keks(is_active) WHERE is_active;
+
+ALTER TABLE users
+ADD COLUMN private_key_enc TEXT,
+ADD COLUMN dek_enc TEXT,
+ADD COLUMN kek_version INT REFERENCES system_keks(version);
+
+-- private_key_pem (bản rõ) được xóa bởi lệnh: go run ./cmd/migrate-private-keys
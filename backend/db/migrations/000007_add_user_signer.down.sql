@@ -0,0 +1,3 @@
+ALTER TABLE users
+DROP COLUMN IF EXISTS hsm_key_label,
+DROP COLUMN IF EXISTS signer_type;
@@ -0,0 +1,4 @@
+-- Nơi giữ khóa ký của từng cán bộ: 'db' (private_key_enc, xem keystore) hoặc 'pkcs11' (HSM/SoftHSM)
+ALTER TABLE users
+ADD COLUMN signer_type VARCHAR(20) NOT NULL DEFAULT 'db' CHECK (signer_type IN ('db', 'pkcs11')),
+ADD COLUMN hsm_key_label VARCHAR(255); -- CKA_LABEL của cặp khóa trên token PKCS#11
@@ -0,0 +1,5 @@
+ALTER TABLE giay_phep
+DROP COLUMN IF EXISTS chung_thu_id;
+
+DROP TABLE IF EXISTS chung_thu_so CASCADE;
+DROP TABLE IF EXISTS certificate_authorities CASCADE;
@@ -0,0 +1,38 @@
+-- CA nội bộ: cấp chứng thư số X.509 cho cán bộ. Private key của CA được mã hóa phong bì như khóa cán bộ.
+CREATE TABLE certificate_authorities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ten TEXT NOT NULL,
+    cert_pem TEXT NOT NULL,
+    private_key_enc TEXT NOT NULL,
+    dek_enc TEXT NOT NULL,
+    kek_version INT NOT NULL REFERENCES system_keks(version),
+    crl_number BIGINT NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_ca_active ON certificate_authorities(is_active) WHERE is_active;
+
+-- Chứng thư số đã cấp cho cán bộ
+CREATE TABLE chung_thu_so (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ca_id UUID NOT NULL REFERENCES certificate_authorities(id),
+    serial_number VARCHAR(64) NOT NULL UNIQUE, -- hex
+    subject_cn TEXT NOT NULL,
+    email VARCHAR(255),
+    cert_pem TEXT NOT NULL,
+    chain_pem TEXT NOT NULL, -- Chuỗi chứng thư của CA phát hành (không gồm chứng thư lá)
+    public_key_fingerprint VARCHAR(64) NOT NULL,
+    not_before TIMESTAMPTZ NOT NULL,
+    not_after TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    ly_do_thu_hoi TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_cts_user_id ON chung_thu_so(user_id);
+
+-- Chứng thư đã dùng để ký giấy phép
+ALTER TABLE giay_phep
+ADD COLUMN chung_thu_id UUID REFERENCES chung_thu_so(id);
@@ -0,0 +1,3 @@
+ALTER TABLE giay_phep
+DROP COLUMN IF EXISTS h2_hash_goc,
+DROP COLUMN IF EXISTS file_goc_duong_dan;
@@ -0,0 +1,5 @@
+-- Ký PAdES tạo file PDF mới có chữ ký nhúng; giữ lại bản gốc chưa ký và hash của nó.
+-- file_duong_dan / h2_hash luôn trỏ tới bản đang phát hành (sau khi ký là bản đã ký).
+ALTER TABLE giay_phep
+ADD COLUMN file_goc_duong_dan TEXT,
+ADD COLUMN h2_hash_goc TEXT;
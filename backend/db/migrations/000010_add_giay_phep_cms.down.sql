@@ -0,0 +1,2 @@
+ALTER TABLE giay_phep
+DROP COLUMN IF EXISTS chu_ky_cms;
@@ -0,0 +1,3 @@
+-- Chữ ký CMS/PKCS#7 detached (DER, base64) trên file giấy phép đang phát hành, xuất ra dạng .p7s
+ALTER TABLE giay_phep
+ADD COLUMN chu_ky_cms TEXT;
@@ -0,0 +1,11 @@
+ALTER TABLE giay_phep
+DROP COLUMN IF EXISTS dau_thoi_gian_blockchain,
+DROP COLUMN IF EXISTS dau_thoi_gian_ky;
+
+DELETE FROM certificate_authorities WHERE loai <> 'ca';
+
+DROP INDEX IF EXISTS idx_ca_active;
+CREATE UNIQUE INDEX idx_ca_active ON certificate_authorities(is_active) WHERE is_active;
+
+ALTER TABLE certificate_authorities
+DROP COLUMN IF EXISTS loai;
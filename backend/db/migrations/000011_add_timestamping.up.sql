@@ -0,0 +1,11 @@
+-- Khóa + chứng thư của TSA nội bộ (RFC 3161) lưu chung bảng với CA, phân biệt bằng cột loai
+ALTER TABLE certificate_authorities
+ADD COLUMN loai VARCHAR(10) NOT NULL DEFAULT 'ca';
+
+DROP INDEX IF EXISTS idx_ca_active;
+CREATE UNIQUE INDEX idx_ca_active ON certificate_authorities(loai) WHERE is_active;
+
+-- Dấu thời gian RFC 3161 (TimeStampToken DER, base64) cho chữ ký số và cho lần đẩy lên blockchain
+ALTER TABLE giay_phep
+ADD COLUMN dau_thoi_gian_ky TEXT,
+ADD COLUMN dau_thoi_gian_blockchain TEXT;
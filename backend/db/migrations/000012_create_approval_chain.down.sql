@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS phe_duyet_giay_phep;
+DROP TABLE IF EXISTS buoc_duyet;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS chuc_vu;
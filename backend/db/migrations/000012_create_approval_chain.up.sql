@@ -0,0 +1,30 @@
+-- Chức vụ của cán bộ trong quy trình phê duyệt (ví dụ CHUYEN_VIEN, TRUONG_PHONG, LANH_DAO)
+ALTER TABLE users
+ADD COLUMN chuc_vu VARCHAR(50) NULL;
+
+-- Quy trình phê duyệt theo loại giấy phép: các bước theo thứ tự, bước cuối cùng là bước ký số
+CREATE TABLE buoc_duyet (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    loai_giay_phep VARCHAR(100) NOT NULL,
+    thu_tu INT NOT NULL CHECK (thu_tu > 0),
+    ten_buoc TEXT NOT NULL,
+    chuc_vu VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (loai_giay_phep, thu_tu)
+);
+
+-- Lịch sử phê duyệt của từng giấy phép. Thông tin bước được chụp lại để sửa quy trình không làm sai lịch sử.
+CREATE TABLE phe_duyet_giay_phep (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    giay_phep_id UUID NOT NULL REFERENCES giay_phep(id) ON DELETE CASCADE,
+    thu_tu INT NOT NULL,
+    ten_buoc TEXT NOT NULL,
+    chuc_vu VARCHAR(50) NOT NULL,
+    nguoi_duyet_id UUID NOT NULL REFERENCES users(id),
+    quyet_dinh VARCHAR(20) NOT NULL CHECK (quyet_dinh IN ('DongY', 'TuChoi')),
+    y_kien TEXT,
+    h2_hash TEXT NOT NULL, -- File giấy phép tại thời điểm duyệt; thay file thì các lượt duyệt cũ hết giá trị
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_pdgp_giay_phep_id ON phe_duyet_giay_phep(giay_phep_id, created_at);
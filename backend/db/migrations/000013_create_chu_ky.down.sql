@@ -0,0 +1,28 @@
+ALTER TABLE giay_phep
+ADD COLUMN chu_ky_so TEXT,
+ADD COLUMN nguoi_ky_id UUID REFERENCES users(id),
+ADD COLUMN ngay_ky TIMESTAMPTZ,
+ADD COLUMN public_key_nguoi_ky TEXT,
+ADD COLUMN chung_thu_id UUID REFERENCES chung_thu_so(id),
+ADD COLUMN chu_ky_cms TEXT,
+ADD COLUMN dau_thoi_gian_ky TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_gp_nguoi_ky_id ON giay_phep(nguoi_ky_id);
+
+-- Chỉ giữ lại được chữ ký cuối cùng của mỗi giấy phép
+UPDATE giay_phep gp
+SET chu_ky_so = ck.chu_ky_so,
+    nguoi_ky_id = ck.nguoi_ky_id,
+    ngay_ky = ck.ngay_ky,
+    public_key_nguoi_ky = ck.public_key_nguoi_ky,
+    chung_thu_id = ck.chung_thu_id,
+    chu_ky_cms = ck.chu_ky_cms,
+    dau_thoi_gian_ky = ck.dau_thoi_gian
+FROM (
+    SELECT DISTINCT ON (giay_phep_id) *
+    FROM chu_ky
+    ORDER BY giay_phep_id, thu_tu DESC
+) ck
+WHERE ck.giay_phep_id = gp.id;
+
+DROP TABLE IF EXISTS chu_ky;
@@ -0,0 +1,37 @@
+-- Mỗi giấy phép có thể mang nhiều chữ ký theo thứ tự (ví dụ ký nháy của chuyên viên rồi chữ ký chính của lãnh đạo)
+CREATE TABLE chu_ky (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    giay_phep_id UUID NOT NULL REFERENCES giay_phep(id) ON DELETE CASCADE,
+    thu_tu INT NOT NULL,
+    loai_chu_ky VARCHAR(20) NOT NULL DEFAULT 'KyChinh' CHECK (loai_chu_ky IN ('KyNhay', 'KyChinh')),
+    nguoi_ky_id UUID NOT NULL REFERENCES users(id),
+    ngay_ky TIMESTAMPTZ NOT NULL,
+    -- Hash file phát hành ngay sau chữ ký này (với PDF là bản sửa đổi mà chữ ký PAdES phủ)
+    h2_hash TEXT NOT NULL,
+    chu_ky_so TEXT NOT NULL, -- RSA PKCS#1 v1.5 trên SHA-256(h2_hash dạng hex), base64
+    public_key_nguoi_ky TEXT,
+    chung_thu_id UUID REFERENCES chung_thu_so(id),
+    chu_ky_cms TEXT,     -- CMS detached (DER, base64) trên file phát hành ngay sau chữ ký này
+    dau_thoi_gian TEXT,  -- TimeStampToken RFC 3161 trên chu_ky_so (DER, base64)
+    nhung_pdf BOOLEAN NOT NULL DEFAULT FALSE, -- Có chữ ký PAdES nhúng trong file PDF
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (giay_phep_id, thu_tu)
+);
+
+CREATE INDEX idx_chu_ky_nguoi_ky_id ON chu_ky(nguoi_ky_id);
+
+INSERT INTO chu_ky (giay_phep_id, thu_tu, loai_chu_ky, nguoi_ky_id, ngay_ky, h2_hash, chu_ky_so,
+                    public_key_nguoi_ky, chung_thu_id, chu_ky_cms, dau_thoi_gian, nhung_pdf)
+SELECT id, 1, 'KyChinh', nguoi_ky_id, COALESCE(ngay_ky, updated_at, NOW()), h2_hash, chu_ky_so,
+       public_key_nguoi_ky, chung_thu_id, chu_ky_cms, dau_thoi_gian_ky, file_goc_duong_dan IS NOT NULL
+FROM giay_phep
+WHERE chu_ky_so IS NOT NULL AND nguoi_ky_id IS NOT NULL AND h2_hash IS NOT NULL;
+
+ALTER TABLE giay_phep
+DROP COLUMN chu_ky_so,
+DROP COLUMN nguoi_ky_id,
+DROP COLUMN ngay_ky,
+DROP COLUMN public_key_nguoi_ky,
+DROP COLUMN chung_thu_id,
+DROP COLUMN chu_ky_cms,
+DROP COLUMN dau_thoi_gian_ky;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_ho_so_trang_thai;
+
+ALTER TABLE ho_so
+DROP COLUMN IF EXISTS ly_do_tra_lai;
@@ -0,0 +1,5 @@
+-- Lý do cán bộ trả lại hồ sơ gần nhất (hiển thị cho doanh nghiệp)
+ALTER TABLE ho_so
+ADD COLUMN ly_do_tra_lai TEXT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_ho_so_trang_thai ON ho_so(trang_thai_ho_so);
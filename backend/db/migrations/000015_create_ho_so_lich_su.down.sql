@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ho_so_lich_su;
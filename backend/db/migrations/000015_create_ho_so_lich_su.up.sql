@@ -0,0 +1,15 @@
+-- Nhật ký xử lý hồ sơ: mỗi sự kiện (tạo, đổi trạng thái, sửa thông tin, nộp/xóa tài liệu, cấp giấy phép) là một dòng
+CREATE TABLE ho_so_lich_su (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ho_so_id UUID NOT NULL REFERENCES ho_so(id) ON DELETE CASCADE,
+    loai_su_kien VARCHAR(50) NOT NULL,
+    nguoi_thuc_hien_id UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL: hệ thống tự thực hiện
+    trang_thai_truoc VARCHAR(50),
+    trang_thai_sau VARCHAR(50),
+    du_lieu_truoc JSONB,
+    du_lieu_sau JSONB,
+    ghi_chu TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_ho_so_lich_su_ho_so ON ho_so_lich_su(ho_so_id, created_at);
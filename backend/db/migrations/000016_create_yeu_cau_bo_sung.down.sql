@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS yeu_cau_bo_sung_tai_lieu;
+DROP TABLE IF EXISTS yeu_cau_bo_sung;
+
+ALTER TABLE ho_so
+DROP COLUMN IF EXISTS so_lan_bo_sung;
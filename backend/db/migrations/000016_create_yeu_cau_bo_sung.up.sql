@@ -0,0 +1,30 @@
+-- Số vòng yêu cầu bổ sung hồ sơ đã trải qua
+ALTER TABLE ho_so
+ADD COLUMN so_lan_bo_sung INT NOT NULL DEFAULT 0;
+
+-- Mỗi lần cán bộ trả lại hồ sơ là một vòng yêu cầu bổ sung
+CREATE TABLE yeu_cau_bo_sung (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ho_so_id UUID NOT NULL REFERENCES ho_so(id) ON DELETE CASCADE,
+    lan INT NOT NULL,
+    nguoi_yeu_cau_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    noi_dung TEXT NOT NULL,
+    trang_thai VARCHAR(20) NOT NULL DEFAULT 'ChoBoSung' CHECK (trang_thai IN ('ChoBoSung', 'DaBoSung')),
+    ngay_nop_lai TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (ho_so_id, lan)
+);
+
+-- Mỗi hồ sơ chỉ có tối đa một vòng đang chờ bổ sung
+CREATE UNIQUE INDEX idx_yeu_cau_bo_sung_dang_mo ON yeu_cau_bo_sung(ho_so_id) WHERE trang_thai = 'ChoBoSung';
+
+-- Các khe tài liệu bị đánh dấu thiếu/không đạt trong một vòng, kèm lý do
+CREATE TABLE yeu_cau_bo_sung_tai_lieu (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    yeu_cau_bo_sung_id UUID NOT NULL REFERENCES yeu_cau_bo_sung(id) ON DELETE CASCADE,
+    ho_so_tai_lieu_id UUID NOT NULL REFERENCES ho_so_tai_lieu(id) ON DELETE CASCADE,
+    ly_do TEXT NOT NULL,
+    da_bo_sung BOOLEAN NOT NULL DEFAULT FALSE,
+    ngay_bo_sung TIMESTAMPTZ,
+    UNIQUE (yeu_cau_bo_sung_id, ho_so_tai_lieu_id)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE ho_so
+DROP COLUMN IF EXISTS so_ngay_tam_dung,
+DROP COLUMN IF EXISTS ngay_tam_dung;
+
+DROP TABLE IF EXISTS thoi_han_xu_ly;
+DROP TABLE IF EXISTS ngay_nghi_le;
@@ -0,0 +1,49 @@
+-- Ngày nghỉ lễ, Tết do quản trị viên duy trì hằng năm. Thứ Bảy, Chủ nhật mặc định là ngày nghỉ;
+-- la_ngay_lam_bu = TRUE đánh dấu ngày cuối tuần phải đi làm bù khi hoán đổi ngày nghỉ.
+CREATE TABLE ngay_nghi_le (
+    ngay DATE PRIMARY KEY,
+    ten TEXT NOT NULL,
+    la_ngay_lam_bu BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Thời hạn giải quyết (ngày làm việc, không tính ngày tiếp nhận) theo loại thủ tục
+CREATE TABLE thoi_han_xu_ly (
+    loai_thu_tuc VARCHAR(255) PRIMARY KEY,
+    so_ngay_lam_viec INT NOT NULL CHECK (so_ngay_lam_viec > 0),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Đồng hồ thời hạn dừng khi hồ sơ bị trả lại: ngay_tam_dung là lúc bắt đầu lần dừng hiện tại,
+-- so_ngay_tam_dung là tổng số ngày làm việc đã dừng và được cộng thêm vào hạn trả
+ALTER TABLE ho_so
+ADD COLUMN ngay_tam_dung TIMESTAMPTZ NULL,
+ADD COLUMN so_ngay_tam_dung INT NOT NULL DEFAULT 0;
+
+INSERT INTO thoi_han_xu_ly (loai_thu_tuc, so_ngay_lam_viec) VALUES
+    ('Cấp mới Giấy phép kinh doanh', 30),
+    ('Sửa đổi, bổ sung Giấy phép kinh doanh', 10),
+    ('Gia hạn Giấy phép kinh doanh', 10),
+    ('Cấp lại Giấy phép kinh doanh', 10),
+    ('Cấp Giấy phép xuất khẩu, nhập khẩu', 10),
+    ('Báo cáo hoạt động định kỳ', 10);
+
+INSERT INTO ngay_nghi_le (ngay, ten)
+SELECT d::date, 'Tết Nguyên đán' FROM generate_series('2025-01-25'::date, '2025-02-02'::date, '1 day') AS d
+UNION ALL
+SELECT d::date, 'Tết Nguyên đán' FROM generate_series('2026-02-14'::date, '2026-02-22'::date, '1 day') AS d;
+
+INSERT INTO ngay_nghi_le (ngay, ten) VALUES
+    ('2025-01-01', 'Tết Dương lịch'),
+    ('2025-04-07', 'Giỗ Tổ Hùng Vương'),
+    ('2025-04-30', 'Ngày Chiến thắng'),
+    ('2025-05-01', 'Ngày Quốc tế Lao động'),
+    ('2025-09-01', 'Quốc khánh'),
+    ('2025-09-02', 'Quốc khánh'),
+    ('2026-01-01', 'Tết Dương lịch'),
+    ('2026-04-27', 'Nghỉ bù Giỗ Tổ Hùng Vương'),
+    ('2026-04-30', 'Ngày Chiến thắng'),
+    ('2026-05-01', 'Ngày Quốc tế Lao động'),
+    ('2026-09-01', 'Quốc khánh'),
+    ('2026-09-02', 'Quốc khánh');
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_ho_so_lich_su_tiep_nhan;
+DROP INDEX IF EXISTS idx_ho_so_dang_giai_quyet_han_tra;
@@ -0,0 +1,7 @@
+-- Thống kê SLA và danh sách quá hạn chỉ quét hồ sơ đang giải quyết theo hạn trả
+CREATE INDEX idx_ho_so_dang_giai_quyet_han_tra ON ho_so(ngay_hen_tra)
+WHERE trang_thai_ho_so IN ('DaTiepNhan', 'DangXuLy', 'BiTraLai');
+
+-- Tra nhanh lần tiếp nhận gần nhất của hồ sơ (cán bộ phụ trách)
+CREATE INDEX idx_ho_so_lich_su_tiep_nhan ON ho_so_lich_su(ho_so_id, created_at DESC)
+WHERE loai_su_kien = 'ChuyenTrangThai' AND du_lieu_sau->>'thao_tac' = 'tiep-nhan';
@@ -0,0 +1,8 @@
+CREATE INDEX idx_ho_so_lich_su_tiep_nhan ON ho_so_lich_su(ho_so_id, created_at DESC)
+WHERE loai_su_kien = 'ChuyenTrangThai' AND du_lieu_sau->>'thao_tac' = 'tiep-nhan';
+
+DROP INDEX IF EXISTS idx_ho_so_can_bo_xu_ly;
+
+ALTER TABLE ho_so
+DROP COLUMN IF EXISTS ngay_phan_cong,
+DROP COLUMN IF EXISTS can_bo_xu_ly_id;
@@ -0,0 +1,24 @@
+-- Cán bộ được phân công xử lý hồ sơ
+ALTER TABLE ho_so
+ADD COLUMN can_bo_xu_ly_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+ADD COLUMN ngay_phan_cong TIMESTAMPTZ NULL;
+
+-- Hàng đợi của từng cán bộ sắp theo hạn trả; đếm tải khi phân công tự động
+CREATE INDEX idx_ho_so_can_bo_xu_ly ON ho_so(can_bo_xu_ly_id, ngay_hen_tra);
+
+-- Hồ sơ đang giải quyết: người tiếp nhận gần nhất được coi là cán bộ xử lý
+UPDATE ho_so hs
+SET can_bo_xu_ly_id = pt.nguoi_thuc_hien_id,
+    ngay_phan_cong = pt.created_at
+FROM (
+    SELECT DISTINCT ON (ls.ho_so_id) ls.ho_so_id, ls.nguoi_thuc_hien_id, ls.created_at
+    FROM ho_so_lich_su ls
+    WHERE ls.loai_su_kien = 'ChuyenTrangThai' AND ls.du_lieu_sau->>'thao_tac' = 'tiep-nhan'
+    ORDER BY ls.ho_so_id, ls.created_at DESC
+) pt
+WHERE pt.ho_so_id = hs.id
+  AND pt.nguoi_thuc_hien_id IS NOT NULL
+  AND hs.trang_thai_ho_so IN ('DaTiepNhan', 'DangXuLy', 'BiTraLai');
+
+-- Thống kê SLA dùng can_bo_xu_ly_id thay cho việc tra nhật ký tiếp nhận
+DROP INDEX IF EXISTS idx_ho_so_lich_su_tiep_nhan;
@@ -0,0 +1,14 @@
+CREATE TABLE thoi_han_xu_ly (
+    loai_thu_tuc VARCHAR(255) PRIMARY KEY,
+    so_ngay_lam_viec INT NOT NULL CHECK (so_ngay_lam_viec > 0),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+INSERT INTO thoi_han_xu_ly (loai_thu_tuc, so_ngay_lam_viec)
+SELECT ten, so_ngay_lam_viec FROM thu_tuc;
+
+ALTER TABLE ho_so_tai_lieu
+DROP COLUMN IF EXISTS bat_buoc;
+
+DROP TABLE IF EXISTS thu_tuc_tai_lieu;
+DROP TABLE IF EXISTS thu_tuc;
@@ -0,0 +1,68 @@
+-- Danh mục thủ tục hành chính: thời hạn giải quyết (ngày làm việc), loại giấy phép được cấp khi duyệt
+-- (NULL: thủ tục không cấp giấy phép, ví dụ báo cáo định kỳ). ho_so.loai_thu_tuc lưu tên thủ tục.
+CREATE TABLE thu_tuc (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ten VARCHAR(255) NOT NULL UNIQUE,
+    mo_ta TEXT,
+    so_ngay_lam_viec INT NOT NULL CHECK (so_ngay_lam_viec > 0),
+    loai_giay_phep VARCHAR(100),
+    hoat_dong BOOLEAN NOT NULL DEFAULT TRUE, -- Ngừng hoạt động: không tạo được hồ sơ mới
+    thu_tu_hien_thi INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Thành phần hồ sơ của từng thủ tục
+CREATE TABLE thu_tuc_tai_lieu (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    thu_tuc_id UUID NOT NULL REFERENCES thu_tuc(id) ON DELETE CASCADE,
+    loai_tai_lieu_id UUID NOT NULL REFERENCES loai_tai_lieu(id) ON DELETE RESTRICT,
+    bat_buoc BOOLEAN NOT NULL DEFAULT TRUE,
+    thu_tu INT NOT NULL DEFAULT 0,
+    UNIQUE (thu_tuc_id, loai_tai_lieu_id)
+);
+
+-- Khe tài liệu ghi lại tính bắt buộc tại thời điểm tạo hồ sơ, sửa danh mục không ảnh hưởng hồ sơ cũ
+ALTER TABLE ho_so_tai_lieu
+ADD COLUMN bat_buoc BOOLEAN NOT NULL DEFAULT TRUE;
+
+INSERT INTO thu_tuc (ten, so_ngay_lam_viec, loai_giay_phep, thu_tu_hien_thi)
+SELECT v.ten, COALESCE(th.so_ngay_lam_viec, v.so_ngay), v.loai_giay_phep, v.thu_tu
+FROM (VALUES
+    ('Cấp mới Giấy phép kinh doanh', 30, 'Giấy phép kinh doanh', 1),
+    ('Sửa đổi, bổ sung Giấy phép kinh doanh', 10, 'Giấy phép kinh doanh', 2),
+    ('Gia hạn Giấy phép kinh doanh', 10, 'Giấy phép kinh doanh', 3),
+    ('Cấp lại Giấy phép kinh doanh', 10, 'Giấy phép kinh doanh', 4),
+    ('Cấp Giấy phép xuất khẩu, nhập khẩu', 10, 'Giấy phép xuất khẩu, nhập khẩu', 5),
+    ('Báo cáo hoạt động định kỳ', 10, NULL, 6)
+) AS v(ten, so_ngay, loai_giay_phep, thu_tu)
+LEFT JOIN thoi_han_xu_ly th ON th.loai_thu_tuc = v.ten;
+
+INSERT INTO thu_tuc_tai_lieu (thu_tuc_id, loai_tai_lieu_id, thu_tu)
+SELECT tt.id, ltl.id, v.thu_tu
+FROM (VALUES
+    ('Cấp mới Giấy phép kinh doanh', 'Đơn đề nghị cấp Giấy phép kinh doanh', 1),
+    ('Cấp mới Giấy phép kinh doanh', 'Giấy chứng nhận đăng ký doanh nghiệp', 2),
+    ('Cấp mới Giấy phép kinh doanh', 'Danh sách đội ngũ kĩ thuật và văn bằng', 3),
+    ('Cấp mới Giấy phép kinh doanh', 'Phương án kinh doanh', 4),
+    ('Cấp mới Giấy phép kinh doanh', 'Phương án bảo mật và an toàn thông tin mạng', 5),
+    ('Cấp mới Giấy phép kinh doanh', 'Phương án kỹ thuật và Phương án bảo hành bảo trì', 6),
+    ('Cấp mới Giấy phép kinh doanh', 'Tài liệu kĩ thuật', 7),
+    ('Cấp mới Giấy phép kinh doanh', 'Giấy chứng nhận hợp quy', 8),
+    ('Sửa đổi, bổ sung Giấy phép kinh doanh', 'Đơn đề nghị cấp sửa đổi, bổ sung Giấy phép kinh doanh', 1),
+    ('Sửa đổi, bổ sung Giấy phép kinh doanh', 'Giấy phép kinh doanh sản phẩm, dịch vụ mật mã dân sự', 2),
+    ('Gia hạn Giấy phép kinh doanh', 'Đơn đề nghị gia hạn Giấy phép kinh doanh', 1),
+    ('Gia hạn Giấy phép kinh doanh', 'Giấy phép kinh doanh sản phẩm, dịch vụ mật mã dân sự', 2),
+    ('Gia hạn Giấy phép kinh doanh', 'Báo cáo hoạt động của doanh nghiệp', 3),
+    ('Cấp lại Giấy phép kinh doanh', 'Đơn đề nghị cấp lại Giấy phép kinh doanh', 1),
+    ('Cấp Giấy phép xuất khẩu, nhập khẩu', 'Đơn đề nghị cấp Giấy phép xuất khẩu, nhập khẩu', 1),
+    ('Cấp Giấy phép xuất khẩu, nhập khẩu', 'Giấy phép kinh doanh sản phẩm, dịch vụ mật mã dân sự', 2),
+    ('Cấp Giấy phép xuất khẩu, nhập khẩu', 'Tài liệu kĩ thuật', 3),
+    ('Cấp Giấy phép xuất khẩu, nhập khẩu', 'Giấy chứng nhận hợp quy', 4),
+    ('Báo cáo hoạt động định kỳ', 'Báo cáo hoạt động của doanh nghiệp', 1)
+) AS v(thu_tuc, loai_tai_lieu, thu_tu)
+JOIN thu_tuc tt ON tt.ten = v.thu_tuc
+JOIN loai_tai_lieu ltl ON ltl.ten = v.loai_tai_lieu;
+
+-- Thời hạn giải quyết chuyển vào danh mục thủ tục
+DROP TABLE thoi_han_xu_ly;
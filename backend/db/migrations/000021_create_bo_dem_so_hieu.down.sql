@@ -0,0 +1 @@
+DROP TABLE IF EXISTS bo_dem_so_hieu;
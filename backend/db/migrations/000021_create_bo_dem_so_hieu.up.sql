@@ -0,0 +1,10 @@
+-- Bộ đếm số hiệu theo từng loại và từng năm (mã hồ sơ, số giấy phép).
+-- Không dùng SEQUENCE vì nextval không hoàn lại khi transaction rollback, gây lỗ hổng trong dãy số;
+-- dòng bộ đếm được cập nhật trong transaction nghiệp vụ nên số chỉ được dùng khi transaction commit.
+CREATE TABLE bo_dem_so_hieu (
+    loai VARCHAR(50) NOT NULL,
+    nam INT NOT NULL,
+    gia_tri BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (loai, nam)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE thu_tuc
+DROP COLUMN IF EXISTS so_thang_hieu_luc;
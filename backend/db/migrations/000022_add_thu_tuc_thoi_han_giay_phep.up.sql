@@ -0,0 +1,6 @@
+-- Thời hạn hiệu lực (tháng) của giấy phép cấp theo thủ tục; NULL với thủ tục không cấp giấy phép
+ALTER TABLE thu_tuc
+ADD COLUMN so_thang_hieu_luc INT NULL CHECK (so_thang_hieu_luc > 0);
+
+UPDATE thu_tuc SET so_thang_hieu_luc = 120 WHERE loai_giay_phep = 'Giấy phép kinh doanh';
+UPDATE thu_tuc SET so_thang_hieu_luc = 24 WHERE loai_giay_phep = 'Giấy phép xuất khẩu, nhập khẩu';
@@ -0,0 +1,2 @@
+-- Không khôi phục giá trị ghi nhầm
+SELECT 1;
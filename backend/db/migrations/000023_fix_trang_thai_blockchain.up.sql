@@ -0,0 +1,2 @@
+-- Các lần đẩy blockchain trước đây ghi nhầm chuỗi 'TrangThaiBCDaDongBo' thay cho 'DaDongBo'
+UPDATE giay_phep SET trang_thai_blockchain = 'DaDongBo' WHERE trang_thai_blockchain = 'TrangThaiBCDaDongBo';
@@ -0,0 +1,3 @@
+ALTER TABLE certificate_authorities
+DROP COLUMN IF EXISTS crl_next_update,
+DROP COLUMN IF EXISTS crl_der;
@@ -0,0 +1,4 @@
+-- CRL đã ký gần nhất của CA; GET /ca/crl trả bản này thay vì ký CRL mới cho mỗi request
+ALTER TABLE certificate_authorities
+ADD COLUMN crl_der BYTEA NULL,
+ADD COLUMN crl_next_update TIMESTAMPTZ NULL;
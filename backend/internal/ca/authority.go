@@ -0,0 +1,593 @@
+// Package ca là CA nội bộ cấp chứng thư số X.509 cho cán bộ, để chữ ký gắn được với danh tính người ký.
+// Khóa của CA được mã hóa phong bì qua keystore giống khóa ký của cán bộ.
+package ca
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/keystore"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrChuaCoCA            = errors.New("hệ thống chưa khởi tạo CA nội bộ")
+	ErrChungThuKhongTonTai = errors.New("không tìm thấy chứng thư số")
+	ErrChungThuDaThuHoi    = errors.New("chứng thư số đã bị thu hồi trước đó")
+	ErrChuaCoChungThu      = errors.New("cán bộ chưa được cấp chứng thư số hợp lệ cho khóa ký hiện tại")
+	ErrChungThuBiThuHoi    = errors.New("chứng thư số đã bị thu hồi tại thời điểm ký")
+	ErrChuoiChungThu       = errors.New("chứng thư số không được CA nội bộ xác nhận tại thời điểm ký")
+)
+
+// Loại bản ghi trong bảng certificate_authorities
+const (
+	loaiCA  = "ca"
+	loaiTSA = "tsa"
+)
+
+const (
+	rootValidity = 10 * 365 * 24 * time.Hour
+	leafValidity = 2 * 365 * 24 * time.Hour
+	crlValidity  = 7 * 24 * time.Hour
+	// CRL đã lưu được ký lại khi còn chưa tới crlRefreshBefore là đến NextUpdate, để bên tải về không nhận CRL quá hạn
+	crlRefreshBefore = 24 * time.Hour
+)
+
+type Authority struct {
+	db   *gorm.DB
+	keys *keystore.Keystore
+
+	mu     sync.Mutex
+	signer *rsa.PrivateKey // Khóa CA đang hoạt động, giải mã một lần khi cần ký
+	caID   uuid.UUID
+}
+
+func New(db *gorm.DB, keys *keystore.Keystore) *Authority {
+	return &Authority{db: db, keys: keys}
+}
+
+func caName() string {
+	if v := os.Getenv("CA_NAME"); v != "" {
+		return v
+	}
+	return "KmaERM Internal CA"
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+}
+
+func encodeCert(der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// ParseCertPEM đọc một chứng thư dạng PEM
+func ParseCertPEM(certPEM string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("chứng thư số không hợp lệ")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// PublicKeyFingerprint là SHA-256 (hex) của public key dạng PKIX, dùng để tìm chứng thư theo khóa ký
+func PublicKeyFingerprint(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// EnsureRoot tạo CA gốc tự ký nếu hệ thống chưa có CA đang hoạt động
+func (a *Authority) EnsureRoot() error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("LOCK TABLE certificate_authorities IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.CertificateAuthority{}).Where("loai = ? AND is_active", loaiCA).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		key, err := rsa.GenerateKey(rand.Reader, 3072)
+		if err != nil {
+			return err
+		}
+		serial, err := randomSerial()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		tmpl := &x509.Certificate{
+			SerialNumber:          serial,
+			Subject:               pkix.Name{CommonName: caName(), Organization: []string{"KmaERM"}},
+			NotBefore:             now.Add(-time.Minute),
+			NotAfter:              now.Add(rootValidity),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			MaxPathLenZero:        true,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+		if err != nil {
+			return err
+		}
+
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		sealed, err := a.keys.Seal(string(keyPEM))
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.CertificateAuthority{
+			Ten:           caName(),
+			Loai:          loaiCA,
+			CertPEM:       encodeCert(der),
+			PrivateKeyEnc: sealed.PrivateKeyEnc,
+			DEKEnc:        sealed.DEKEnc,
+			KEKVersion:    sealed.KEKVersion,
+			IsActive:      true,
+		}).Error
+	})
+}
+
+func (a *Authority) activeCA(tx *gorm.DB) (*models.CertificateAuthority, error) {
+	var row models.CertificateAuthority
+	err := tx.Where("loai = ? AND is_active", loaiCA).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChuaCoCA
+	}
+	return &row, err
+}
+
+// caKey giải mã khóa CA (cache trong bộ nhớ theo CA ID)
+func (a *Authority) caKey(row *models.CertificateAuthority) (*rsa.PrivateKey, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.signer != nil && a.caID == row.ID {
+		return a.signer, nil
+	}
+
+	keyPEM, err := a.keys.Open(&keystore.SealedKey{
+		PrivateKeyEnc: row.PrivateKeyEnc,
+		DEKEnc:        row.DEKEnc,
+		KEKVersion:    row.KEKVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("không giải mã được khóa CA: %w", err)
+	}
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, errors.New("khóa CA không hợp lệ")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	a.signer, a.caID = key, row.ID
+	return key, nil
+}
+
+// Issue cấp chứng thư cho user (CN = họ tên, SAN = email) gắn với public key pub.
+// tx cho phép cấp trong cùng transaction tạo tài khoản.
+func (a *Authority) Issue(tx *gorm.DB, user *models.User, pub crypto.PublicKey) (*models.ChungThuSo, error) {
+	if tx == nil {
+		tx = a.db
+	}
+	caRow, err := a.activeCA(tx)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := ParseCertPEM(caRow.CertPEM)
+	if err != nil {
+		return nil, err
+	}
+	caKey, err := a.caKey(caRow)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := PublicKeyFingerprint(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	notAfter := now.Add(leafValidity)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:   serial,
+		Subject:        pkix.Name{CommonName: user.FullName, Organization: []string{"KmaERM"}},
+		EmailAddresses: []string{user.Email},
+		NotBefore:      now.Add(-time.Minute),
+		NotAfter:       notAfter,
+		KeyUsage:       x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+	}
+	if url := os.Getenv("CA_CRL_URL"); url != "" {
+		tmpl.CRLDistributionPoints = []string{url}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, pub, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi cấp chứng thư số: %w", err)
+	}
+
+	ct := &models.ChungThuSo{
+		UserID:               user.ID,
+		CAID:                 caRow.ID,
+		SerialNumber:         serial.Text(16),
+		SubjectCN:            user.FullName,
+		Email:                user.Email,
+		CertPEM:              encodeCert(der),
+		ChainPEM:             caRow.CertPEM,
+		PublicKeyFingerprint: fingerprint,
+		NotBefore:            tmpl.NotBefore,
+		NotAfter:             tmpl.NotAfter,
+	}
+	if err := tx.Create(ct).Error; err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
+
+// FindForKey trả về chứng thư mới nhất còn hiệu lực tại 'at' của user, khớp với public key đang dùng để ký
+func (a *Authority) FindForKey(userID uuid.UUID, pub crypto.PublicKey, at time.Time) (*models.ChungThuSo, error) {
+	fingerprint, err := PublicKeyFingerprint(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	var ct models.ChungThuSo
+	err = a.db.Where("user_id = ? AND public_key_fingerprint = ?", userID, fingerprint).
+		Where("revoked_at IS NULL AND not_before <= ? AND not_after > ?", at, at).
+		Order("created_at DESC").
+		First(&ct).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChuaCoChungThu
+	}
+	return &ct, err
+}
+
+func (a *Authority) GetByID(id uuid.UUID) (*models.ChungThuSo, error) {
+	var ct models.ChungThuSo
+	err := a.db.First(&ct, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChungThuKhongTonTai
+	}
+	return &ct, err
+}
+
+func (a *Authority) ListByUser(userID uuid.UUID) ([]models.ChungThuSo, error) {
+	var list []models.ChungThuSo
+	err := a.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+// Revoke thu hồi chứng thư và ký lại CRL trong cùng transaction; chữ ký tạo TRƯỚC thời điểm thu hồi
+// vẫn được coi là hợp lệ
+func (a *Authority) Revoke(tx *gorm.DB, id uuid.UUID, reason string) error {
+	if tx == nil {
+		return a.db.Transaction(func(tx *gorm.DB) error {
+			return a.Revoke(tx, id, reason)
+		})
+	}
+	result := tx.Model(&models.ChungThuSo{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "ly_do_thu_hoi": reason})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := a.GetByID(id); err != nil {
+			return err
+		}
+		return ErrChungThuDaThuHoi
+	}
+	_, err := a.phatHanhCRL(tx, true)
+	return err
+}
+
+// RevokeAllByUser thu hồi mọi chứng thư còn hiệu lực của user (ví dụ khi đổi khóa ký)
+func (a *Authority) RevokeAllByUser(tx *gorm.DB, userID uuid.UUID, reason string) error {
+	if tx == nil {
+		return a.db.Transaction(func(tx *gorm.DB) error {
+			return a.RevokeAllByUser(tx, userID, reason)
+		})
+	}
+	result := tx.Model(&models.ChungThuSo{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "ly_do_thu_hoi": reason})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	_, err := a.phatHanhCRL(tx, true)
+	return err
+}
+
+// RootCertPEM trả về chứng thư CA đang hoạt động (để bên thứ ba cài làm trust anchor)
+func (a *Authority) RootCertPEM() (string, error) {
+	row, err := a.activeCA(a.db)
+	if err != nil {
+		return "", err
+	}
+	return row.CertPEM, nil
+}
+
+// CRL trả về danh sách thu hồi (DER) đã lưu của CA đang hoạt động. CRL chỉ được ký lại khi có chứng thư
+// bị thu hồi (Revoke, RevokeAllByUser) hoặc khi sắp tới NextUpdate, nên request tải CRL thông thường
+// không khóa dòng CA và không dùng khóa CA.
+func (a *Authority) CRL() ([]byte, error) {
+	caRow, err := a.activeCA(a.db)
+	if err != nil {
+		return nil, err
+	}
+	if crlConDung(caRow, time.Now()) {
+		return caRow.CRLDER, nil
+	}
+	var der []byte
+	err = a.db.Transaction(func(tx *gorm.DB) error {
+		der, err = a.phatHanhCRL(tx, false)
+		return err
+	})
+	return der, err
+}
+
+// crlConDung: CA đã có CRL lưu sẵn và CRL đó chưa tới lúc phải ký lại
+func crlConDung(row *models.CertificateAuthority, now time.Time) bool {
+	return len(row.CRLDER) > 0 && row.CRLNextUpdate != nil && now.Before(row.CRLNextUpdate.Add(-crlRefreshBefore))
+}
+
+// phatHanhCRL khóa dòng CA đang hoạt động, ký CRL mới (tăng CRL number) và lưu lại. Khi batBuoc = false,
+// CRL đã lưu còn dùng được thì trả luôn (request khác vừa ký xong trong lúc chờ khóa).
+func (a *Authority) phatHanhCRL(tx *gorm.DB, batBuoc bool) ([]byte, error) {
+	var caRow models.CertificateAuthority
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("loai = ? AND is_active", loaiCA).First(&caRow).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChuaCoCA
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !batBuoc && crlConDung(&caRow, now) {
+		return caRow.CRLDER, nil
+	}
+
+	caCert, err := ParseCertPEM(caRow.CertPEM)
+	if err != nil {
+		return nil, err
+	}
+	caKey, err := a.caKey(&caRow)
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked []models.ChungThuSo
+	if err := tx.Where("ca_id = ? AND revoked_at IS NOT NULL", caRow.ID).Find(&revoked).Error; err != nil {
+		return nil, err
+	}
+	entries := make([]x509.RevocationListEntry, 0, len(revoked))
+	for _, r := range revoked {
+		serial, ok := new(big.Int).SetString(r.SerialNumber, 16)
+		if !ok {
+			return nil, fmt.Errorf("serial không hợp lệ: %s", r.SerialNumber)
+		}
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: serial, RevocationTime: *r.RevokedAt})
+	}
+
+	nextUpdate := now.Add(crlValidity)
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(caRow.CRLNumber + 1),
+		ThisUpdate:                now,
+		NextUpdate:                nextUpdate,
+		RevokedCertificateEntries: entries,
+	}, caCert, caKey)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Model(&caRow).Updates(map[string]interface{}{
+		"crl_number":      caRow.CRLNumber + 1,
+		"crl_der":         der,
+		"crl_next_update": nextUpdate,
+	}).Error
+	return der, err
+}
+
+// VerifyAt kiểm tra chứng thư tại thời điểm 'at': chuỗi tới một CA nội bộ, còn hạn, chưa bị thu hồi.
+// Trust anchor lấy từ bảng certificate_authorities, không dùng chain_pem lưu kèm chứng thư.
+func (a *Authority) VerifyAt(ct *models.ChungThuSo, at time.Time) (*x509.Certificate, error) {
+	caCerts, err := a.RootCertificates()
+	if err != nil {
+		return nil, err
+	}
+	return verifyAt(ct, caCerts, at)
+}
+
+// verifyAt là phần kiểm tra của VerifyAt với danh sách trust anchor cho trước
+func verifyAt(ct *models.ChungThuSo, caCerts []*x509.Certificate, at time.Time) (*x509.Certificate, error) {
+	cert, err := ParseCertPEM(ct.CertPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := x509.NewCertPool()
+	for _, c := range caCerts {
+		roots.AddCert(c)
+	}
+
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: at,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return cert, fmt.Errorf("%w: %v", ErrChuoiChungThu, err)
+	}
+
+	if ct.RevokedAt != nil && !ct.RevokedAt.After(at) {
+		return cert, ErrChungThuBiThuHoi
+	}
+	return cert, nil
+}
+
+// RootCertificates trả về chứng thư của mọi CA nội bộ (kể cả CA cũ đã ngừng hoạt động) để làm trust anchor
+func (a *Authority) RootCertificates() ([]*x509.Certificate, error) {
+	var cas []models.CertificateAuthority
+	if err := a.db.Where("loai = ?", loaiCA).Find(&cas).Error; err != nil {
+		return nil, err
+	}
+	certs := make([]*x509.Certificate, 0, len(cas))
+	for _, c := range cas {
+		if caCert, err := ParseCertPEM(c.CertPEM); err == nil {
+			certs = append(certs, caCert)
+		}
+	}
+	return certs, nil
+}
+
+func tsaName() string {
+	if v := os.Getenv("TSA_NAME"); v != "" {
+		return v
+	}
+	return "KmaERM Time Stamping Authority"
+}
+
+// oidExtKeyUsage và oidTimeStamping: RFC 3161 yêu cầu EKU timeStamping là DUY NHẤT và đánh dấu critical,
+// x509.CreateCertificate luôn sinh EKU non-critical nên phải tự mã hóa phần mở rộng này
+var (
+	oidExtKeyUsage  = asn1.ObjectIdentifier{2, 5, 29, 37}
+	oidTimeStamping = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}
+)
+
+// EnsureTimestamping trả về khóa riêng (PEM) và chứng thư của TSA nội bộ kèm chứng thư CA phát hành.
+// Lần đầu (hoặc khi chứng thư TSA sắp hết hạn) sinh khóa mới, do CA đang hoạt động cấp chứng thư.
+func (a *Authority) EnsureTimestamping() (string, []*x509.Certificate, error) {
+	var keyPEM string
+	var chain []*x509.Certificate
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("LOCK TABLE certificate_authorities IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		caRow, err := a.activeCA(tx)
+		if err != nil {
+			return err
+		}
+		caCert, err := ParseCertPEM(caRow.CertPEM)
+		if err != nil {
+			return err
+		}
+
+		var row models.CertificateAuthority
+		err = tx.Where("loai = ? AND is_active", loaiTSA).First(&row).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			tsaCert, err := ParseCertPEM(row.CertPEM)
+			if err != nil {
+				return err
+			}
+			// Còn hạn ít nhất 30 ngày và vẫn do CA hiện tại phát hành thì dùng tiếp
+			if tsaCert.NotAfter.After(time.Now().Add(30*24*time.Hour)) && tsaCert.CheckSignatureFrom(caCert) == nil {
+				keyPEM, err = a.keys.Open(&keystore.SealedKey{
+					PrivateKeyEnc: row.PrivateKeyEnc,
+					DEKEnc:        row.DEKEnc,
+					KEKVersion:    row.KEKVersion,
+				})
+				if err != nil {
+					return fmt.Errorf("không giải mã được khóa TSA: %w", err)
+				}
+				chain = []*x509.Certificate{tsaCert, caCert}
+				return nil
+			}
+			if err := tx.Model(&row).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+
+		caKey, err := a.caKey(caRow)
+		if err != nil {
+			return err
+		}
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return err
+		}
+		serial, err := randomSerial()
+		if err != nil {
+			return err
+		}
+		ekuDER, err := asn1.Marshal([]asn1.ObjectIdentifier{oidTimeStamping})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		notAfter := now.Add(leafValidity)
+		if notAfter.After(caCert.NotAfter) {
+			notAfter = caCert.NotAfter
+		}
+		tmpl := &x509.Certificate{
+			SerialNumber:    serial,
+			Subject:         pkix.Name{CommonName: tsaName(), Organization: []string{"KmaERM"}},
+			NotBefore:       now.Add(-time.Minute),
+			NotAfter:        notAfter,
+			KeyUsage:        x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+			ExtraExtensions: []pkix.Extension{{Id: oidExtKeyUsage, Critical: true, Value: ekuDER}},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			return fmt.Errorf("lỗi cấp chứng thư TSA: %w", err)
+		}
+		tsaCert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return err
+		}
+
+		keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+		sealed, err := a.keys.Seal(keyPEM)
+		if err != nil {
+			return err
+		}
+		chain = []*x509.Certificate{tsaCert, caCert}
+		return tx.Create(&models.CertificateAuthority{
+			Ten:           tsaName(),
+			Loai:          loaiTSA,
+			CertPEM:       encodeCert(der),
+			PrivateKeyEnc: sealed.PrivateKeyEnc,
+			DEKEnc:        sealed.DEKEnc,
+			KEKVersion:    sealed.KEKVersion,
+			IsActive:      true,
+		}).Error
+	})
+	return keyPEM, chain, err
+}
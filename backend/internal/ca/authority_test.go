@@ -0,0 +1,56 @@
+package ca
+
+import (
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/pkitest"
+)
+
+func TestVerifyAt(t *testing.T) {
+	ca := pkitest.CA(t, "KmaERM Test CA")
+	caKhac := pkitest.CA(t, "CA khác")
+	leaf := pkitest.NguoiKy(t, ca, "Nguyễn Văn A")
+	certPEM := encodeCert(leaf.Cert.Raw)
+
+	now := time.Now()
+	thuHoiTruoc := now.Add(-30 * time.Minute)
+	thuHoiSau := now.Add(30 * time.Minute)
+
+	tests := []struct {
+		ten       string
+		revokedAt *time.Time
+		roots     []*x509.Certificate
+		at        time.Time
+		wantErr   error
+	}{
+		{ten: "hợp lệ", roots: []*x509.Certificate{ca.Cert}, at: now},
+		{ten: "CA cũ vẫn là trust anchor", roots: []*x509.Certificate{caKhac.Cert, ca.Cert}, at: now},
+		{ten: "hết hạn", roots: []*x509.Certificate{ca.Cert}, at: now.Add(48 * time.Hour), wantErr: ErrChuoiChungThu},
+		{ten: "chưa có hiệu lực", roots: []*x509.Certificate{ca.Cert}, at: now.Add(-2 * time.Hour), wantErr: ErrChuoiChungThu},
+		{ten: "sai CA cấp", roots: []*x509.Certificate{caKhac.Cert}, at: now, wantErr: ErrChuoiChungThu},
+		{ten: "chưa có CA", at: now, wantErr: ErrChuoiChungThu},
+		{ten: "thu hồi trước lúc ký", revokedAt: &thuHoiTruoc, roots: []*x509.Certificate{ca.Cert}, at: now, wantErr: ErrChungThuBiThuHoi},
+		{ten: "thu hồi đúng lúc ký", revokedAt: &now, roots: []*x509.Certificate{ca.Cert}, at: now, wantErr: ErrChungThuBiThuHoi},
+		{ten: "thu hồi sau lúc ký", revokedAt: &thuHoiSau, roots: []*x509.Certificate{ca.Cert}, at: now},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ten, func(t *testing.T) {
+			ct := &models.ChungThuSo{CertPEM: certPEM, RevokedAt: tc.revokedAt}
+			cert, err := verifyAt(ct, tc.roots, tc.at)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, muốn %v", err, tc.wantErr)
+			}
+			if cert == nil || !cert.Equal(leaf.Cert) {
+				t.Error("phải trả về chứng thư đã đọc kể cả khi không hợp lệ")
+			}
+		})
+	}
+
+	if _, err := verifyAt(&models.ChungThuSo{CertPEM: "không phải PEM"}, []*x509.Certificate{ca.Cert}, now); err == nil {
+		t.Error("PEM hỏng phải trả lỗi")
+	}
+}
@@ -0,0 +1,85 @@
+// Package calendar tính toán theo ngày làm việc: bỏ qua thứ Bảy, Chủ nhật và ngày nghỉ lễ,
+// tính cả ngày cuối tuần phải làm bù. Mọi ngày được xét theo giờ Việt Nam.
+package calendar
+
+import "time"
+
+// GioKetThuc là giờ hết giờ hành chính; hạn trả hồ sơ là cuối ngày làm việc cuối cùng
+const GioKetThuc = 17
+
+const dateLayout = "2006-01-02"
+
+// ViTri là múi giờ dùng để xác định "ngày"; Việt Nam không đổi giờ theo mùa nên có thể dùng múi cố định
+var ViTri = loadViTri()
+
+func loadViTri() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Ho_Chi_Minh"); err == nil {
+		return loc
+	}
+	return time.FixedZone("ICT", 7*60*60)
+}
+
+// Lich là lịch làm việc trong một khoảng thời gian đã nạp ngày nghỉ lễ
+type Lich struct {
+	ngayNghi  map[string]bool
+	ngayLamBu map[string]bool
+}
+
+// New tạo lịch từ danh sách ngày nghỉ lễ và ngày làm bù. Các ngày được lấy theo giá trị lịch
+// (năm-tháng-ngày) của time.Time, đúng với cột DATE đọc từ CSDL.
+func New(ngayNghi []time.Time, ngayLamBu []time.Time) *Lich {
+	l := &Lich{ngayNghi: map[string]bool{}, ngayLamBu: map[string]bool{}}
+	for _, d := range ngayNghi {
+		l.ngayNghi[d.Format(dateLayout)] = true
+	}
+	for _, d := range ngayLamBu {
+		l.ngayLamBu[d.Format(dateLayout)] = true
+	}
+	return l
+}
+
+// NgayCua trả về 0 giờ (giờ Việt Nam) của ngày chứa t
+func NgayCua(t time.Time) time.Time {
+	y, m, d := t.In(ViTri).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, ViTri)
+}
+
+// LaNgayLamViec: ngày chứa t (giờ Việt Nam) có phải ngày làm việc không
+func (l *Lich) LaNgayLamViec(t time.Time) bool {
+	ngay := NgayCua(t)
+	key := ngay.Format(dateLayout)
+	if l.ngayLamBu[key] {
+		return true
+	}
+	if l.ngayNghi[key] {
+		return false
+	}
+	wd := ngay.Weekday()
+	return wd != time.Saturday && wd != time.Sunday
+}
+
+// CongNgayLamViec trả về hết giờ hành chính của ngày làm việc thứ n sau ngày chứa tu
+// (không tính chính ngày tu). n <= 0 trả về hết giờ hành chính của ngày tu.
+func (l *Lich) CongNgayLamViec(tu time.Time, n int) time.Time {
+	ngay := NgayCua(tu)
+	for n > 0 {
+		ngay = ngay.AddDate(0, 0, 1)
+		if l.LaNgayLamViec(ngay) {
+			n--
+		}
+	}
+	return ngay.Add(GioKetThuc * time.Hour)
+}
+
+// DemNgayLamViec đếm số ngày làm việc sau ngày chứa tu đến hết ngày chứa den; 0 nếu den không sau tu
+func (l *Lich) DemNgayLamViec(tu, den time.Time) int {
+	ngay, cuoi := NgayCua(tu), NgayCua(den)
+	count := 0
+	for ngay.Before(cuoi) {
+		ngay = ngay.AddDate(0, 0, 1)
+		if l.LaNgayLamViec(ngay) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,100 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func ngay(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, ViTri)
+}
+
+func hetGio(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, GioKetThuc, 0, 0, 0, ViTri)
+}
+
+// Tết Ất Tỵ 2025: nghỉ từ thứ Bảy 25/01 đến Chủ nhật 02/02, ngày nghỉ lễ là thứ Hai 27/01 - thứ Sáu 31/01
+func lichTet2025() *Lich {
+	var nghi []time.Time
+	for d := 27; d <= 31; d++ {
+		nghi = append(nghi, ngay(2025, time.January, d))
+	}
+	return New(nghi, nil)
+}
+
+func TestCongNgayLamViecQuaTetVaCuoiTuan(t *testing.T) {
+	l := lichTet2025()
+
+	cases := []struct {
+		ten  string
+		tu   time.Time
+		n    int
+		muon time.Time
+	}{
+		{"ngày làm việc đầu tiên sau Tết", time.Date(2025, time.January, 24, 10, 30, 0, 0, ViTri), 1, hetGio(2025, time.February, 3)},
+		{"tiếp nhận giữa kỳ nghỉ", ngay(2025, time.January, 29), 1, hetGio(2025, time.February, 3)},
+		{"tiếp nhận Chủ nhật cuối kỳ nghỉ", ngay(2025, time.February, 2), 5, hetGio(2025, time.February, 7)},
+		{"trước Tết một tuần", ngay(2025, time.January, 20), 5, hetGio(2025, time.February, 3)},
+	}
+	for _, tc := range cases {
+		if got := l.CongNgayLamViec(tc.tu, tc.n); !got.Equal(tc.muon) {
+			t.Errorf("%s: CongNgayLamViec(%s, %d) = %s, muốn %s", tc.ten, tc.tu.Format(dateLayout), tc.n, got, tc.muon)
+		}
+	}
+
+	if got := l.DemNgayLamViec(ngay(2025, time.January, 24), ngay(2025, time.February, 3)); got != 1 {
+		t.Errorf("DemNgayLamViec qua Tết = %d, muốn 1", got)
+	}
+}
+
+// Dịp 30/4 - 1/5/2024: nghỉ thứ Hai 29/04 (hoán đổi), làm bù thứ Bảy 04/05
+func TestCongNgayLamViecNgayLamBu(t *testing.T) {
+	l := New(
+		[]time.Time{ngay(2024, time.April, 29), ngay(2024, time.April, 30), ngay(2024, time.May, 1)},
+		[]time.Time{ngay(2024, time.May, 4)},
+	)
+
+	if !l.LaNgayLamViec(ngay(2024, time.May, 4)) {
+		t.Error("thứ Bảy làm bù phải là ngày làm việc")
+	}
+	if l.LaNgayLamViec(ngay(2024, time.May, 11)) {
+		t.Error("thứ Bảy thường không phải ngày làm việc")
+	}
+	if got, muon := l.CongNgayLamViec(ngay(2024, time.April, 26), 3), hetGio(2024, time.May, 4); !got.Equal(muon) {
+		t.Errorf("CongNgayLamViec = %s, muốn %s", got, muon)
+	}
+	if got := l.DemNgayLamViec(ngay(2024, time.April, 26), ngay(2024, time.May, 6)); got != 4 {
+		t.Errorf("DemNgayLamViec = %d, muốn 4", got)
+	}
+}
+
+func TestSoNgayKhongDuong(t *testing.T) {
+	l := lichTet2025()
+	// Kể cả khi tu là ngày nghỉ, n <= 0 trả về hết giờ hành chính của chính ngày tu
+	tu := time.Date(2025, time.January, 28, 8, 0, 0, 0, ViTri)
+	for _, n := range []int{0, -1, -10} {
+		if got, muon := l.CongNgayLamViec(tu, n), hetGio(2025, time.January, 28); !got.Equal(muon) {
+			t.Errorf("CongNgayLamViec(n=%d) = %s, muốn %s", n, got, muon)
+		}
+	}
+
+	if got := l.DemNgayLamViec(ngay(2025, time.February, 5), ngay(2025, time.February, 3)); got != 0 {
+		t.Errorf("DemNgayLamViec khi den trước tu = %d, muốn 0", got)
+	}
+	if got := l.DemNgayLamViec(ngay(2025, time.February, 5), ngay(2025, time.February, 5)); got != 0 {
+		t.Errorf("DemNgayLamViec cùng ngày = %d, muốn 0", got)
+	}
+}
+
+// Thời điểm UTC thuộc ngày hôm sau theo giờ Việt Nam
+func TestNgayTheoGioVietNam(t *testing.T) {
+	l := New(nil, nil)
+	// 18:00 UTC thứ Sáu 07/02/2025 là 01:00 thứ Bảy 08/02 giờ Việt Nam
+	tu := time.Date(2025, time.February, 7, 18, 0, 0, 0, time.UTC)
+	if l.LaNgayLamViec(tu) {
+		t.Error("01:00 thứ Bảy giờ Việt Nam không phải ngày làm việc")
+	}
+	if got, muon := l.CongNgayLamViec(tu, 1), hetGio(2025, time.February, 10); !got.Equal(muon) {
+		t.Errorf("CongNgayLamViec = %s, muốn %s", got, muon)
+	}
+}
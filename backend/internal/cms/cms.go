@@ -0,0 +1,411 @@
+// Package cms dựng và kiểm tra SignedData (CMS, RFC 5652) cho chữ ký của cán bộ và dấu thời gian.
+// Chỉ hỗ trợ đúng cấu hình hệ thống dùng: SHA-256 + RSA PKCS#1 v1.5, có signed attributes
+// (content-type, message-digest, signing-certificate-v2 theo CAdES/PAdES baseline).
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/signer"
+)
+
+var (
+	oidData               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidAttrContentType    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttrMessageDigest  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttrSigningTime    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidAttrSigningCertV2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	oidAttrTimeStampToken = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+	oidSHA256             = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA256WithRSA      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	asn1Null              = asn1.RawValue{Tag: asn1.TagNull}
+	sha256Algorithm       = algorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1Null}
+)
+
+var (
+	ErrChuKyCMSKhongHopLe = errors.New("chữ ký CMS không hợp lệ")
+	ErrDigestKhongKhop    = errors.New("message-digest trong CMS không khớp nội dung được ký")
+
+	errKhongPhaiSignedData  = errors.New("dữ liệu không phải CMS SignedData")
+	errThuatToanKhongHoTro  = errors.New("CMS dùng thuật toán chưa được hỗ trợ (chỉ SHA-256/RSA)")
+	errThieuChungThuNguoiKy = errors.New("CMS không chứa chứng thư của người ký")
+)
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"optional,explicit,tag:0"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	SignerInfos      asn1.RawValue
+}
+
+type signerInfo struct {
+	Version            int
+	SID                issuerAndSerialNumber
+	DigestAlgorithm    algorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm algorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// ESSCertIDv2 (RFC 5035), hashAlgorithm mặc định SHA-256 nên bỏ qua
+type essCertIDv2 struct {
+	CertHash     []byte
+	IssuerSerial issuerSerial
+}
+
+type issuerSerial struct {
+	Issuer       asn1.RawValue // GeneralNames
+	SerialNumber *big.Int
+}
+
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+// SignOptions tùy chọn khi dựng SignedData
+type SignOptions struct {
+	// SigningTime khác zero thì thêm thuộc tính signing-time (PAdES dùng /M trong từ điển chữ ký nên để trống)
+	SigningTime time.Time
+	// Timestamper khác nil thì lấy dấu thời gian RFC 3161 trên giá trị chữ ký và gắn vào
+	// unsigned attribute signature-time-stamp (CAdES-T / PAdES-B-T)
+	Timestamper func(signature []byte) ([]byte, error)
+}
+
+// SignDigest dựng SignedData detached cho nội dung có SHA-256 là contentDigest.
+// Khóa riêng không đi qua package này: chỉ gọi sgn.SignDigest trên digest của signed attributes.
+func SignDigest(contentDigest []byte, sgn signer.Signer, cert *x509.Certificate, chain []*x509.Certificate, opts SignOptions) ([]byte, error) {
+	if len(contentDigest) != sha256.Size {
+		return nil, errors.New("digest nội dung phải là SHA-256")
+	}
+	return sign(oidData, nil, contentDigest, sgn, cert, chain, opts)
+}
+
+// SignEncapsulated dựng SignedData chứa luôn nội dung (eContent) với kiểu nội dung contentType,
+// ví dụ TSTInfo của dấu thời gian RFC 3161
+func SignEncapsulated(contentType asn1.ObjectIdentifier, content []byte, sgn signer.Signer, cert *x509.Certificate, chain []*x509.Certificate) ([]byte, error) {
+	digest := sha256.Sum256(content)
+	return sign(contentType, content, digest[:], sgn, cert, chain, SignOptions{})
+}
+
+func sign(contentType asn1.ObjectIdentifier, content, contentDigest []byte, sgn signer.Signer, cert *x509.Certificate, chain []*x509.Certificate, opts SignOptions) ([]byte, error) {
+	attrs, err := buildSignedAttrs(contentType, contentDigest, cert, opts)
+	if err != nil {
+		return nil, err
+	}
+	attrsDigest := sha256.Sum256(attrs)
+	sig, err := sgn.SignDigest(attrsDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trong SignerInfo, signed attributes mang tag [0] IMPLICIT thay cho SET
+	implicitAttrs := append([]byte{0xa0}, attrs[1:]...)
+
+	si := signerInfo{
+		Version: 1,
+		SID: issuerAndSerialNumber{
+			Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+			SerialNumber: cert.SerialNumber,
+		},
+		DigestAlgorithm:    sha256Algorithm,
+		SignedAttrs:        asn1.RawValue{FullBytes: implicitAttrs},
+		SignatureAlgorithm: algorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1Null},
+		Signature:          sig,
+	}
+	if opts.Timestamper != nil {
+		token, err := opts.Timestamper(sig)
+		if err != nil {
+			return nil, fmt.Errorf("lỗi lấy dấu thời gian cho chữ ký: %w", err)
+		}
+		unsigned, err := encodeAttributes(asn1.ClassContextSpecific, 1, []attributeValue{{oidAttrTimeStampToken, asn1.RawValue{FullBytes: token}}})
+		if err != nil {
+			return nil, err
+		}
+		si.UnsignedAttrs = asn1.RawValue{FullBytes: unsigned}
+	}
+	siDER, err := asn1.Marshal(si)
+	if err != nil {
+		return nil, err
+	}
+	digestAlgDER, err := asn1.Marshal(sha256Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []byte
+	certs = append(certs, cert.Raw...)
+	for _, c := range chain {
+		certs = append(certs, c.Raw...)
+	}
+
+	// Version 3 khi eContentType khác id-data (RFC 5652 mục 5.1)
+	version := 1
+	encap := encapsulatedContentInfo{EContentType: contentType}
+	if !contentType.Equal(oidData) {
+		version = 3
+	}
+	if content != nil {
+		octets, err := asn1.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		encap.EContent = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: octets}
+	}
+
+	sd := signedData{
+		Version:          version,
+		DigestAlgorithms: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: digestAlgDER},
+		EncapContentInfo: encap,
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs},
+		SignerInfos:      asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: siDER},
+	}
+	sdDER, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sdDER},
+	})
+}
+
+type attributeValue struct {
+	oid   asn1.ObjectIdentifier
+	value any
+}
+
+// buildSignedAttrs trả về SET OF Attribute đã sắp xếp theo DER (đây chính là dữ liệu được ký)
+func buildSignedAttrs(contentType asn1.ObjectIdentifier, contentDigest []byte, cert *x509.Certificate, opts SignOptions) ([]byte, error) {
+	certHash := sha256.Sum256(cert.Raw)
+	// GeneralNames { directoryName [4] EXPLICIT Name }
+	directoryName, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: cert.RawIssuer})
+	if err != nil {
+		return nil, err
+	}
+	signingCert := signingCertificateV2{Certs: []essCertIDv2{{
+		CertHash: certHash[:],
+		IssuerSerial: issuerSerial{
+			Issuer:       asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: directoryName},
+			SerialNumber: cert.SerialNumber,
+		},
+	}}}
+
+	values := []attributeValue{
+		{oidAttrContentType, contentType},
+		{oidAttrMessageDigest, contentDigest},
+		{oidAttrSigningCertV2, signingCert},
+	}
+	if !opts.SigningTime.IsZero() {
+		values = append(values, attributeValue{oidAttrSigningTime, opts.SigningTime.UTC()})
+	}
+	return encodeAttributes(asn1.ClassUniversal, asn1.TagSet, values)
+}
+
+// encodeAttributes mã hóa SET OF Attribute theo DER (các phần tử sắp xếp tăng dần theo mã hóa),
+// với tag ngoài cùng là SET hoặc [n] IMPLICIT tùy class/tag truyền vào
+func encodeAttributes(class, tag int, values []attributeValue) ([]byte, error) {
+	encoded := make([][]byte, 0, len(values))
+	for _, v := range values {
+		valDER, err := asn1.Marshal(v.value)
+		if err != nil {
+			return nil, err
+		}
+		attrDER, err := asn1.Marshal(attribute{
+			Type:   v.oid,
+			Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: valDER},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, attrDER)
+	}
+	sort.Slice(encoded, func(i, j int) bool { return bytes.Compare(encoded[i], encoded[j]) < 0 })
+	return asn1.Marshal(asn1.RawValue{Class: class, Tag: tag, IsCompound: true, Bytes: bytes.Join(encoded, nil)})
+}
+
+// SignerResult là thông tin người ký lấy được sau khi kiểm tra CMS
+type SignerResult struct {
+	Certificate    *x509.Certificate   // Chứng thư của người ký (khớp issuer + serial trong SignerInfo)
+	Certificates   []*x509.Certificate // Toàn bộ chứng thư nhúng trong CMS
+	SigningTime    *time.Time          // Nếu CMS có thuộc tính signing-time
+	Signature      []byte              // Giá trị chữ ký RSA (đầu vào của dấu thời gian RFC 3161)
+	TimestampToken []byte              // Dấu thời gian RFC 3161 gắn kèm chữ ký (nếu có)
+}
+
+// VerifyDigest kiểm tra SignedData detached với nội dung có SHA-256 là contentDigest.
+// Chỉ xác nhận toán học (digest + chữ ký); việc tin chứng thư nào là của người gọi.
+// Dữ liệu thừa phía sau cấu trúc DER (ví dụ phần đệm 0 trong /Contents của PDF) được bỏ qua.
+func VerifyDigest(der []byte, contentDigest []byte) (*SignerResult, error) {
+	sd, err := parseSignedData(der)
+	if err != nil {
+		return nil, err
+	}
+	return verifySignerInfo(sd, oidData, contentDigest)
+}
+
+// VerifyEncapsulated kiểm tra SignedData có nội dung đi kèm và trả về kiểu nội dung + nội dung đó
+func VerifyEncapsulated(der []byte) (asn1.ObjectIdentifier, []byte, *SignerResult, error) {
+	sd, err := parseSignedData(der)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var content []byte
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent.Bytes, &content); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: thiếu eContent", errKhongPhaiSignedData)
+	}
+	digest := sha256.Sum256(content)
+	res, err := verifySignerInfo(sd, sd.EncapContentInfo.EContentType, digest[:])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return sd.EncapContentInfo.EContentType, content, res, nil
+}
+
+func parseSignedData(der []byte) (*signedData, error) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, fmt.Errorf("%w: %v", errKhongPhaiSignedData, err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, errKhongPhaiSignedData
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("%w: %v", errKhongPhaiSignedData, err)
+	}
+	return &sd, nil
+}
+
+func verifySignerInfo(sd *signedData, contentType asn1.ObjectIdentifier, contentDigest []byte) (*SignerResult, error) {
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("chứng thư trong CMS không hợp lệ: %w", err)
+	}
+
+	var si signerInfo
+	rest, err := asn1.Unmarshal(sd.SignerInfos.Bytes, &si)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errKhongPhaiSignedData, err)
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("CMS có nhiều hơn một người ký")
+	}
+	if !si.DigestAlgorithm.Algorithm.Equal(oidSHA256) ||
+		!(si.SignatureAlgorithm.Algorithm.Equal(oidRSAEncryption) || si.SignatureAlgorithm.Algorithm.Equal(oidSHA256WithRSA)) {
+		return nil, errThuatToanKhongHoTro
+	}
+	if len(si.SignedAttrs.FullBytes) == 0 {
+		return nil, errors.New("CMS thiếu signed attributes")
+	}
+
+	res := &SignerResult{Certificates: certs, Signature: si.Signature}
+	for _, c := range certs {
+		if bytes.Equal(c.RawIssuer, si.SID.Issuer.FullBytes) && c.SerialNumber.Cmp(si.SID.SerialNumber) == 0 {
+			res.Certificate = c
+			break
+		}
+	}
+	if res.Certificate == nil {
+		return nil, errThieuChungThuNguoiKy
+	}
+
+	// Thuộc tính content-type và message-digest phải khớp nội dung
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(si.SignedAttrs.FullBytes, &attrs, "set,tag:0"); err != nil {
+		return nil, fmt.Errorf("signed attributes không hợp lệ: %w", err)
+	}
+	var messageDigest []byte
+	var signedContentType asn1.ObjectIdentifier
+	for _, a := range attrs {
+		switch {
+		case a.Type.Equal(oidAttrContentType):
+			if _, err := asn1.Unmarshal(a.Values.Bytes, &signedContentType); err != nil {
+				return nil, err
+			}
+		case a.Type.Equal(oidAttrMessageDigest):
+			if _, err := asn1.Unmarshal(a.Values.Bytes, &messageDigest); err != nil {
+				return nil, err
+			}
+		case a.Type.Equal(oidAttrSigningTime):
+			var t time.Time
+			if _, err := asn1.Unmarshal(a.Values.Bytes, &t); err == nil {
+				res.SigningTime = &t
+			}
+		}
+	}
+	if !signedContentType.Equal(contentType) {
+		return nil, errors.New("thuộc tính content-type không khớp nội dung được ký")
+	}
+	if !bytes.Equal(messageDigest, contentDigest) {
+		return nil, ErrDigestKhongKhop
+	}
+
+	// Chữ ký được tính trên mã hóa SET (tag 0x31) của signed attributes
+	signed := append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	attrsDigest := sha256.Sum256(signed)
+	pub, ok := res.Certificate.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errThuatToanKhongHoTro
+	}
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, attrsDigest[:], si.Signature); err != nil {
+		return nil, ErrChuKyCMSKhongHopLe
+	}
+
+	if len(si.UnsignedAttrs.FullBytes) > 0 {
+		var unsigned []attribute
+		if _, err := asn1.UnmarshalWithParams(si.UnsignedAttrs.FullBytes, &unsigned, "set,tag:1"); err != nil {
+			return nil, fmt.Errorf("unsigned attributes không hợp lệ: %w", err)
+		}
+		for _, a := range unsigned {
+			if a.Type.Equal(oidAttrTimeStampToken) {
+				// Values là SET chứa đúng một ContentInfo
+				var token asn1.RawValue
+				if _, err := asn1.Unmarshal(a.Values.Bytes, &token); err != nil {
+					return nil, err
+				}
+				res.TimestampToken = token.FullBytes
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,142 @@
+package cms
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/pkitest"
+	"github.com/vnkmasc/KmaERM/backend/internal/signer"
+)
+
+type nguoiKy struct {
+	sgn  signer.Signer
+	cert *x509.Certificate
+	ca   *x509.Certificate
+}
+
+// taoNguoiKy dựng CA tự ký và chứng thư người ký do CA đó cấp, giống CA nội bộ (package ca)
+func taoNguoiKy(t *testing.T) nguoiKy {
+	t.Helper()
+	ca := pkitest.CA(t, "KmaERM Test CA")
+	leaf := pkitest.NguoiKy(t, ca, "Nguyễn Văn A")
+	return nguoiKy{sgn: leaf.Signer(t), cert: leaf.Cert, ca: ca.Cert}
+}
+
+func TestSignDigestVerifyDigest(t *testing.T) {
+	nk := taoNguoiKy(t)
+	noiDung := []byte("%PDF-1.7 giấy phép 15/2025/GP-BCY")
+	digest := sha256.Sum256(noiDung)
+	kyLuc := time.Date(2025, time.March, 3, 9, 30, 0, 0, time.UTC)
+
+	der, err := SignDigest(digest[:], nk.sgn, nk.cert, []*x509.Certificate{nk.ca}, SignOptions{SigningTime: kyLuc})
+	if err != nil {
+		t.Fatalf("SignDigest: %v", err)
+	}
+
+	res, err := VerifyDigest(der, digest[:])
+	if err != nil {
+		t.Fatalf("VerifyDigest: %v", err)
+	}
+	if !res.Certificate.Equal(nk.cert) {
+		t.Error("chứng thư người ký không khớp")
+	}
+	if len(res.Certificates) != 2 {
+		t.Errorf("CMS chứa %d chứng thư, muốn 2 (người ký + CA)", len(res.Certificates))
+	}
+	if res.SigningTime == nil || !res.SigningTime.Equal(kyLuc) {
+		t.Errorf("SigningTime = %v, muốn %s", res.SigningTime, kyLuc)
+	}
+	if res.TimestampToken != nil {
+		t.Error("không có Timestamper thì không được có dấu thời gian")
+	}
+
+	// Phần đệm 0 phía sau (như /Contents của PDF) được bỏ qua
+	if _, err := VerifyDigest(append(der, make([]byte, 64)...), digest[:]); err != nil {
+		t.Errorf("VerifyDigest với phần đệm: %v", err)
+	}
+
+	khac := sha256.Sum256([]byte("nội dung đã bị sửa"))
+	if _, err := VerifyDigest(der, khac[:]); !errors.Is(err, ErrDigestKhongKhop) {
+		t.Errorf("digest khác: err = %v, muốn ErrDigestKhongKhop", err)
+	}
+
+	// Sửa một byte của giá trị chữ ký (nằm cuối SignerInfo, ngay trước hết cấu trúc)
+	hong := append([]byte(nil), der...)
+	hong[len(hong)-1] ^= 0xff
+	if _, err := VerifyDigest(hong, digest[:]); !errors.Is(err, ErrChuKyCMSKhongHopLe) {
+		t.Errorf("chữ ký bị sửa: err = %v, muốn ErrChuKyCMSKhongHopLe", err)
+	}
+
+	if _, err := SignDigest(digest[:16], nk.sgn, nk.cert, nil, SignOptions{}); err == nil {
+		t.Error("digest không phải SHA-256 phải bị từ chối")
+	}
+
+	kiemTraBangOpenSSL(t, der, noiDung, nk.ca)
+}
+
+func TestSignOptionsTimestamper(t *testing.T) {
+	nk := taoNguoiKy(t)
+	digest := sha256.Sum256([]byte("nội dung"))
+	token := []byte{0x30, 0x03, 0x02, 0x01, 0x07} // Bất kỳ cấu trúc DER nào; package tsa kiểm tra nội dung token
+
+	var daDongDau []byte
+	der, err := SignDigest(digest[:], nk.sgn, nk.cert, nil, SignOptions{Timestamper: func(sig []byte) ([]byte, error) {
+		daDongDau = sig
+		return token, nil
+	}})
+	if err != nil {
+		t.Fatalf("SignDigest: %v", err)
+	}
+	res, err := VerifyDigest(der, digest[:])
+	if err != nil {
+		t.Fatalf("VerifyDigest: %v", err)
+	}
+	if string(res.TimestampToken) != string(token) {
+		t.Errorf("TimestampToken = %x, muốn %x", res.TimestampToken, token)
+	}
+	if string(res.Signature) != string(daDongDau) {
+		t.Error("dấu thời gian phải được lấy trên đúng giá trị chữ ký")
+	}
+}
+
+func TestSignEncapsulated(t *testing.T) {
+	nk := taoNguoiKy(t)
+	contentType := []int{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	noiDung := []byte{0x30, 0x00}
+
+	der, err := SignEncapsulated(contentType, noiDung, nk.sgn, nk.cert, nil)
+	if err != nil {
+		t.Fatalf("SignEncapsulated: %v", err)
+	}
+	ct, content, res, err := VerifyEncapsulated(der)
+	if err != nil {
+		t.Fatalf("VerifyEncapsulated: %v", err)
+	}
+	if !ct.Equal(contentType) || string(content) != string(noiDung) || !res.Certificate.Equal(nk.cert) {
+		t.Errorf("VerifyEncapsulated = %v, %x", ct, content)
+	}
+
+	if _, err := VerifyDigest([]byte("không phải CMS"), nil); err == nil {
+		t.Error("dữ liệu không phải CMS phải trả lỗi")
+	}
+}
+
+// kiemTraBangOpenSSL: file .p7s hệ thống phát hành phải kiểm tra được bằng công cụ độc lập
+// (openssl cms -verify) với CA làm trust anchor
+func kiemTraBangOpenSSL(t *testing.T, p7s, noiDung []byte, ca *x509.Certificate) {
+	t.Helper()
+	pkitest.OpenSSL(t, map[string][]byte{
+		"signature.p7s": p7s,
+		"noi-dung.bin":  noiDung,
+		"ca.pem":        pkitest.PEM(ca),
+	}, "cms", "-verify", "-binary", "-inform", "DER",
+		"-in", "signature.p7s",
+		"-content", "noi-dung.bin",
+		"-CAfile", "ca.pem",
+		"-purpose", "any",
+		"-out", os.DevNull)
+}
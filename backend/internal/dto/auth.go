@@ -1,13 +1,39 @@
 package dto
 
+import "time"
+
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	// Bước 2 (nếu tài khoản bật 2FA): gửi 1 trong 2
+	TOTPCode     string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code"`
 }
 
 type LoginResponse struct {
-	AccessToken string      `json:"access_token"`
-	User        UserSummary `json:"user"`
+	AccessToken  string      `json:"access_token"`
+	RefreshToken string      `json:"refresh_token,omitempty"`
+	ExpiresIn    int64       `json:"expires_in"` // Số giây access token còn hiệu lực
+	User         UserSummary `json:"user"`
+	// Cán bộ/quản trị chưa bật 2FA: access token chỉ dùng được ở /auth/2fa/setup và /auth/2fa/enable,
+	// không có refresh token; frontend chuyển tới màn hình đăng ký TOTP rồi đăng nhập lại kèm mã
+	TOTPEnrollmentRequired bool `json:"totp_enrollment_required,omitempty"`
+}
+
+// ClientMeta là thông tin thiết bị gửi request, lưu kèm phiên đăng nhập
+type ClientMeta struct {
+	UserAgent string
+	IPAddress string
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
 }
 
 type UserSummary struct {
@@ -38,3 +64,38 @@ type VerifyOtpRequest struct {
 	Email string `json:"email" binding:"required,email"`
 	Otp   string `json:"otp" binding:"required,len=6"`
 }
+
+type TOTPSetupResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"` // Frontend render thành mã QR
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // Chỉ hiển thị MỘT lần
+}
+
+type ThrottleState struct {
+	FailCount   int        `json:"fail_count"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	Locked      bool       `json:"locked"`
+}
+
+// UserLockStateResponse: trạng thái khóa của tài khoản, hiển thị cho quản trị viên
+type UserLockStateResponse struct {
+	UserID         string        `json:"user_id"`
+	Email          string        `json:"email"`
+	IsActive       bool          `json:"is_active"` // Khóa thủ công bởi quản trị viên
+	Login          ThrottleState `json:"login"`     // Khóa tạm do đăng nhập sai
+	OTP            ThrottleState `json:"otp"`       // Khóa tạm do nhập sai OTP quên mật khẩu
+	TOTP           ThrottleState `json:"totp"`      // Khóa tạm do nhập sai mã TOTP khi ký số
+	OtpFailedCount int           `json:"otp_failed_count"`
+}
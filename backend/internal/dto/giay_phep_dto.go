@@ -12,19 +12,34 @@ type CreateGiayPhepRequest struct {
 
 	LoaiGiayPhep string `json:"loai_giay_phep" binding:"required"`
 
-	SoGiayPhep        string    `json:"so_giay_phep" binding:"required"`
+	// Bỏ trống để hệ thống cấp số theo mẫu; chỉ nhập tay khi vào sổ giấy phép đã cấp trên giấy,
+	// số nhập tay không được có dạng số theo mẫu MAU_SO_GIAY_PHEP
+	SoGiayPhep        string    `json:"so_giay_phep"`
 	NgayHieuLuc       time.Time `json:"ngay_hieu_luc" binding:"required"`
 	NgayHetHan        time.Time `json:"ngay_het_han" binding:"required"`
 	TrangThaiGiayPhep string    `json:"trang_thai_giay_phep" binding:"required"`
 }
 
+// CapGiayPhepRequest: các trường khác của giấy phép lấy từ danh mục thủ tục của hồ sơ
+type CapGiayPhepRequest struct {
+	// Bỏ trống: hiệu lực từ ngày cấp
+	NgayHieuLuc *time.Time `json:"ngay_hieu_luc"`
+}
+
+// UpdateGiayPhepRequest không nhận trạng thái giấy phép: trạng thái do hệ thống chuyển (ký số -> DaKy)
 type UpdateGiayPhepRequest struct {
 	LoaiGiayPhep string `json:"loai_giay_phep" binding:"required"`
 
-	SoGiayPhep        string    `json:"so_giay_phep" binding:"required"`
-	NgayHieuLuc       time.Time `json:"ngay_hieu_luc" binding:"required"`
-	NgayHetHan        time.Time `json:"ngay_het_han" binding:"required"`
-	TrangThaiGiayPhep string    `json:"trang_thai_giay_phep" binding:"required"`
+	SoGiayPhep  string    `json:"so_giay_phep" binding:"required"`
+	NgayHieuLuc time.Time `json:"ngay_hieu_luc" binding:"required"`
+	NgayHetHan  time.Time `json:"ngay_het_han" binding:"required"`
+}
+
+// KySoRequest: ký số là thao tác nhạy cảm, bắt buộc nhập lại mã TOTP (step-up).
+// LoaiChuKy: KyNhay (ký nháy, có thể nhiều người) hoặc KyChinh (mặc định, chữ ký phát hành giấy phép).
+type KySoRequest struct {
+	TOTPCode  string `json:"totp_code" binding:"required,len=6,numeric"`
+	LoaiChuKy string `json:"loai_chu_ky" binding:"omitempty,oneof=KyNhay KyChinh"`
 }
 
 type GiayPhepSearchParams struct {
@@ -59,12 +74,29 @@ type GiayPhepResponse struct {
 	CreatedAt           time.Time `json:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at"`
 
-	FileDuongDan *string `json:"file_duong_dan,omitempty"`
-	H1Hash       *string `json:"h1_hash,omitempty"`
-	H2Hash       *string `json:"h2_hash,omitempty"`
+	FileDuongDan    *string `json:"file_duong_dan,omitempty"`
+	FileGocDuongDan *string `json:"file_goc_duong_dan,omitempty"`
+	H1Hash          *string `json:"h1_hash,omitempty"`
+	H2Hash          *string `json:"h2_hash,omitempty"`
+	H2HashGoc       *string `json:"h2_hash_goc,omitempty"`
+
+	ChuKy []ChuKyResponse `json:"chu_ky"`
 
 	HoSo *models.HoSo `json:"ho_so,omitempty"`
 }
+
+// ChuKyResponse là một chữ ký trên giấy phép, theo thứ tự ký
+type ChuKyResponse struct {
+	ID            uuid.UUID    `json:"id"`
+	ThuTu         int          `json:"thu_tu"`
+	LoaiChuKy     string       `json:"loai_chu_ky"`
+	NguoiKy       *NguoiKyInfo `json:"nguoi_ky"`
+	NgayKy        time.Time    `json:"ngay_ky"`
+	H2Hash        string       `json:"h2_hash"` // Hash file ngay sau chữ ký này
+	ChungThuID    *uuid.UUID   `json:"chung_thu_id,omitempty"`
+	NhungPDF      bool         `json:"nhung_pdf"`
+	CoDauThoiGian bool         `json:"co_dau_thoi_gian"`
+}
 type AssetOnBlockchain struct {
 	ID     string `json:"id"`
 	H1Hash string `json:"h1Hash"`
@@ -83,5 +115,88 @@ type VerifyGiayPhepResponse struct {
 	IsH2Matched bool   `json:"is_h2_matched"`
 	Message     string `json:"message"`
 
+	// Dấu thời gian RFC 3161 lấy lúc đẩy (id, h1, h2) lên blockchain; nil với giấy phép đẩy trước khi hỗ trợ
+	DauThoiGian        *DauThoiGianInfo `json:"dau_thoi_gian,omitempty"`
+	IsDauThoiGianHopLe bool             `json:"is_dau_thoi_gian_hop_le"`
+
 	GiayPhepData *GiayPhepResponse `json:"giay_phep_data,omitempty"`
 }
+
+// Tên các bước kiểm tra chữ ký số (trường failed_check)
+const (
+	KiemTraDaKy        = "da_ky"
+	KiemTraFileTonTai  = "file_ton_tai"
+	KiemTraHashFile    = "hash_file"
+	KiemTraPublicKey   = "public_key"
+	KiemTraChuKy       = "chu_ky"
+	KiemTraDauThoiGian = "dau_thoi_gian"
+	KiemTraChungThu    = "chung_thu"
+	KiemTraPAdES       = "pades"
+	KiemTraCMS         = "cms"
+)
+
+// DauThoiGianInfo là dấu thời gian RFC 3161 đã kiểm tra
+type DauThoiGianInfo struct {
+	ThoiDiem     time.Time `json:"thoi_diem"` // genTime do TSA xác nhận
+	TSA          string    `json:"tsa"`
+	SerialNumber string    `json:"serial_number"`
+}
+
+// ChuKyPDFInfo là chữ ký PAdES nhúng trong file PDF đã ký
+type ChuKyPDFInfo struct {
+	SubjectCN    string `json:"subject_cn"`
+	SerialNumber string `json:"serial_number"`
+}
+
+type ChungThuInfo struct {
+	SerialNumber string     `json:"serial_number"`
+	SubjectCN    string     `json:"subject_cn"`
+	Email        string     `json:"email"`
+	Issuer       string     `json:"issuer,omitempty"`
+	NotBefore    time.Time  `json:"not_before"`
+	NotAfter     time.Time  `json:"not_after"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+type NguoiKyInfo struct {
+	ID    string `json:"id"`
+	HoTen string `json:"ho_ten"`
+	KeyID string `json:"key_id,omitempty"` // Fingerprint public key đã dùng để ký
+}
+
+// KetQuaChuKy là kết quả kiểm tra độc lập một chữ ký trên giấy phép
+type KetQuaChuKy struct {
+	ThuTu       int    `json:"thu_tu"`
+	LoaiChuKy   string `json:"loai_chu_ky"`
+	IsValid     bool   `json:"is_valid"`
+	FailedCheck string `json:"failed_check,omitempty"`
+	Message     string `json:"message"`
+	H2Hash      string `json:"h2_hash"` // Hash bản file mà chữ ký này phủ
+
+	NguoiKy     *NguoiKyInfo     `json:"nguoi_ky,omitempty"`
+	NgayKy      *time.Time       `json:"ngay_ky,omitempty"`
+	DauThoiGian *DauThoiGianInfo `json:"dau_thoi_gian,omitempty"`
+	ChungThu    *ChungThuInfo    `json:"chung_thu,omitempty"`
+	ChuKyPDF    *ChuKyPDFInfo    `json:"chu_ky_pdf,omitempty"`
+}
+
+// VerifySignatureResponse: các trường người ký, ngày ký, chứng thư... ở cấp ngoài lấy theo chữ ký
+// thất bại đầu tiên, hoặc chữ ký cuối cùng nếu mọi chữ ký đều hợp lệ. Chi tiết từng chữ ký nằm trong ChuKy.
+type VerifySignatureResponse struct {
+	GiayPhepID string `json:"giay_phep_id"`
+	IsValid    bool   `json:"is_valid"`
+	// Bước kiểm tra đầu tiên thất bại (rỗng nếu hợp lệ)
+	FailedCheck string `json:"failed_check,omitempty"`
+	Message     string `json:"message"`
+
+	H2HashDB       string `json:"h2_hash_db,omitempty"`
+	H2HashComputed string `json:"h2_hash_computed,omitempty"` // Tính lại từ file trên đĩa
+
+	NguoiKy     *NguoiKyInfo     `json:"nguoi_ky,omitempty"`
+	NgayKy      *time.Time       `json:"ngay_ky,omitempty"`
+	DauThoiGian *DauThoiGianInfo `json:"dau_thoi_gian,omitempty"`
+	ChungThu    *ChungThuInfo    `json:"chung_thu,omitempty"`
+	ChuKyPDF    *ChuKyPDFInfo    `json:"chu_ky_pdf,omitempty"`
+
+	ChuKy []KetQuaChuKy `json:"chu_ky"`
+}
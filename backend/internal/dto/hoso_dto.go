@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -36,20 +37,27 @@ type HoSoListResponse struct {
 	Total    int64 `json:"total"`
 }
 type GroupedLoaiTaiLieuResponse struct {
-	TenThuTuc string               `json:"ten_thu_tuc"`
-	TaiLieus  []models.LoaiTaiLieu `json:"tai_lieus"`
+	TenThuTuc string                      `json:"ten_thu_tuc"`
+	TaiLieus  []LoaiTaiLieuThuTucResponse `json:"tai_lieus"`
 }
 type HoSoDetailsResponse struct {
-	ID                 uuid.UUID  `json:"id"`
-	DoanhNghiepID      uuid.UUID  `json:"doanh_nghiep_id"`
-	TenDoanhnghiepVI   string     `json:"ten_doanh_nghiep_vi"`
-	MaHoSo             string     `json:"ma_ho_so"`
-	LoaiThuTuc         string     `json:"loai_thu_tuc"`
-	NgayDangKy         time.Time  `json:"ngay_dang_ky"`
-	NgayTiepNhan       *time.Time `json:"ngay_tiep_nhan,omitempty"`
-	NgayHenTra         *time.Time `json:"ngay_hen_tra,omitempty"`
-	SoGiayPhepTheoHoSo string     `json:"so_giay_phep_theo_ho_so,omitempty"`
-	TrangThaiHoSo      string     `json:"trang_thai_ho_so"`
+	ID                 uuid.UUID          `json:"id"`
+	DoanhNghiepID      uuid.UUID          `json:"doanh_nghiep_id"`
+	TenDoanhnghiepVI   string             `json:"ten_doanh_nghiep_vi"`
+	MaHoSo             string             `json:"ma_ho_so"`
+	LoaiThuTuc         string             `json:"loai_thu_tuc"`
+	NgayDangKy         time.Time          `json:"ngay_dang_ky"`
+	NgayTiepNhan       *time.Time         `json:"ngay_tiep_nhan,omitempty"`
+	NgayHenTra         *time.Time         `json:"ngay_hen_tra,omitempty"`
+	SoGiayPhepTheoHoSo string             `json:"so_giay_phep_theo_ho_so,omitempty"`
+	TrangThaiHoSo      string             `json:"trang_thai_ho_so"`
+	LyDoTraLai         *string            `json:"ly_do_tra_lai,omitempty"`
+	SoLanBoSung        int                `json:"so_lan_bo_sung"`
+	SoNgayTamDung      int                `json:"so_ngay_tam_dung"`
+	CanBoXuLy          *NguoiThucHienInfo `json:"can_bo_xu_ly,omitempty"`
+	NgayPhanCong       *time.Time         `json:"ngay_phan_cong,omitempty"`
+	// Các thao tác chuyển trạng thái người xem được thực hiện từ trạng thái hiện tại (chỉ có ở API chi tiết)
+	ThaoTacHopLe []string `json:"thao_tac_hop_le,omitempty"`
 	// CreatedAt          time.Time  `json:"created_at"`
 	// UpdatedAt          time.Time  `json:"updated_at"`
 
@@ -63,6 +71,7 @@ func ToHoSoTaiLieuResponse(hoSoTaiLieu models.HoSoTaiLieu) HoSoTaiLieuResponse {
 		ID:          hoSoTaiLieu.ID,
 		LoaiTaiLieu: hoSoTaiLieu.LoaiTaiLieu, // Giả định models.LoaiTaiLieu có thể gán trực tiếp
 		TaiLieus:    hoSoTaiLieu.TaiLieus,    // Giả định []models.TaiLieu có thể gán trực tiếp
+		BatBuoc:     hoSoTaiLieu.BatBuoc,
 	}
 }
 
@@ -88,11 +97,19 @@ func ToHoSoDetailsResponse(hoSo *models.HoSo) HoSoDetailsResponse {
 		NgayHenTra:         ngayHenTraPtr,
 		SoGiayPhepTheoHoSo: hoSo.SoGiayPhepTheoHoSo,
 		TrangThaiHoSo:      hoSo.TrangThaiHoSo,
+		LyDoTraLai:         hoSo.LyDoTraLai,
+		SoLanBoSung:        hoSo.SoLanBoSung,
+		SoNgayTamDung:      hoSo.SoNgayTamDung,
+		NgayPhanCong:       hoSo.NgayPhanCong,
 		// CreatedAt:          hoSo.CreatedAt,
 		// UpdatedAt:          hoSo.UpdatedAt,
 		HoSoTaiLieus: make([]HoSoTaiLieuResponse, len(hoSo.HoSoTaiLieus)),
 	}
 
+	if hoSo.CanBoXuLy != nil {
+		response.CanBoXuLy = &NguoiThucHienInfo{ID: hoSo.CanBoXuLy.ID, HoTen: hoSo.CanBoXuLy.FullName}
+	}
+
 	for i, hstl := range hoSo.HoSoTaiLieus {
 		response.HoSoTaiLieus[i] = ToHoSoTaiLieuResponse(hstl)
 	}
@@ -103,6 +120,7 @@ func ToHoSoDetailsResponse(hoSo *models.HoSo) HoSoDetailsResponse {
 type HoSoTaiLieuResponse struct {
 	ID          uuid.UUID          `json:"id"`
 	LoaiTaiLieu models.LoaiTaiLieu `json:"loai_tai_lieu"`
+	BatBuoc     bool               `json:"bat_buoc"`
 	TaiLieus    []models.TaiLieu   `json:"tai_lieus,omitempty"`
 }
 
@@ -111,10 +129,117 @@ type UploadTaiLieuRequest struct {
 	TieuDe        string    `form:"tieu_de,omitempty"`
 }
 
+// UpdateHoSoRequest: ngày tiếp nhận và hạn trả do thao tác tiếp nhận ghi, số giấy phép của hồ sơ chỉ được gắn khi cấp giấy phép
 type UpdateHoSoRequest struct {
-	NgayDangKy         time.Time `json:"ngay_dang_ky" binding:"required"`
-	NgayTiepNhan       time.Time `json:"ngay_tiep_nhan" binding:"required"`
-	NgayHenTra         time.Time `json:"ngay_hen_tra" binding:"required"`
-	SoGiayPhepTheoHoSo string    `json:"so_giay_phep_theo_ho_so,omitempty"`
-	TrangThaiHoSo      string    `json:"trang_thai_ho_so" binding:"required"`
+	NgayDangKy time.Time `json:"ngay_dang_ky" binding:"required"`
+}
+
+// PhanCongHoSoRequest giao hồ sơ cho một cán bộ; lý do bắt buộc khi hồ sơ đã có người xử lý (phân công lại)
+type PhanCongHoSoRequest struct {
+	CanBoID uuid.UUID `json:"can_bo_id" binding:"required"`
+	LyDo    string    `json:"ly_do"`
+}
+
+// ChuyenTrangThaiHoSoRequest: lý do bắt buộc khi trả lại hồ sơ, tùy chọn với các thao tác khác.
+// TaiLieu chỉ dùng khi trả lại: các khe tài liệu doanh nghiệp cần bổ sung, kèm lý do từng khe.
+type ChuyenTrangThaiHoSoRequest struct {
+	LyDo    string                `json:"ly_do"`
+	TaiLieu []KheTaiLieuCanBoSung `json:"tai_lieu" binding:"omitempty,dive"`
+}
+
+type KheTaiLieuCanBoSung struct {
+	HoSoTaiLieuID uuid.UUID `json:"ho_so_tai_lieu_id" binding:"required"`
+	LyDo          string    `json:"ly_do" binding:"required"`
+}
+
+// YeuCauBoSungResponse là một vòng yêu cầu bổ sung hồ sơ
+type YeuCauBoSungResponse struct {
+	ID         uuid.UUID                  `json:"id"`
+	Lan        int                        `json:"lan"`
+	NoiDung    string                     `json:"noi_dung"`
+	TrangThai  string                     `json:"trang_thai"`
+	NgayYeuCau time.Time                  `json:"ngay_yeu_cau"`
+	NgayNopLai *time.Time                 `json:"ngay_nop_lai,omitempty"`
+	TaiLieus   []KheTaiLieuBoSungResponse `json:"tai_lieus"`
+}
+
+// TaiLieuConThieuResponse là một khe tài liệu bắt buộc chưa có tài liệu khi nộp hồ sơ
+type TaiLieuConThieuResponse struct {
+	HoSoTaiLieuID  uuid.UUID `json:"ho_so_tai_lieu_id"`
+	LoaiTaiLieuID  uuid.UUID `json:"loai_tai_lieu_id"`
+	TenLoaiTaiLieu string    `json:"ten_loai_tai_lieu"`
+}
+
+type KheTaiLieuBoSungResponse struct {
+	HoSoTaiLieuID  uuid.UUID  `json:"ho_so_tai_lieu_id"`
+	TenLoaiTaiLieu string     `json:"ten_loai_tai_lieu"`
+	LyDo           string     `json:"ly_do"`
+	DaBoSung       bool       `json:"da_bo_sung"`
+	NgayBoSung     *time.Time `json:"ngay_bo_sung,omitempty"`
+}
+
+func ToYeuCauBoSungResponse(yc *models.YeuCauBoSung) YeuCauBoSungResponse {
+	resp := YeuCauBoSungResponse{
+		ID:         yc.ID,
+		Lan:        yc.Lan,
+		NoiDung:    yc.NoiDung,
+		TrangThai:  yc.TrangThai,
+		NgayYeuCau: yc.CreatedAt,
+		NgayNopLai: yc.NgayNopLai,
+		TaiLieus:   make([]KheTaiLieuBoSungResponse, len(yc.TaiLieus)),
+	}
+	for i, tl := range yc.TaiLieus {
+		resp.TaiLieus[i] = ToKheTaiLieuBoSungResponse(&tl)
+	}
+	return resp
+}
+
+func ToKheTaiLieuBoSungResponse(tl *models.YeuCauBoSungTaiLieu) KheTaiLieuBoSungResponse {
+	return KheTaiLieuBoSungResponse{
+		HoSoTaiLieuID:  tl.HoSoTaiLieuID,
+		TenLoaiTaiLieu: tl.HoSoTaiLieu.LoaiTaiLieu.Ten,
+		LyDo:           tl.LyDo,
+		DaBoSung:       tl.DaBoSung,
+		NgayBoSung:     tl.NgayBoSung,
+	}
+}
+
+// NguoiThucHienInfo là người thực hiện một sự kiện trong nhật ký hồ sơ
+type NguoiThucHienInfo struct {
+	ID    uuid.UUID `json:"id"`
+	HoTen string    `json:"ho_ten"`
+}
+
+// HoSoLichSuResponse là một sự kiện trên dòng thời gian xử lý hồ sơ
+type HoSoLichSuResponse struct {
+	ID             uuid.UUID          `json:"id"`
+	LoaiSuKien     string             `json:"loai_su_kien"`
+	NguoiThucHien  *NguoiThucHienInfo `json:"nguoi_thuc_hien,omitempty"` // nil: hệ thống tự thực hiện
+	TrangThaiTruoc *string            `json:"trang_thai_truoc,omitempty"`
+	TrangThaiSau   *string            `json:"trang_thai_sau,omitempty"`
+	DuLieuTruoc    json.RawMessage    `json:"du_lieu_truoc,omitempty"`
+	DuLieuSau      json.RawMessage    `json:"du_lieu_sau,omitempty"`
+	GhiChu         *string            `json:"ghi_chu,omitempty"`
+	ThoiDiem       time.Time          `json:"thoi_diem"`
+}
+
+func ToHoSoLichSuResponse(row *models.HoSoLichSu) HoSoLichSuResponse {
+	resp := HoSoLichSuResponse{
+		ID:             row.ID,
+		LoaiSuKien:     row.LoaiSuKien,
+		TrangThaiTruoc: row.TrangThaiTruoc,
+		TrangThaiSau:   row.TrangThaiSau,
+		GhiChu:         row.GhiChu,
+		ThoiDiem:       row.CreatedAt,
+	}
+	if row.NguoiThucHien != nil {
+		resp.NguoiThucHien = &NguoiThucHienInfo{ID: row.NguoiThucHien.ID, HoTen: row.NguoiThucHien.FullName}
+	}
+	if row.DuLieuTruoc != nil {
+		resp.DuLieuTruoc = json.RawMessage(*row.DuLieuTruoc)
+	}
+	if row.DuLieuSau != nil {
+		resp.DuLieuSau = json.RawMessage(*row.DuLieuSau)
+	}
+	return resp
 }
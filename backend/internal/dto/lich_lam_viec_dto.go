@@ -0,0 +1,8 @@
+package dto
+
+// LuuNgayNghiRequest thêm hoặc sửa một ngày trong lịch nghỉ lễ (ngày nằm trên đường dẫn)
+type LuuNgayNghiRequest struct {
+	Ten string `json:"ten" binding:"required"`
+	// true: ngày cuối tuần phải đi làm bù thay vì ngày nghỉ
+	LaNgayLamBu bool `json:"la_ngay_lam_bu"`
+}
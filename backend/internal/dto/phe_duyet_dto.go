@@ -0,0 +1,71 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type BuocDuyetInput struct {
+	TenBuoc string `json:"ten_buoc" binding:"required"`
+	ChucVu  string `json:"chuc_vu" binding:"required,max=50"`
+}
+
+// CauHinhQuyTrinhRequest thay toàn bộ quy trình của một loại giấy phép; danh sách rỗng để bỏ quy trình
+type CauHinhQuyTrinhRequest struct {
+	LoaiGiayPhep string           `json:"loai_giay_phep" binding:"required,max=100"`
+	CacBuoc      []BuocDuyetInput `json:"cac_buoc" binding:"dive"`
+}
+
+type UpdateChucVuRequest struct {
+	// Rỗng để gỡ chức vụ
+	ChucVu string `json:"chuc_vu" binding:"max=50"`
+}
+
+type PheDuyetRequest struct {
+	QuyetDinh string `json:"quyet_dinh" binding:"required,oneof=DongY TuChoi"`
+	YKien     string `json:"y_kien" binding:"required_if=QuyetDinh TuChoi"`
+}
+
+// Trạng thái của một bước trong lượt duyệt hiện tại
+const (
+	TrangThaiBuocDaDuyet  = "DaDuyet"
+	TrangThaiBuocChoDuyet = "ChoDuyet"
+	TrangThaiBuocChuaToi  = "ChuaToi"
+)
+
+type BuocDuyetStatus struct {
+	ThuTu      int             `json:"thu_tu"`
+	TenBuoc    string          `json:"ten_buoc"`
+	ChucVu     string          `json:"chuc_vu"`
+	LaBuocKy   bool            `json:"la_buoc_ky"`
+	TrangThai  string          `json:"trang_thai"`
+	NguoiDuyet *NguoiDuyetInfo `json:"nguoi_duyet,omitempty"`
+	ThoiDiem   *time.Time      `json:"thoi_diem,omitempty"`
+}
+
+type NguoiDuyetInfo struct {
+	ID    uuid.UUID `json:"id"`
+	HoTen string    `json:"ho_ten"`
+}
+
+type PheDuyetLichSu struct {
+	ID         uuid.UUID      `json:"id"`
+	ThuTu      int            `json:"thu_tu"`
+	TenBuoc    string         `json:"ten_buoc"`
+	ChucVu     string         `json:"chuc_vu"`
+	NguoiDuyet NguoiDuyetInfo `json:"nguoi_duyet"`
+	QuyetDinh  string         `json:"quyet_dinh"`
+	YKien      *string        `json:"y_kien,omitempty"`
+	H2Hash     string         `json:"h2_hash"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+type PheDuyetStatusResponse struct {
+	GiayPhepID   uuid.UUID         `json:"giay_phep_id"`
+	LoaiGiayPhep string            `json:"loai_giay_phep"`
+	CacBuoc      []BuocDuyetStatus `json:"cac_buoc"`
+	// Đã duyệt đủ mọi bước trước bước ký
+	SanSangKy bool             `json:"san_sang_ky"`
+	LichSu    []PheDuyetLichSu `json:"lich_su"`
+}
@@ -0,0 +1,73 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Phân loại SLA của hồ sơ đang giải quyết
+const (
+	SLADungHan   = "DungHan"
+	SLASapDenHan = "SapDenHan"
+	SLAQuaHan    = "QuaHan"
+	// Hồ sơ bị trả lại chờ bổ sung: đồng hồ thời hạn đang dừng
+	SLATamDung = "TamDung"
+)
+
+type SoLuongSLA struct {
+	DungHan   int64 `json:"dung_han"`
+	SapDenHan int64 `json:"sap_den_han"`
+	QuaHan    int64 `json:"qua_han"`
+	TamDung   int64 `json:"tam_dung"`
+	Tong      int64 `json:"tong"`
+}
+
+type SLATheoCanBo struct {
+	// nil: hồ sơ chưa được phân công
+	CanBoID *uuid.UUID `json:"can_bo_id"`
+	HoTen   *string    `json:"ho_ten"`
+	SoLuongSLA
+}
+
+type SLATheoThuTuc struct {
+	LoaiThuTuc string `json:"loai_thu_tuc"`
+	SoLuongSLA
+}
+
+type ThongKeSLAResponse struct {
+	ThoiDiem time.Time `json:"thoi_diem"`
+	// Hồ sơ có hạn trả không muộn hơn mốc này (và chưa quá hạn) là sắp đến hạn
+	MocSapDenHan    time.Time       `json:"moc_sap_den_han"`
+	SoNgaySapDenHan int             `json:"so_ngay_sap_den_han"`
+	Tong            SoLuongSLA      `json:"tong"`
+	TheoCanBo       []SLATheoCanBo  `json:"theo_can_bo"`
+	TheoThuTuc      []SLATheoThuTuc `json:"theo_thu_tuc"`
+}
+
+type HoSoQuaHanSearchParams struct {
+	LoaiThuTuc string `form:"loai_thu_tuc"`
+	CanBoID    string `form:"can_bo_id"`
+}
+
+type HoSoQuaHanResponse struct {
+	ID               uuid.UUID  `json:"id"`
+	MaHoSo           string     `json:"ma_ho_so"`
+	LoaiThuTuc       string     `json:"loai_thu_tuc"`
+	DoanhNghiepID    uuid.UUID  `json:"doanh_nghiep_id"`
+	TenDoanhNghiepVI string     `json:"ten_doanh_nghiep_vi"`
+	TrangThaiHoSo    string     `json:"trang_thai_ho_so"`
+	NgayTiepNhan     time.Time  `json:"ngay_tiep_nhan"`
+	NgayHenTra       time.Time  `json:"ngay_hen_tra"`
+	SoNgayQuaHan     int        `json:"so_ngay_qua_han"`
+	CanBoID          *uuid.UUID `json:"can_bo_id"`
+	HoTenCanBo       *string    `json:"ho_ten_can_bo"`
+}
+
+type HoSoQuaHanListResponse struct {
+	Data []HoSoQuaHanResponse `json:"data"`
+
+	Page     int   `json:"page"`
+	PageSize int   `json:"page_size"`
+	Total    int64 `json:"total"`
+}
@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+)
+
+type ThuTucTaiLieuInput struct {
+	LoaiTaiLieuID uuid.UUID `json:"loai_tai_lieu_id" binding:"required"`
+	// Bỏ trống: bắt buộc
+	BatBuoc *bool `json:"bat_buoc"`
+}
+
+// ThuTucRequest tạo hoặc sửa thủ tục; TaiLieus thay toàn bộ thành phần hồ sơ, theo đúng thứ tự gửi lên
+type ThuTucRequest struct {
+	Ten           string  `json:"ten" binding:"required,max=255"`
+	MoTa          *string `json:"mo_ta"`
+	SoNgayLamViec int     `json:"so_ngay_lam_viec" binding:"required,min=1,max=365"`
+	// Bỏ trống: thủ tục không cấp giấy phép
+	LoaiGiayPhep *string `json:"loai_giay_phep" binding:"omitempty,max=100"`
+	// Bắt buộc khi thủ tục cấp giấy phép
+	SoThangHieuLuc *int `json:"so_thang_hieu_luc" binding:"omitempty,min=1,max=1200"`
+	// Bỏ trống: đang hoạt động
+	HoatDong     *bool                `json:"hoat_dong"`
+	ThuTuHienThi int                  `json:"thu_tu_hien_thi"`
+	TaiLieus     []ThuTucTaiLieuInput `json:"tai_lieus" binding:"required,min=1,dive"`
+}
+
+type CreateLoaiTaiLieuRequest struct {
+	Ten  string `json:"ten" binding:"required,max=255"`
+	MoTa string `json:"mo_ta"`
+}
+
+// LoaiTaiLieuThuTucResponse là một thành phần hồ sơ của thủ tục
+type LoaiTaiLieuThuTucResponse struct {
+	models.LoaiTaiLieu
+	BatBuoc bool `json:"bat_buoc"`
+}
+
+func ToLoaiTaiLieuThuTucResponses(thuTuc *models.ThuTuc) []LoaiTaiLieuThuTucResponse {
+	list := make([]LoaiTaiLieuThuTucResponse, len(thuTuc.TaiLieus))
+	for i, tl := range thuTuc.TaiLieus {
+		list[i] = LoaiTaiLieuThuTucResponse{LoaiTaiLieu: tl.LoaiTaiLieu, BatBuoc: tl.BatBuoc}
+	}
+	return list
+}
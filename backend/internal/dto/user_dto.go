@@ -5,3 +5,23 @@ type CreateCanBoRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 	FullName string `json:"full_name" binding:"required"`
 }
+
+type UpdateUserStatusRequest struct {
+	IsActive *bool `json:"is_active" binding:"required"`
+}
+
+// UpdateSignerRequest chọn nơi giữ khóa ký của cán bộ
+type UpdateSignerRequest struct {
+	SignerType  string `json:"signer_type" binding:"required,oneof=db pkcs11"`
+	HSMKeyLabel string `json:"hsm_key_label" binding:"required_if=SignerType pkcs11"`
+}
+
+type SignerInfoResponse struct {
+	SignerType   string `json:"signer_type"`
+	KeyID        string `json:"key_id"`
+	PublicKeyPEM string `json:"public_key_pem"`
+}
+
+type RevokeChungThuRequest struct {
+	LyDo string `json:"ly_do" binding:"required"`
+}
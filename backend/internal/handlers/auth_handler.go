@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
 	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/router"
 	"github.com/vnkmasc/KmaERM/backend/internal/service"
 )
 
@@ -17,20 +21,23 @@ func NewAuthHandler(userService service.UserService) *AuthHandler {
 	return &AuthHandler{userService: userService}
 }
 
-func (h *AuthHandler) RegisterRoutes(public *gin.RouterGroup, protected *gin.RouterGroup) {
+func (h *AuthHandler) Routes() []router.Route {
+	return []router.Route{
+		{Method: http.MethodPost, Path: "/auth/login", Access: router.PublicWrite, Handler: h.Login},
+		{Method: http.MethodPost, Path: "/auth/forgot-password", Access: router.PublicWrite, Handler: h.ForgotPassword},
+		{Method: http.MethodPost, Path: "/auth/verify-otp", Access: router.PublicWrite, Handler: h.VerifyOTP},
+		{Method: http.MethodPost, Path: "/auth/reset-password", Access: router.PublicWrite, Handler: h.ResetPassword},
+		{Method: http.MethodPost, Path: "/auth/refresh", Access: router.PublicWrite, Handler: h.Refresh},
 
-	publicAuth := public.Group("/auth")
-	{
-		publicAuth.POST("/login", h.Login)
-		publicAuth.POST("/forgot-password", h.ForgotPassword)
-		publicAuth.POST("/verify-otp", h.VerifyOTP)
-		publicAuth.POST("/reset-password", h.ResetPassword)
-	}
-
-	protectedAuth := protected.Group("/auth")
-	{
-		protectedAuth.POST("/change-password", h.ChangePassword)
+		{Method: http.MethodPost, Path: "/auth/change-password", Access: router.Protected, Handler: h.ChangePassword},
+		{Method: http.MethodPost, Path: "/auth/logout", Access: router.Protected, Handler: h.Logout},
+		{Method: http.MethodPost, Path: "/auth/logout-all", Access: router.Protected, Handler: h.LogoutAll},
 
+		// Xác thực 2 lớp (TOTP)
+		{Method: http.MethodPost, Path: "/auth/2fa/setup", Access: router.TOTPEnrollment, Handler: h.SetupTOTP},
+		{Method: http.MethodPost, Path: "/auth/2fa/enable", Access: router.TOTPEnrollment, Handler: h.EnableTOTP},
+		{Method: http.MethodPost, Path: "/auth/2fa/disable", Access: router.Protected, Handler: h.DisableTOTP},
+		{Method: http.MethodPost, Path: "/auth/2fa/recovery-codes", Access: router.Protected, Handler: h.RegenerateRecoveryCodes},
 	}
 }
 
@@ -41,7 +48,10 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.ForgotPassword(req.Email); err != nil {
+	if err := h.userService.ForgotPassword(req.Email, clientMeta(c)); err != nil {
+		if respondLocked(c, err) {
+			return
+		}
 		// Bảo mật: Nên trả về 200 dù email có tồn tại hay không để tránh dò user
 		// Nhưng demo thì cứ return lỗi cho dễ biết
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -58,7 +68,10 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 	}
 
 	// Gọi Service kiểm tra
-	if err := h.userService.VerifyOTP(req.Email, req.Otp); err != nil {
+	if err := h.userService.VerifyOTP(req.Email, req.Otp, clientMeta(c)); err != nil {
+		if respondLocked(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -74,7 +87,10 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.ResetPassword(&req); err != nil {
+	if err := h.userService.ResetPassword(&req, clientMeta(c)); err != nil {
+		if respondLocked(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -122,8 +138,16 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	res, err := h.userService.Login(&req)
+	res, err := h.userService.Login(&req, clientMeta(c))
 	if err != nil {
+		if respondLocked(c, err) {
+			return
+		}
+		// Client hiển thị ô nhập mã TOTP rồi gửi lại request kèm totp_code
+		if errors.Is(err, service.ErrCanMaHaiLop) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "totp_required": true})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
@@ -133,3 +157,152 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		"data":    res,
 	})
 }
+
+// respondLocked trả 429 + Retry-After nếu tài khoản/IP đang bị khóa tạm do nhập sai nhiều lần
+func respondLocked(c *gin.Context, err error) bool {
+	if !errors.Is(err, service.ErrTamKhoaDangNhap) {
+		return false
+	}
+
+	var locked *service.LockedError
+	if errors.As(err, &locked) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter().Seconds()))))
+	}
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	return true
+}
+
+func clientMeta(c *gin.Context) dto.ClientMeta {
+	return dto.ClientMeta{UserAgent: c.Request.UserAgent(), IPAddress: c.ClientIP()}
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req dto.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Thiếu refresh token"})
+		return
+	}
+
+	res, err := h.userService.Refresh(req.RefreshToken, clientMeta(c))
+	if err != nil {
+		if errors.Is(err, service.ErrRefreshTokenKhongHopLe) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionID, ok := c.Get("sessionID")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Không xác định được phiên đăng nhập"})
+		return
+	}
+
+	if err := h.userService.Logout(sessionID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Đăng xuất thành công"})
+}
+
+// LogoutAll đăng xuất khỏi mọi thiết bị (thu hồi mọi phiên và access token đang còn hạn)
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, ok := c.Get("userID")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Không xác định được người dùng (Chưa đăng nhập)"})
+		return
+	}
+
+	if err := h.userService.LogoutAll(userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Đã đăng xuất khỏi tất cả thiết bị"})
+}
+
+// respondTOTPError ánh xạ lỗi 2FA sang mã HTTP
+func respondTOTPError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrMaHaiLopKhongDung), errors.Is(err, service.ErrMatKhauKhongDung):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrHaiLopDaBat), errors.Is(err, service.ErrHaiLopChuaBat), errors.Is(err, service.ErrHaiLopChuaKhoiTao):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrKhongTheTatHaiLop):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNguoiDungKhongTonTai):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+	}
+}
+
+// SetupTOTP trả về secret + URI otpauth:// để quét QR bằng ứng dụng Authenticator
+func (h *AuthHandler) SetupTOTP(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	res, err := h.userService.SetupTOTP(userID)
+	if err != nil {
+		respondTOTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+// EnableTOTP xác nhận mã đầu tiên từ ứng dụng rồi bật 2FA, trả về mã khôi phục (chỉ một lần)
+func (h *AuthHandler) EnableTOTP(c *gin.Context) {
+	var req dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mã xác thực phải gồm 6 chữ số"})
+		return
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	res, err := h.userService.EnableTOTP(userID, req.Code)
+	if err != nil {
+		respondTOTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Đã bật xác thực 2 lớp. Hãy lưu mã khôi phục ở nơi an toàn.", "data": res})
+}
+
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	var req dto.DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cần mật khẩu và mã xác thực 6 chữ số"})
+		return
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	if err := h.userService.DisableTOTP(userID, &req); err != nil {
+		respondTOTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Đã tắt xác thực 2 lớp"})
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mã xác thực phải gồm 6 chữ số"})
+		return
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	res, err := h.userService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		respondTOTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Đã tạo bộ mã khôi phục mới, các mã cũ không còn hiệu lực", "data": res})
+}
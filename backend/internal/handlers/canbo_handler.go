@@ -6,8 +6,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid"
 	"github.com/vnkmasc/KmaERM/backend/internal/dto"
 	"github.com/vnkmasc/KmaERM/backend/internal/helper"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/router"
 	"github.com/vnkmasc/KmaERM/backend/internal/service"
 )
 
@@ -19,11 +22,15 @@ func NewCanBoHandler(s service.UserService) *CanBoHandler {
 	return &CanBoHandler{service: s}
 }
 
-func (h *CanBoHandler) RegisterRoutes(rg *gin.RouterGroup) {
+func (h *CanBoHandler) Routes() []router.Route {
+	admin := []string{middleware.RoleAdmin}
 
-	adminGroup := rg.Group("/admin")
-	{
-		adminGroup.POST("/create-can-bo", h.Create)
+	return []router.Route{
+		{Method: http.MethodPost, Path: "/admin/create-can-bo", Access: router.RoleRestricted, Roles: admin, Handler: h.Create},
+		{Method: http.MethodPut, Path: "/admin/users/:id/trang-thai", Access: router.RoleRestricted, Roles: admin, Handler: h.UpdateStatus},
+		{Method: http.MethodPut, Path: "/admin/users/:id/signer", Access: router.RoleRestricted, Roles: admin, Handler: h.UpdateSigner},
+		{Method: http.MethodGet, Path: "/admin/users/:id/khoa", Access: router.RoleRestricted, Roles: admin, Handler: h.GetLockState},
+		{Method: http.MethodDelete, Path: "/admin/users/:id/khoa", Access: router.RoleRestricted, Roles: admin, Handler: h.Unlock},
 	}
 }
 
@@ -56,3 +63,107 @@ func (h *CanBoHandler) Create(c *gin.Context) {
 		"role":    "CAN_BO",
 	})
 }
+
+// UpdateStatus khóa/mở khóa tài khoản. Khóa sẽ thu hồi ngay mọi phiên đăng nhập của tài khoản.
+func (h *CanBoHandler) UpdateStatus(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID người dùng không hợp lệ"})
+		return
+	}
+
+	var input dto.UpdateUserStatusRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dữ liệu không hợp lệ (cần is_active)"})
+		return
+	}
+
+	if err := h.service.SetActive(id, *input.IsActive); err != nil {
+		if errors.Is(err, service.ErrNguoiDungKhongTonTai) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cập nhật trạng thái tài khoản thành công", "is_active": *input.IsActive})
+}
+
+// GetLockState xem tài khoản có đang bị khóa tạm do đăng nhập/OTP sai nhiều lần không
+func (h *CanBoHandler) GetLockState(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID người dùng không hợp lệ"})
+		return
+	}
+
+	res, err := h.service.GetLockState(id)
+	if err != nil {
+		if errors.Is(err, service.ErrNguoiDungKhongTonTai) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+// Unlock gỡ khóa tạm thời (bộ đếm đăng nhập/OTP sai) cho tài khoản
+func (h *CanBoHandler) Unlock(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID người dùng không hợp lệ"})
+		return
+	}
+
+	if err := h.service.Unlock(id); err != nil {
+		if errors.Is(err, service.ErrNguoiDungKhongTonTai) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Đã gỡ khóa tạm thời cho tài khoản"})
+}
+
+// UpdateSigner cấu hình cán bộ ký bằng khóa trong CSDL hoặc khóa trong HSM (PKCS#11)
+func (h *CanBoHandler) UpdateSigner(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID người dùng không hợp lệ"})
+		return
+	}
+
+	var input dto.UpdateSignerRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			out := make(map[string]string)
+			for _, fe := range ve {
+				out[fe.Field()] = helper.FormatValidationMessage(fe)
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"errors": out})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Dữ liệu không hợp lệ"})
+		}
+		return
+	}
+
+	res, err := h.service.ConfigureSigner(id, &input)
+	if err != nil {
+		if errors.Is(err, service.ErrNguoiDungKhongTonTai) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		// Không mở được khóa (chưa cấu hình HSM, sai nhãn khóa, chưa có khóa CSDL...)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Không thể dùng signer này", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cập nhật signer thành công", "data": res})
+}
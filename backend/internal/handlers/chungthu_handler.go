@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/ca"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/router"
+	"github.com/vnkmasc/KmaERM/backend/internal/service"
+)
+
+type ChungThuHandler struct {
+	service service.ChungThuService
+}
+
+func NewChungThuHandler(s service.ChungThuService) *ChungThuHandler {
+	return &ChungThuHandler{service: s}
+}
+
+func (h *ChungThuHandler) Routes() []router.Route {
+	admin := []string{middleware.RoleAdmin}
+
+	return []router.Route{
+		// Chứng thư CA và CRL công khai để bên thứ ba tự kiểm tra chữ ký
+		{Method: http.MethodGet, Path: "/ca/cert", Access: router.Public, Handler: h.RootCert},
+		{Method: http.MethodGet, Path: "/ca/crl", Access: router.Public, Handler: h.CRL},
+
+		{Method: http.MethodGet, Path: "/admin/users/:id/chung-thu", Access: router.RoleRestricted, Roles: admin, Handler: h.ListByUser},
+		{Method: http.MethodPost, Path: "/admin/users/:id/chung-thu", Access: router.RoleRestricted, Roles: admin, Handler: h.CapLai},
+		{Method: http.MethodPost, Path: "/admin/chung-thu/:id/thu-hoi", Access: router.RoleRestricted, Roles: admin, Handler: h.ThuHoi},
+	}
+}
+
+func (h *ChungThuHandler) RootCert(c *gin.Context) {
+	certPEM, err := h.service.RootCertPEM()
+	if err != nil {
+		if errors.Is(err, ca.ErrChuaCoCA) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/x-pem-file", []byte(certPEM))
+}
+
+func (h *ChungThuHandler) CRL(c *gin.Context) {
+	der, err := h.service.CRL()
+	if err != nil {
+		if errors.Is(err, ca.ErrChuaCoCA) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi sinh CRL", "details": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="kmaerm.crl"`)
+	c.Data(http.StatusOK, "application/pkix-crl", der)
+}
+
+func (h *ChungThuHandler) ListByUser(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID người dùng không hợp lệ"})
+		return
+	}
+
+	list, err := h.service.ListByUser(id)
+	if err != nil {
+		if errors.Is(err, service.ErrNguoiDungKhongTonTai) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// CapLai cấp chứng thư mới cho khóa ký hiện tại của cán bộ (chứng thư cũ bị thu hồi)
+func (h *ChungThuHandler) CapLai(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID người dùng không hợp lệ"})
+		return
+	}
+
+	ct, err := h.service.CapLai(id)
+	if err != nil {
+		if errors.Is(err, service.ErrNguoiDungKhongTonTai) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Không thể cấp chứng thư", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Cấp chứng thư số thành công", "data": ct})
+}
+
+func (h *ChungThuHandler) ThuHoi(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID chứng thư không hợp lệ"})
+		return
+	}
+
+	var input dto.RevokeChungThuRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dữ liệu không hợp lệ (cần ly_do)"})
+		return
+	}
+
+	if err := h.service.ThuHoi(id, input.LyDo); err != nil {
+		switch {
+		case errors.Is(err, ca.ErrChungThuKhongTonTai):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ca.ErrChungThuDaThuHoi):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Đã thu hồi chứng thư số"})
+}
@@ -15,6 +15,9 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/vnkmasc/KmaERM/backend/internal/dto"
 	"github.com/vnkmasc/KmaERM/backend/internal/helper"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/policy"
+	"github.com/vnkmasc/KmaERM/backend/internal/router"
 	"github.com/vnkmasc/KmaERM/backend/internal/service"
 	"gorm.io/gorm"
 )
@@ -29,18 +32,20 @@ func NewDoanhNghiepHandler(s service.DoanhNghiepService) *DoanhNghiepHandler {
 	}
 }
 
-func (h *DoanhNghiepHandler) RegisterRoutes(rg *gin.RouterGroup) {
-	dnGroup := rg.Group("/doanh-nghiep")
-	{
-		dnGroup.POST("", h.Create)
-		dnGroup.GET("", h.List)
-		dnGroup.GET("/:id", h.GetByID)
-		dnGroup.GET("/maso/:maso", h.GetByMaSo)
-		dnGroup.PUT("/:id", h.Update)
-		dnGroup.PUT("/:id/changemsdn", h.ChangeMSDN)
-		dnGroup.DELETE("/:id", h.Delete)
-		dnGroup.POST("/:id/uploadgcn", h.UploadGCN)
-		dnGroup.GET("/:id/viewgcn", h.ViewGCN)
+func (h *DoanhNghiepHandler) Routes() []router.Route {
+	canBo := []string{middleware.RoleAdmin, middleware.RoleCanBo}
+	admin := []string{middleware.RoleAdmin}
+
+	return []router.Route{
+		{Method: http.MethodPost, Path: "/doanh-nghiep", Access: router.RoleRestricted, Roles: canBo, Handler: h.Create},
+		{Method: http.MethodGet, Path: "/doanh-nghiep", Access: router.RoleRestricted, Roles: canBo, Handler: h.List},
+		{Method: http.MethodGet, Path: "/doanh-nghiep/:id", Access: router.Protected, Handler: h.GetByID},
+		{Method: http.MethodGet, Path: "/doanh-nghiep/maso/:maso", Access: router.RoleRestricted, Roles: canBo, Handler: h.GetByMaSo},
+		{Method: http.MethodPut, Path: "/doanh-nghiep/:id", Access: router.RoleRestricted, Roles: canBo, Handler: h.Update},
+		{Method: http.MethodPut, Path: "/doanh-nghiep/:id/changemsdn", Access: router.RoleRestricted, Roles: canBo, Handler: h.ChangeMSDN},
+		{Method: http.MethodDelete, Path: "/doanh-nghiep/:id", Access: router.RoleRestricted, Roles: admin, Handler: h.Delete},
+		{Method: http.MethodPost, Path: "/doanh-nghiep/:id/uploadgcn", Access: router.RoleRestricted, Roles: canBo, Handler: h.UploadGCN},
+		{Method: http.MethodGet, Path: "/doanh-nghiep/:id/viewgcn", Access: router.Protected, Handler: h.ViewGCN},
 	}
 }
 
@@ -89,6 +94,12 @@ func (h *DoanhNghiepHandler) GetByID(c *gin.Context) {
 		return
 	}
 
+	// Tài khoản doanh nghiệp chỉ xem được doanh nghiệp của mình
+	if !policy.CanAccessDoanhNghiep(c.Request.Context(), id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy doanh nghiệp"})
+		return
+	}
+
 	dn, err := h.service.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -371,6 +382,11 @@ func (h *DoanhNghiepHandler) ViewGCN(c *gin.Context) {
 		return
 	}
 
+	if !policy.CanAccessDoanhNghiep(c.Request.Context(), id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy file hoặc doanh nghiệp"})
+		return
+	}
+
 	filePath, err := h.service.GetGCNFilePath(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) || strings.Contains(err.Error(), "chưa được upload") {
@@ -13,6 +13,10 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/vnkmasc/KmaERM/backend/internal/dto"
 	"github.com/vnkmasc/KmaERM/backend/internal/helper"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/pades"
+	"github.com/vnkmasc/KmaERM/backend/internal/router"
 	"github.com/vnkmasc/KmaERM/backend/internal/service"
 )
 
@@ -26,25 +30,27 @@ func NewGiayPhepHandler(gpService service.GiayPhepService) *GiayPhepHandler {
 	}
 }
 
-// Thêm tham số authMiddleware kiểu gin.HandlerFunc vào hàm
-func (h *GiayPhepHandler) RegisterRoutes(router *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
-	gpGroup := router.Group("/giay-phep")
-	{
-		// Các API này KHÔNG có middleware (chạy tự do)
-		gpGroup.POST("", h.CreateGiayPhep)
-		gpGroup.GET("", h.ListGiayPhep)
-		gpGroup.GET("/:id", h.GetGiayPhepByID)
-		gpGroup.PUT("/:id", h.UpdateGiayPhep)
-		gpGroup.DELETE("/:id", h.DeleteGiayPhep)
-		gpGroup.POST("/:id/upload", h.UploadGiayPhepFile)
-		gpGroup.GET("/:id/view-file", h.DownloadGiayPhepFile)
-		gpGroup.POST("/:id/push-blockchain", h.PushToBlockchain)
-		gpGroup.GET("/:id/verify", h.VerifyGiayPhep)
-
-		// --- CHỈ CÓ API NÀY GẮN MIDDLEWARE ---
-		// Cú pháp: .POST(path, middleware, handler)
-		// Middleware chạy xong -> mới đến h.KySo
-		gpGroup.POST("/:id/ky-so", authMiddleware, h.KySo)
+func (h *GiayPhepHandler) Routes() []router.Route {
+	canBo := []string{middleware.RoleAdmin, middleware.RoleCanBo}
+
+	return []router.Route{
+		// Nhập giấy phép cho hồ sơ đã duyệt với các trường do cán bộ điền
+		{Method: http.MethodPost, Path: "/giay-phep", Access: router.RoleRestricted, Roles: canBo, Handler: h.CreateGiayPhep},
+		// Cấp giấy phép cho hồ sơ đã duyệt theo cấu hình thủ tục
+		{Method: http.MethodPost, Path: "/ho-so/:id/cap-giay-phep", Access: router.RoleRestricted, Roles: canBo, Handler: h.CapGiayPhepTheoHoSo},
+		{Method: http.MethodGet, Path: "/giay-phep", Access: router.Protected, Handler: h.ListGiayPhep},
+		{Method: http.MethodGet, Path: "/giay-phep/:id", Access: router.Protected, Handler: h.GetGiayPhepByID},
+		{Method: http.MethodPut, Path: "/giay-phep/:id", Access: router.RoleRestricted, Roles: canBo, Handler: h.UpdateGiayPhep},
+		{Method: http.MethodDelete, Path: "/giay-phep/:id", Access: router.RoleRestricted, Roles: canBo, Handler: h.DeleteGiayPhep},
+		{Method: http.MethodPost, Path: "/giay-phep/:id/upload", Access: router.RoleRestricted, Roles: canBo, Handler: h.UploadGiayPhepFile},
+		{Method: http.MethodGet, Path: "/giay-phep/:id/view-file", Access: router.Protected, Handler: h.DownloadGiayPhepFile},
+		{Method: http.MethodPost, Path: "/giay-phep/:id/push-blockchain", Access: router.RoleRestricted, Roles: canBo, Handler: h.PushToBlockchain},
+		// Tra cứu đối chiếu blockchain để công khai cho bên thứ ba
+		{Method: http.MethodGet, Path: "/giay-phep/:id/verify", Access: router.Public, Handler: h.VerifyGiayPhep},
+		{Method: http.MethodGet, Path: "/giay-phep/:id/verify-signature", Access: router.Public, Handler: h.VerifyChuKy},
+		// Chữ ký CMS rời để bên thứ ba tự kiểm tra file giấy phép (openssl cms -verify)
+		{Method: http.MethodGet, Path: "/giay-phep/:id/signature.p7s", Access: router.Public, Handler: h.DownloadChuKyCMS},
+		{Method: http.MethodPost, Path: "/giay-phep/:id/ky-so", Access: router.RoleRestricted, Roles: canBo, Handler: h.KySo},
 	}
 }
 
@@ -71,15 +77,7 @@ func (h *GiayPhepHandler) CreateGiayPhep(c *gin.Context) {
 
 	giayPhep, err := h.gpService.CreateGiayPhep(c.Request.Context(), &req)
 	if err != nil {
-		if errors.Is(err, service.ErrHoSoDaCoGiayPhep) {
-			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-			return
-		}
-		if err.Error() == "số giấy phép đã tồn tại" {
-			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi máy chủ khi tạo giấy phép", "details": err.Error()})
+		capGiayPhepError(c, err, "Lỗi máy chủ khi tạo giấy phép")
 		return
 	}
 
@@ -92,6 +90,46 @@ func (h *GiayPhepHandler) CreateGiayPhep(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"data": details})
 }
 
+func (h *GiayPhepHandler) CapGiayPhepTheoHoSo(c *gin.Context) {
+	hoSoID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID hồ sơ không hợp lệ"})
+		return
+	}
+
+	// Body là tùy chọn
+	var req dto.CapGiayPhepRequest
+	if c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	giayPhep, err := h.gpService.CapGiayPhepTheoHoSo(c.Request.Context(), hoSoID, &req)
+	if err != nil {
+		capGiayPhepError(c, err, "Lỗi máy chủ khi cấp giấy phép")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": giayPhep})
+}
+
+// capGiayPhepError trả lỗi của hai API tạo giấy phép (nhập tay và cấp theo thủ tục)
+func capGiayPhepError(c *gin.Context, err error, loiHeThong string) {
+	switch {
+	case errors.Is(err, service.ErrHoSoKhongTimThay):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrKhongCoQuyenThaoTac):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrChuyenTrangThaiKhongHopLe), errors.Is(err, service.ErrHoSoDaCoGiayPhep),
+		errors.Is(err, service.ErrSoGiayPhepDaTonTai):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrThuTucKhongCapGiayPhep), errors.Is(err, service.ErrThieuThoiHanGiayPhep),
+		errors.Is(err, service.ErrLoaiThuTucKhongHopLe), errors.Is(err, service.ErrSoGiayPhepTrungMau):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": loiHeThong, "details": err.Error()})
+	}
+}
+
 func (h *GiayPhepHandler) UpdateGiayPhep(c *gin.Context) {
 	idStr := c.Param("id")
 	giayPhepID, err := uuid.FromString(idStr)
@@ -122,10 +160,14 @@ func (h *GiayPhepHandler) UpdateGiayPhep(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		if err.Error() == "số giấy phép đã tồn tại" {
+		if errors.Is(err, service.ErrSoGiayPhepDaTonTai) || errors.Is(err, service.ErrSuaGiayPhepDaKy) {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, service.ErrSoGiayPhepTrungMau) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi máy chủ khi cập nhật giấy phép", "details": err.Error()})
 		return
 	}
@@ -231,7 +273,7 @@ func (h *GiayPhepHandler) DeleteGiayPhep(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		if errors.Is(err, service.ErrGiayPhepDangHieuLuc) {
+		if errors.Is(err, service.ErrGiayPhepDangHieuLuc) || errors.Is(err, service.ErrXoaGiayPhepDaPhatHanh) {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 			return
 		}
@@ -282,6 +324,10 @@ func (h *GiayPhepHandler) UploadGiayPhepFile(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, service.ErrGiayPhepDaKy) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi máy chủ khi xử lý file giấy phép", "details": err.Error()})
 		return
 	}
@@ -417,11 +463,47 @@ func (h *GiayPhepHandler) KySo(c *gin.Context) {
 	// Nếu userID trong context đã là kiểu uuid.UUID rồi thì ép kiểu thẳng:
 	userID := userIDVal.(uuid.UUID)
 
+	var req dto.KySoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cần nhập mã xác thực 2 lớp (totp_code, 6 chữ số) để ký số"})
+		return
+	}
+
 	// 3. Gọi Service
-	err = h.gpService.KySoGiayPhep(c.Request.Context(), gpID, userID)
+	chuKy, err := h.gpService.KySoGiayPhep(c.Request.Context(), gpID, userID, req.TOTPCode, req.LoaiChuKy)
 	if err != nil {
-		// [FIX LỖI 500 -> 409] Kiểm tra nội dung lỗi
-		if err.Error() == "giấy phép này đã được ký rồi" {
+		if respondLocked(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrHaiLopChuaBat) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Cần bật xác thực 2 lớp trước khi ký số", "details": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrMaHaiLopKhongDung) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Xác nhận ký số thất bại", "details": err.Error()})
+			return
+		}
+
+		if errors.Is(err, service.ErrChuaDuPheDuyet) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Giấy phép chưa được phê duyệt đủ", "details": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrKhongDungChucVu) || errors.Is(err, service.ErrDaDuyetBuocKhac) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Bạn không được ký giấy phép này", "details": err.Error()})
+			return
+		}
+
+		if errors.Is(err, pades.ErrPDFDaMaHoa) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Không thể nhúng chữ ký vào file giấy phép", "details": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrFileGiayPhepBiThayDoi) || errors.Is(err, service.ErrDaKyGiayPhep) ||
+			errors.Is(err, service.ErrChuKyDongThoi) || errors.Is(err, service.ErrGiayPhepDaBiThuHoi) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors.Is(err, service.ErrGiayPhepDaKyChinh) {
 			c.JSON(http.StatusConflict, gin.H{ // Trả về 409 Conflict
 				"error": "Giấy phép này đã hoàn tất ký số, không thể ký lại.",
 			})
@@ -433,5 +515,51 @@ func (h *GiayPhepHandler) KySo(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Ký số thành công!", "status": "DaKy"})
+	if chuKy.LoaiChuKy == models.LoaiChuKyNhay {
+		c.JSON(http.StatusOK, gin.H{"message": "Ký nháy thành công!", "data": chuKy})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Ký số thành công!", "status": "DaKy", "data": chuKy})
+}
+
+func (h *GiayPhepHandler) VerifyChuKy(c *gin.Context) {
+	giayPhepID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID giấy phép không hợp lệ"})
+		return
+	}
+
+	resp, err := h.gpService.VerifyChuKy(c.Request.Context(), giayPhepID)
+	if err != nil {
+		if errors.Is(err, service.ErrGiayPhepKhongTimThay) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi máy chủ khi kiểm tra chữ ký", "details": err.Error()})
+		return
+	}
+
+	// Kết quả kiểm tra (kể cả không hợp lệ) đều trả 200, chi tiết nằm ở is_valid/failed_check
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *GiayPhepHandler) DownloadChuKyCMS(c *gin.Context) {
+	giayPhepID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID giấy phép không hợp lệ"})
+		return
+	}
+
+	p7s, err := h.gpService.GetChuKyCMS(c.Request.Context(), giayPhepID)
+	if err != nil {
+		if errors.Is(err, service.ErrGiayPhepKhongTimThay) || errors.Is(err, service.ErrChuaCoChuKyCMS) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi máy chủ khi lấy chữ ký", "details": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="giay-phep-%s.p7s"`, giayPhepID))
+	c.Data(http.StatusOK, "application/pkcs7-signature", p7s)
 }
@@ -14,6 +14,9 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/vnkmasc/KmaERM/backend/internal/dto"
 	"github.com/vnkmasc/KmaERM/backend/internal/helper"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/policy"
+	"github.com/vnkmasc/KmaERM/backend/internal/router"
 	"github.com/vnkmasc/KmaERM/backend/internal/service"
 )
 
@@ -26,23 +29,34 @@ func NewHoSoHandler(hosoService service.HoSoService) *HoSoHandler {
 		hosoService: hosoService,
 	}
 }
-func (h *HoSoHandler) RegisterRoutes(router *gin.RouterGroup) {
-	hoSoGroup := router.Group("/ho-so")
-	{
-		hoSoGroup.POST("", h.CreateHoSo)
-		hoSoGroup.GET("/:id", h.GetHoSoDetails)
-		hoSoGroup.PUT("/:id", h.UpdateHoSo)
-		hoSoGroup.GET("", h.ListHoSo)
-		hoSoGroup.DELETE("/:id", h.DeleteHoSo)
-
-	}
-	taiLieuGroup := router.Group("/tai-lieu")
-	{
-		taiLieuGroup.POST("/upload", h.UploadTaiLieu)
-		taiLieuGroup.DELETE("/:id", h.DeleteTaiLieu)
-		taiLieuGroup.GET("/download/:id", h.DownloadTaiLieu)
-	}
-	router.GET("/loai-tai-lieu", h.ListLoaiTaiLieu)
+func (h *HoSoHandler) Routes() []router.Route {
+	canBo := []string{middleware.RoleAdmin, middleware.RoleCanBo}
+
+	return []router.Route{
+		{Method: http.MethodPost, Path: "/ho-so", Access: router.Protected, Handler: h.CreateHoSo},
+		{Method: http.MethodGet, Path: "/ho-so/cua-toi", Access: router.RoleRestricted, Roles: canBo, Handler: h.ListHoSoCuaToi},
+		{Method: http.MethodGet, Path: "/ho-so/:id", Access: router.Protected, Handler: h.GetHoSoDetails},
+		{Method: http.MethodPut, Path: "/ho-so/:id", Access: router.RoleRestricted, Roles: canBo, Handler: h.UpdateHoSo},
+		{Method: http.MethodGet, Path: "/ho-so", Access: router.Protected, Handler: h.ListHoSo},
+		{Method: http.MethodGet, Path: "/ho-so/:id/lich-su", Access: router.Protected, Handler: h.GetLichSu},
+		{Method: http.MethodGet, Path: "/ho-so/:id/yeu-cau-bo-sung", Access: router.Protected, Handler: h.GetYeuCauBoSung},
+		{Method: http.MethodDelete, Path: "/ho-so/:id", Access: router.Protected, Handler: h.DeleteHoSo},
+
+		// Chuyển trạng thái hồ sơ; quyền theo từng thao tác do máy trạng thái kiểm tra
+		{Method: http.MethodPost, Path: "/ho-so/:id/nop", Access: router.Protected, Handler: h.chuyenTrangThai(service.ThaoTacNop)},
+		{Method: http.MethodPost, Path: "/ho-so/:id/phan-cong", Access: router.RoleRestricted, Roles: canBo, Handler: h.PhanCong},
+		{Method: http.MethodPost, Path: "/ho-so/:id/tiep-nhan", Access: router.RoleRestricted, Roles: canBo, Handler: h.chuyenTrangThai(service.ThaoTacTiepNhan)},
+		{Method: http.MethodPost, Path: "/ho-so/:id/xu-ly", Access: router.RoleRestricted, Roles: canBo, Handler: h.chuyenTrangThai(service.ThaoTacXuLy)},
+		{Method: http.MethodPost, Path: "/ho-so/:id/tra-lai", Access: router.RoleRestricted, Roles: canBo, Handler: h.chuyenTrangThai(service.ThaoTacTraLai)},
+		{Method: http.MethodPost, Path: "/ho-so/:id/nop-lai", Access: router.Protected, Handler: h.chuyenTrangThai(service.ThaoTacNopLai)},
+		{Method: http.MethodPost, Path: "/ho-so/:id/duyet", Access: router.RoleRestricted, Roles: canBo, Handler: h.chuyenTrangThai(service.ThaoTacDuyet)},
+
+		{Method: http.MethodPost, Path: "/tai-lieu/upload", Access: router.Protected, Handler: h.UploadTaiLieu},
+		{Method: http.MethodDelete, Path: "/tai-lieu/:id", Access: router.Protected, Handler: h.DeleteTaiLieu},
+		{Method: http.MethodGet, Path: "/tai-lieu/download/:id", Access: router.Protected, Handler: h.DownloadTaiLieu},
+
+		{Method: http.MethodGet, Path: "/loai-tai-lieu", Access: router.Public, Handler: h.ListLoaiTaiLieu},
+	}
 }
 
 func (h *HoSoHandler) CreateHoSo(c *gin.Context) {
@@ -124,6 +138,9 @@ func (h *HoSoHandler) GetHoSoDetails(c *gin.Context) {
 	}
 
 	response := dto.ToHoSoDetailsResponse(hoSo)
+	if actor, ok := policy.ActorFrom(c.Request.Context()); ok {
+		response.ThaoTacHopLe = service.ThaoTacHopLe(hoSo.TrangThaiHoSo, actor.RoleName)
+	}
 
 	c.JSON(http.StatusOK, response)
 }
@@ -137,16 +154,20 @@ func (h *HoSoHandler) ListHoSo(c *gin.Context) {
 	}
 
 	// 2. Lấy query param BẮT BUỘC (doanh_nghiep_id)
+	// Tài khoản doanh nghiệp được bỏ trống: service tự ép về doanh nghiệp của họ
 	doanhNghiepIDStr := c.Query("doanh_nghiep_id")
-	if doanhNghiepIDStr == "" {
+	var doanhNghiepID uuid.UUID
+	var err error
+	if doanhNghiepIDStr != "" {
+		doanhNghiepID, err = uuid.FromString(doanhNghiepIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "doanh_nghiep_id không hợp lệ"})
+			return
+		}
+	} else if !policy.IsTenantScoped(c.Request.Context()) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "doanh_nghiep_id là bắt buộc"})
 		return
 	}
-	doanhNghiepID, err := uuid.FromString(doanhNghiepIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "doanh_nghiep_id không hợp lệ"})
-		return
-	}
 
 	// 3. Lấy query param Phân trang (với giá trị mặc định)
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -220,6 +241,10 @@ func (h *HoSoHandler) UploadTaiLieu(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, service.ErrKheKhongCanBoSung) || errors.Is(err, service.ErrHoSoKhongSuaTaiLieu) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi máy chủ khi xử lý file", "details": err.Error()})
 		return
 	}
@@ -246,6 +271,10 @@ func (h *HoSoHandler) DeleteTaiLieu(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, service.ErrKheKhongCanBoSung) || errors.Is(err, service.ErrHoSoKhongSuaTaiLieu) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Lỗi máy chủ khi xóa tài liệu",
 			"details": err.Error(),
@@ -366,3 +395,152 @@ func (h *HoSoHandler) DeleteHoSo(c *gin.Context) {
 	// 4. Trả về thành công
 	c.JSON(http.StatusOK, gin.H{"message": "Đã xóa hồ sơ và các tài liệu liên quan thành công"})
 }
+
+func (h *HoSoHandler) GetLichSu(c *gin.Context) {
+	hoSoID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID hồ sơ không hợp lệ"})
+		return
+	}
+
+	lichSu, err := h.hosoService.GetLichSu(c.Request.Context(), hoSoID)
+	if err != nil {
+		if errors.Is(err, service.ErrHoSoKhongTimThay) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi máy chủ khi lấy lịch sử hồ sơ", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": lichSu})
+}
+
+func (h *HoSoHandler) PhanCong(c *gin.Context) {
+	hoSoID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID hồ sơ không hợp lệ"})
+		return
+	}
+	var req dto.PhanCongHoSoRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if _, err := h.hosoService.PhanCong(c.Request.Context(), hoSoID, &req); err != nil {
+		switch {
+		case errors.Is(err, service.ErrHoSoKhongTimThay):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrKhongThePhanCong), errors.Is(err, service.ErrDaPhanCongCanBoNay):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrCanBoKhongHopLe):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrThieuLyDo):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cần nhập lý do khi phân công lại hồ sơ"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi máy chủ khi phân công hồ sơ", "details": err.Error()})
+		}
+		return
+	}
+
+	details, err := h.hosoService.GetHoSoDetails(c.Request.Context(), hoSoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi khi lấy chi tiết hồ sơ vừa cập nhật", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Phân công hồ sơ thành công", "data": dto.ToHoSoDetailsResponse(details)})
+}
+
+func (h *HoSoHandler) ListHoSoCuaToi(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	response, err := h.hosoService.ListHoSoCuaToi(c.Request.Context(), c.Query("trang_thai_ho_so"), page, pageSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTrangThaiLocKhongHopLe):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrKhongCoQuyenThaoTac):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi máy chủ khi lấy danh sách hồ sơ", "details": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *HoSoHandler) GetYeuCauBoSung(c *gin.Context) {
+	hoSoID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID hồ sơ không hợp lệ"})
+		return
+	}
+
+	list, err := h.hosoService.GetYeuCauBoSung(c.Request.Context(), hoSoID)
+	if err != nil {
+		if errors.Is(err, service.ErrHoSoKhongTimThay) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi máy chủ khi lấy yêu cầu bổ sung", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+func (h *HoSoHandler) chuyenTrangThai(thaoTac string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hoSoID, err := uuid.FromString(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID hồ sơ không hợp lệ"})
+			return
+		}
+
+		// Body là tùy chọn (chỉ trả lại mới bắt buộc lý do)
+		var req dto.ChuyenTrangThaiHoSoRequest
+		if c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+			return
+		}
+
+		_, err = h.hosoService.ChuyenTrangThai(c.Request.Context(), hoSoID, thaoTac, &req)
+		if err != nil {
+			var thieu *service.ChuaBoSungDuError
+			var chuaDayDu *service.HoSoChuaDayDuError
+			switch {
+			case errors.As(err, &thieu):
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": service.ErrChuaBoSungDu.Error(), "details": thieu.TaiLieus})
+			case errors.As(err, &chuaDayDu):
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": service.ErrHoSoChuaDayDu.Error(), "details": chuaDayDu.TaiLieus})
+			case errors.Is(err, service.ErrHoSoKhongTimThay):
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrKhongCoQuyenThaoTac):
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrChuaCauHinhThoiHan):
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrChuyenTrangThaiKhongHopLe):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrThieuLyDo), errors.Is(err, service.ErrThaoTacKhongHopLe),
+				errors.Is(err, service.ErrKheTaiLieuKhongThuocHoSo), errors.Is(err, service.ErrKheTaiLieuTrungLap):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi máy chủ khi chuyển trạng thái hồ sơ", "details": err.Error()})
+			}
+			return
+		}
+
+		details, err := h.hosoService.GetHoSoDetails(c.Request.Context(), hoSoID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi khi lấy chi tiết hồ sơ vừa cập nhật", "details": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": dto.ToHoSoDetailsResponse(details)})
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/router"
+	"github.com/vnkmasc/KmaERM/backend/internal/service"
+)
+
+type LichLamViecHandler struct {
+	service service.LichLamViecService
+}
+
+func NewLichLamViecHandler(s service.LichLamViecService) *LichLamViecHandler {
+	return &LichLamViecHandler{service: s}
+}
+
+func (h *LichLamViecHandler) Routes() []router.Route {
+	admin := []string{middleware.RoleAdmin}
+
+	return []router.Route{
+		{Method: http.MethodGet, Path: "/lich-lam-viec/ngay-nghi", Access: router.Protected, Handler: h.ListNgayNghi},
+
+		{Method: http.MethodPut, Path: "/admin/ngay-nghi/:ngay", Access: router.RoleRestricted, Roles: admin, Handler: h.LuuNgayNghi},
+		{Method: http.MethodDelete, Path: "/admin/ngay-nghi/:ngay", Access: router.RoleRestricted, Roles: admin, Handler: h.XoaNgayNghi},
+	}
+}
+
+func parseNgay(c *gin.Context) (time.Time, bool) {
+	ngay, err := time.Parse("2006-01-02", c.Param("ngay"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ngày không hợp lệ, định dạng YYYY-MM-DD"})
+		return time.Time{}, false
+	}
+	return ngay, true
+}
+
+func (h *LichLamViecHandler) ListNgayNghi(c *gin.Context) {
+	nam := 0
+	if s := c.Query("nam"); s != "" {
+		var err error
+		if nam, err = strconv.Atoi(s); err != nil || nam < 1900 || nam > 9999 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Năm không hợp lệ"})
+			return
+		}
+	}
+
+	list, err := h.service.ListNgayNghi(c.Request.Context(), nam)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+func (h *LichLamViecHandler) LuuNgayNghi(c *gin.Context) {
+	ngay, ok := parseNgay(c)
+	if !ok {
+		return
+	}
+	var req dto.LuuNgayNghiRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ngayNghi, err := h.service.LuuNgayNghi(c.Request.Context(), ngay, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cập nhật lịch nghỉ thành công", "data": ngayNghi})
+}
+
+func (h *LichLamViecHandler) XoaNgayNghi(c *gin.Context) {
+	ngay, ok := parseNgay(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.XoaNgayNghi(c.Request.Context(), ngay); err != nil {
+		if errors.Is(err, service.ErrNgayNghiKhongTimThay) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Xóa ngày nghỉ thành công"})
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/helper"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/router"
+	"github.com/vnkmasc/KmaERM/backend/internal/service"
+)
+
+type PheDuyetHandler struct {
+	service service.PheDuyetService
+}
+
+func NewPheDuyetHandler(s service.PheDuyetService) *PheDuyetHandler {
+	return &PheDuyetHandler{service: s}
+}
+
+func (h *PheDuyetHandler) Routes() []router.Route {
+	canBo := []string{middleware.RoleAdmin, middleware.RoleCanBo}
+	admin := []string{middleware.RoleAdmin}
+
+	return []router.Route{
+		{Method: http.MethodGet, Path: "/giay-phep/:id/phe-duyet", Access: router.RoleRestricted, Roles: canBo, Handler: h.GetTrangThai},
+		{Method: http.MethodPost, Path: "/giay-phep/:id/phe-duyet", Access: router.RoleRestricted, Roles: canBo, Handler: h.PheDuyet},
+
+		{Method: http.MethodGet, Path: "/admin/quy-trinh-duyet", Access: router.RoleRestricted, Roles: admin, Handler: h.GetQuyTrinh},
+		{Method: http.MethodPut, Path: "/admin/quy-trinh-duyet", Access: router.RoleRestricted, Roles: admin, Handler: h.CauHinhQuyTrinh},
+		{Method: http.MethodPut, Path: "/admin/users/:id/chuc-vu", Access: router.RoleRestricted, Roles: admin, Handler: h.UpdateChucVu},
+	}
+}
+
+func bindJSON(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			errorMessages := make(map[string]string)
+			for _, fe := range validationErrs {
+				errorMessages[fe.Field()] = helper.FormatValidationMessage(fe)
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Dữ liệu đầu vào không hợp lệ", "details": errorMessages})
+			return false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "JSON body không hợp lệ", "details": err.Error()})
+		return false
+	}
+	return true
+}
+
+func (h *PheDuyetHandler) GetTrangThai(c *gin.Context) {
+	giayPhepID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID giấy phép không hợp lệ"})
+		return
+	}
+
+	resp, err := h.service.GetTrangThai(c.Request.Context(), giayPhepID)
+	if err != nil {
+		if errors.Is(err, service.ErrGiayPhepKhongTimThay) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": resp})
+}
+
+func (h *PheDuyetHandler) PheDuyet(c *gin.Context) {
+	giayPhepID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID giấy phép không hợp lệ"})
+		return
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var req dto.PheDuyetRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.service.PheDuyet(c.Request.Context(), giayPhepID, userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrGiayPhepKhongTimThay):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrKhongDungChucVu), errors.Is(err, service.ErrDaDuyetBuocKhac):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrPheDuyetSauKhiKy), errors.Is(err, service.ErrBuocCuoiLaKySo),
+			errors.Is(err, service.ErrGiayPhepDaBiThuHoi):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrChuaCoQuyTrinhDuyet), errors.Is(err, service.ErrGiayPhepChuaCoFile):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Đã ghi nhận phê duyệt", "data": resp})
+}
+
+func (h *PheDuyetHandler) GetQuyTrinh(c *gin.Context) {
+	list, err := h.service.GetQuyTrinh(c.Request.Context(), c.Query("loai_giay_phep"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+func (h *PheDuyetHandler) CauHinhQuyTrinh(c *gin.Context) {
+	var req dto.CauHinhQuyTrinhRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	list, err := h.service.CauHinhQuyTrinh(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrQuyTrinhTrungChucVu) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cập nhật quy trình phê duyệt thành công", "data": list})
+}
+
+func (h *PheDuyetHandler) UpdateChucVu(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID người dùng không hợp lệ"})
+		return
+	}
+
+	var req dto.UpdateChucVuRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.service.UpdateChucVu(c.Request.Context(), id, req.ChucVu); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNguoiDungKhongTonTai):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrChucVuChiChoCanBo):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cập nhật chức vụ thành công"})
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/router"
+)
+
+var (
+	roleIDAdmin       = uuid.Must(uuid.NewV4())
+	roleIDCanBo       = uuid.Must(uuid.NewV4())
+	roleIDDoanhNghiep = uuid.Must(uuid.NewV4())
+)
+
+// dungEngine đăng ký toàn bộ route thật như cmd/server, thay handler bằng handler trả 200
+// để chỉ kiểm tra chuỗi AuthMiddleware/RequireRole
+func dungEngine(t *testing.T) (*gin.Engine, []router.Route) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	middleware.InitRoleResolverWithLoader(func() ([]models.Role, error) {
+		return []models.Role{
+			{ID: roleIDAdmin, Name: middleware.RoleAdmin},
+			{ID: roleIDCanBo, Name: middleware.RoleCanBo},
+			{ID: roleIDDoanhNghiep, Name: middleware.RoleDoanhNghiep},
+		}, nil
+	})
+
+	reg := router.NewRegistry("/api/v1")
+	for _, routes := range [][]router.Route{
+		NewAuthHandler(nil).Routes(),
+		NewDoanhNghiepHandler(nil).Routes(),
+		NewHoSoHandler(nil).Routes(),
+		NewGiayPhepHandler(nil).Routes(),
+		NewCanBoHandler(nil).Routes(),
+		NewChungThuHandler(nil).Routes(),
+		NewPheDuyetHandler(nil).Routes(),
+		NewLichLamViecHandler(nil).Routes(),
+		NewThongKeHandler(nil).Routes(),
+		NewThuTucHandler(nil).Routes(),
+	} {
+		for _, rt := range routes {
+			rt.Handler = func(c *gin.Context) { c.Status(http.StatusOK) }
+			reg.Add(rt)
+		}
+	}
+
+	engine := gin.New()
+	if err := reg.Mount(engine, middleware.AuthMiddleware()); err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	return engine, reg.Routes()
+}
+
+func tokenCho(t *testing.T, roleID uuid.UUID) string {
+	t.Helper()
+	var doanhNghiepID *uuid.UUID
+	if roleID == roleIDDoanhNghiep {
+		id := uuid.Must(uuid.NewV4())
+		doanhNghiepID = &id
+	}
+	token, err := middleware.GenerateToken(uuid.Must(uuid.NewV4()), roleID, doanhNghiepID, uuid.Must(uuid.NewV4()))
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return token
+}
+
+// duongDan thay các tham số ":x" bằng UUID để khớp route
+func duongDan(path string) string {
+	phan := strings.Split(path, "/")
+	for i, p := range phan {
+		if strings.HasPrefix(p, ":") {
+			phan[i] = uuid.Must(uuid.NewV4()).String()
+		}
+	}
+	return "/api/v1" + strings.Join(phan, "/")
+}
+
+func goi(engine *gin.Engine, method, path, token string) int {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRouteGroupsRequireRole(t *testing.T) {
+	engine, _ := dungEngine(t)
+	tokens := map[string]string{
+		middleware.RoleAdmin:       tokenCho(t, roleIDAdmin),
+		middleware.RoleCanBo:       tokenCho(t, roleIDCanBo),
+		middleware.RoleDoanhNghiep: tokenCho(t, roleIDDoanhNghiep),
+	}
+
+	cases := []struct {
+		nhom, method, path string
+		// mã trả về theo role; không có token luôn là 401
+		admin, canBo, doanhNghiep int
+	}{
+		{"/admin", http.MethodGet, "/admin/thu-tuc", http.StatusOK, http.StatusForbidden, http.StatusForbidden},
+		{"/admin", http.MethodPost, "/admin/create-can-bo", http.StatusOK, http.StatusForbidden, http.StatusForbidden},
+		{"/admin", http.MethodPut, "/admin/users/:id/trang-thai", http.StatusOK, http.StatusForbidden, http.StatusForbidden},
+		{"/doanh-nghiep", http.MethodGet, "/doanh-nghiep", http.StatusOK, http.StatusOK, http.StatusForbidden},
+		{"/doanh-nghiep", http.MethodPost, "/doanh-nghiep", http.StatusOK, http.StatusOK, http.StatusForbidden},
+		{"/doanh-nghiep", http.MethodPut, "/doanh-nghiep/:id", http.StatusOK, http.StatusOK, http.StatusForbidden},
+		{"/doanh-nghiep", http.MethodDelete, "/doanh-nghiep/:id", http.StatusOK, http.StatusForbidden, http.StatusForbidden},
+		{"/ho-so", http.MethodGet, "/ho-so/cua-toi", http.StatusOK, http.StatusOK, http.StatusForbidden},
+		{"/ho-so", http.MethodPut, "/ho-so/:id", http.StatusOK, http.StatusOK, http.StatusForbidden},
+		{"/ho-so", http.MethodPost, "/ho-so/:id/tiep-nhan", http.StatusOK, http.StatusOK, http.StatusForbidden},
+		{"/ho-so", http.MethodPost, "/ho-so/:id/duyet", http.StatusOK, http.StatusOK, http.StatusForbidden},
+		// Doanh nghiệp tự nộp và bổ sung tài liệu; phạm vi dữ liệu do tầng service (policy) kiểm tra
+		{"/tai-lieu", http.MethodPost, "/tai-lieu/upload", http.StatusOK, http.StatusOK, http.StatusOK},
+		{"/tai-lieu", http.MethodDelete, "/tai-lieu/:id", http.StatusOK, http.StatusOK, http.StatusOK},
+		{"/giay-phep", http.MethodPost, "/giay-phep", http.StatusOK, http.StatusOK, http.StatusForbidden},
+		{"/giay-phep", http.MethodPut, "/giay-phep/:id", http.StatusOK, http.StatusOK, http.StatusForbidden},
+		{"/giay-phep", http.MethodDelete, "/giay-phep/:id", http.StatusOK, http.StatusOK, http.StatusForbidden},
+		{"/giay-phep", http.MethodPost, "/giay-phep/:id/ky-so", http.StatusOK, http.StatusOK, http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			path := duongDan(tc.path)
+			if got := goi(engine, tc.method, path, ""); got != http.StatusUnauthorized {
+				t.Errorf("không có token: %d, muốn %d", got, http.StatusUnauthorized)
+			}
+			for role, muon := range map[string]int{
+				middleware.RoleAdmin:       tc.admin,
+				middleware.RoleCanBo:       tc.canBo,
+				middleware.RoleDoanhNghiep: tc.doanhNghiep,
+			} {
+				if got := goi(engine, tc.method, path, tokens[role]); got != muon {
+					t.Errorf("%s: %d, muốn %d", role, got, muon)
+				}
+			}
+		})
+	}
+}
+
+// Mọi route giới hạn role trong các nhóm nghiệp vụ phải từ chối role không được khai báo
+func TestRoleRestrictedRoutesRejectUndeclaredRoles(t *testing.T) {
+	engine, routes := dungEngine(t)
+	roleIDs := map[string]uuid.UUID{
+		middleware.RoleAdmin:       roleIDAdmin,
+		middleware.RoleCanBo:       roleIDCanBo,
+		middleware.RoleDoanhNghiep: roleIDDoanhNghiep,
+	}
+
+	for _, rt := range routes {
+		if rt.Access != router.RoleRestricted {
+			continue
+		}
+		path := duongDan(rt.Path)
+		if got := goi(engine, rt.Method, path, ""); got != http.StatusUnauthorized {
+			t.Errorf("%s %s không có token: %d, muốn %d", rt.Method, rt.Path, got, http.StatusUnauthorized)
+		}
+		for role, roleID := range roleIDs {
+			muon := http.StatusForbidden
+			for _, r := range rt.Roles {
+				if r == role {
+					muon = http.StatusOK
+				}
+			}
+			if got := goi(engine, rt.Method, path, tokenCho(t, roleID)); got != muon {
+				t.Errorf("%s %s với %s: %d, muốn %d", rt.Method, rt.Path, role, got, muon)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/router"
+	"github.com/vnkmasc/KmaERM/backend/internal/service"
+)
+
+type ThongKeHandler struct {
+	service service.ThongKeService
+}
+
+func NewThongKeHandler(s service.ThongKeService) *ThongKeHandler {
+	return &ThongKeHandler{service: s}
+}
+
+func (h *ThongKeHandler) Routes() []router.Route {
+	canBo := []string{middleware.RoleAdmin, middleware.RoleCanBo}
+
+	return []router.Route{
+		{Method: http.MethodGet, Path: "/thong-ke/sla", Access: router.RoleRestricted, Roles: canBo, Handler: h.ThongKeSLA},
+		{Method: http.MethodGet, Path: "/thong-ke/sla/qua-han", Access: router.RoleRestricted, Roles: canBo, Handler: h.ListQuaHan},
+	}
+}
+
+func (h *ThongKeHandler) ThongKeSLA(c *gin.Context) {
+	soNgay := service.SoNgaySapDenHanMacDinh
+	if s := c.Query("so_ngay_sap_den_han"); s != "" {
+		var err error
+		if soNgay, err = strconv.Atoi(s); err != nil || soNgay < 0 || soNgay > 30 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "so_ngay_sap_den_han phải là số nguyên từ 0 đến 30"})
+			return
+		}
+	}
+
+	resp, err := h.service.ThongKeSLA(c.Request.Context(), soNgay)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": resp})
+}
+
+func (h *ThongKeHandler) ListQuaHan(c *gin.Context) {
+	var params dto.HoSoQuaHanSearchParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query param không hợp lệ", "details": err.Error()})
+		return
+	}
+	var canBoID uuid.UUID
+	if params.CanBoID != "" {
+		var err error
+		if canBoID, err = uuid.FromString(params.CanBoID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "can_bo_id không hợp lệ"})
+			return
+		}
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	resp, err := h.service.ListQuaHan(c.Request.Context(), &params, canBoID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/router"
+	"github.com/vnkmasc/KmaERM/backend/internal/service"
+)
+
+type ThuTucHandler struct {
+	service service.ThuTucService
+}
+
+func NewThuTucHandler(s service.ThuTucService) *ThuTucHandler {
+	return &ThuTucHandler{service: s}
+}
+
+func (h *ThuTucHandler) Routes() []router.Route {
+	admin := []string{middleware.RoleAdmin}
+
+	return []router.Route{
+		// Danh mục thủ tục đang hoạt động, dùng khi doanh nghiệp chọn thủ tục để tạo hồ sơ
+		{Method: http.MethodGet, Path: "/thu-tuc", Access: router.Public, Handler: h.ListThuTucHoatDong},
+
+		{Method: http.MethodGet, Path: "/admin/thu-tuc", Access: router.RoleRestricted, Roles: admin, Handler: h.ListThuTuc},
+		{Method: http.MethodPost, Path: "/admin/thu-tuc", Access: router.RoleRestricted, Roles: admin, Handler: h.CreateThuTuc},
+		{Method: http.MethodGet, Path: "/admin/thu-tuc/:id", Access: router.RoleRestricted, Roles: admin, Handler: h.GetThuTuc},
+		{Method: http.MethodPut, Path: "/admin/thu-tuc/:id", Access: router.RoleRestricted, Roles: admin, Handler: h.UpdateThuTuc},
+		{Method: http.MethodDelete, Path: "/admin/thu-tuc/:id", Access: router.RoleRestricted, Roles: admin, Handler: h.DeleteThuTuc},
+		{Method: http.MethodGet, Path: "/admin/loai-tai-lieu", Access: router.RoleRestricted, Roles: admin, Handler: h.ListLoaiTaiLieu},
+		{Method: http.MethodPost, Path: "/admin/loai-tai-lieu", Access: router.RoleRestricted, Roles: admin, Handler: h.CreateLoaiTaiLieu},
+	}
+}
+
+func parseThuTucID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID thủ tục không hợp lệ"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func thuTucError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrThuTucKhongTimThay):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrThuTucTrungTen), errors.Is(err, service.ErrThuTucDaCoHoSo):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrLoaiTaiLieuKhongTonTai), errors.Is(err, service.ErrLoaiTaiLieuLapLai),
+		errors.Is(err, service.ErrThieuThoiHanGiayPhep):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+	}
+}
+
+func (h *ThuTucHandler) ListThuTucHoatDong(c *gin.Context) {
+	list, err := h.service.ListThuTuc(c.Request.Context(), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+func (h *ThuTucHandler) ListThuTuc(c *gin.Context) {
+	list, err := h.service.ListThuTuc(c.Request.Context(), false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+func (h *ThuTucHandler) GetThuTuc(c *gin.Context) {
+	id, ok := parseThuTucID(c)
+	if !ok {
+		return
+	}
+
+	thuTuc, err := h.service.GetThuTuc(c.Request.Context(), id)
+	if err != nil {
+		thuTucError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": thuTuc})
+}
+
+func (h *ThuTucHandler) CreateThuTuc(c *gin.Context) {
+	var req dto.ThuTucRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	thuTuc, err := h.service.CreateThuTuc(c.Request.Context(), &req)
+	if err != nil {
+		thuTucError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Tạo thủ tục thành công", "data": thuTuc})
+}
+
+func (h *ThuTucHandler) UpdateThuTuc(c *gin.Context) {
+	id, ok := parseThuTucID(c)
+	if !ok {
+		return
+	}
+	var req dto.ThuTucRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	thuTuc, err := h.service.UpdateThuTuc(c.Request.Context(), id, &req)
+	if err != nil {
+		thuTucError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cập nhật thủ tục thành công", "data": thuTuc})
+}
+
+func (h *ThuTucHandler) DeleteThuTuc(c *gin.Context) {
+	id, ok := parseThuTucID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteThuTuc(c.Request.Context(), id); err != nil {
+		thuTucError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Xóa thủ tục thành công"})
+}
+
+func (h *ThuTucHandler) ListLoaiTaiLieu(c *gin.Context) {
+	list, err := h.service.ListLoaiTaiLieu(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+func (h *ThuTucHandler) CreateLoaiTaiLieu(c *gin.Context) {
+	var req dto.CreateLoaiTaiLieuRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	loaiTaiLieu, err := h.service.CreateLoaiTaiLieu(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrLoaiTaiLieuTrungTen) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lỗi hệ thống", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Tạo loại tài liệu thành công", "data": loaiTaiLieu})
+}
@@ -0,0 +1,346 @@
+// Package keystore quản lý khóa ký của cán bộ theo mô hình mã hóa phong bì:
+// mỗi private key được mã hóa bằng một DEK riêng, DEK được mã hóa bằng KEK của hệ thống,
+// KEK được mã hóa bằng APP_MASTER_KEY (utils.GetSystemMasterKey) và lưu trong bảng system_keks.
+package keystore
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrChuaCoKEK          = errors.New("hệ thống chưa có KEK đang hoạt động")
+	ErrKhoaChuaMaHoa      = errors.New("khóa ký của tài khoản chưa được mã hóa, hãy chạy lệnh migrate-private-keys")
+	ErrTaiKhoanChuaCoKhoa = errors.New("tài khoản này chưa được cấu hình Chữ ký số")
+)
+
+// SealedKey là private key đã được đóng gói, tương ứng các cột private_key_enc, dek_enc, kek_version
+type SealedKey struct {
+	PrivateKeyEnc string
+	DEKEnc        string
+	KEKVersion    int
+}
+
+type Keystore struct {
+	db        *gorm.DB
+	masterKey []byte
+
+	mu   sync.RWMutex
+	keks map[int][]byte // Cache KEK đã giải mã theo version (version không bao giờ thay đổi nội dung)
+}
+
+func New(db *gorm.DB) (*Keystore, error) {
+	masterKey, err := utils.GetSystemMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	return &Keystore{db: db, masterKey: masterKey, keks: make(map[int][]byte)}, nil
+}
+
+// EnsureKEK tạo KEK phiên bản đầu tiên nếu hệ thống chưa có
+func (k *Keystore) EnsureKEK() error {
+	return k.db.Transaction(func(tx *gorm.DB) error {
+		// Khóa bảng để 2 tiến trình khởi động cùng lúc không cùng tạo KEK
+		if err := tx.Exec("LOCK TABLE system_keks IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.SystemKEK{}).Where("is_active").Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		_, _, err := k.createKEK(tx)
+		return err
+	})
+}
+
+func (k *Keystore) createKEK(tx *gorm.DB) (*models.SystemKEK, []byte, error) {
+	kek, err := utils.GenerateRandomKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	enc, err := utils.EncryptAES(kek, k.masterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	row := &models.SystemKEK{EncryptedKEK: base64.StdEncoding.EncodeToString(enc), IsActive: true}
+	if err := tx.Create(row).Error; err != nil {
+		return nil, nil, err
+	}
+	return row, kek, nil
+}
+
+func (k *Keystore) kek(tx *gorm.DB, version int) ([]byte, error) {
+	k.mu.RLock()
+	kek, ok := k.keks[version]
+	k.mu.RUnlock()
+	if ok {
+		return kek, nil
+	}
+
+	var row models.SystemKEK
+	if err := tx.First(&row, "version = ?", version).Error; err != nil {
+		return nil, fmt.Errorf("không tìm thấy KEK phiên bản %d: %w", version, err)
+	}
+	kek, err := k.decryptKEK(&row)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.keks[version] = kek
+	k.mu.Unlock()
+	return kek, nil
+}
+
+func (k *Keystore) decryptKEK(row *models.SystemKEK) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(row.EncryptedKEK)
+	if err != nil {
+		return nil, err
+	}
+	kek, err := utils.DecryptAES(raw, k.masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("không giải mã được KEK phiên bản %d (sai APP_MASTER_KEY?): %w", row.Version, err)
+	}
+	return kek, nil
+}
+
+func (k *Keystore) activeVersion(tx *gorm.DB) (int, error) {
+	var row models.SystemKEK
+	err := tx.Select("version").Where("is_active").First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrChuaCoKEK
+	}
+	return row.Version, err
+}
+
+// Seal mã hóa private key PEM bằng một DEK mới, DEK được bọc bởi KEK đang hoạt động
+func (k *Keystore) Seal(privateKeyPEM string) (*SealedKey, error) {
+	return k.seal(k.db, privateKeyPEM)
+}
+
+func (k *Keystore) seal(tx *gorm.DB, privateKeyPEM string) (*SealedKey, error) {
+	version, err := k.activeVersion(tx)
+	if err != nil {
+		return nil, err
+	}
+	kek, err := k.kek(tx, version)
+	if err != nil {
+		return nil, err
+	}
+	return sealWithKEK(kek, version, privateKeyPEM)
+}
+
+// sealWithKEK là phần mã hóa của Seal khi đã có KEK, không truy cập CSDL
+func sealWithKEK(kek []byte, version int, privateKeyPEM string) (*SealedKey, error) {
+	dek, err := utils.GenerateRandomKey()
+	if err != nil {
+		return nil, err
+	}
+	privEnc, err := utils.EncryptAES([]byte(privateKeyPEM), dek)
+	if err != nil {
+		return nil, err
+	}
+	dekEnc, err := utils.EncryptAES(dek, kek)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SealedKey{
+		PrivateKeyEnc: base64.StdEncoding.EncodeToString(privEnc),
+		DEKEnc:        base64.StdEncoding.EncodeToString(dekEnc),
+		KEKVersion:    version,
+	}, nil
+}
+
+// Open giải mã private key. Chỉ được gọi trong luồng ký số, kết quả không được lưu lại hay ghi log.
+func (k *Keystore) Open(sealed *SealedKey) (string, error) {
+	kek, err := k.kek(k.db, sealed.KEKVersion)
+	if err != nil {
+		return "", err
+	}
+	return openWithKEK(kek, sealed)
+}
+
+// openWithKEK là phần giải mã của Open khi đã có KEK ứng với sealed.KEKVersion
+func openWithKEK(kek []byte, sealed *SealedKey) (string, error) {
+	dek, err := unwrapDEK(kek, sealed.DEKEnc)
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(sealed.PrivateKeyEnc)
+	if err != nil {
+		return "", err
+	}
+	pem, err := utils.DecryptAES(raw, dek)
+	if err != nil {
+		return "", fmt.Errorf("không giải mã được private key: %w", err)
+	}
+	return string(pem), nil
+}
+
+// OpenUser giải mã khóa ký của user từ các cột đã mã hóa
+func (k *Keystore) OpenUser(user *models.User) (string, error) {
+	if user.PrivateKeyEnc == nil || user.DEKEnc == nil || user.KEKVersion == nil {
+		if user.PrivateKeyPEM != nil && *user.PrivateKeyPEM != "" {
+			return "", ErrKhoaChuaMaHoa
+		}
+		return "", ErrTaiKhoanChuaCoKhoa
+	}
+	return k.Open(&SealedKey{
+		PrivateKeyEnc: *user.PrivateKeyEnc,
+		DEKEnc:        *user.DEKEnc,
+		KEKVersion:    *user.KEKVersion,
+	})
+}
+
+func unwrapDEK(kek []byte, dekEnc string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(dekEnc)
+	if err != nil {
+		return nil, err
+	}
+	return utils.DecryptAES(raw, kek)
+}
+
+// rewrapDEK bọc lại DEK từ KEK cũ sang KEK mới; private key được DEK mã hóa không thay đổi
+func rewrapDEK(dekEnc string, oldKEK, newKEK []byte) (string, error) {
+	dek, err := unwrapDEK(oldKEK, dekEnc)
+	if err != nil {
+		return "", err
+	}
+	enc, err := utils.EncryptAES(dek, newKEK)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(enc), nil
+}
+
+// MigratePlaintext mã hóa mọi private_key_pem còn ở dạng bản rõ rồi xóa bản rõ.
+// Chạy lại nhiều lần vẫn an toàn (chỉ xử lý các dòng chưa mã hóa).
+func (k *Keystore) MigratePlaintext() (int, error) {
+	if err := k.EnsureKEK(); err != nil {
+		return 0, err
+	}
+
+	var users []models.User
+	err := k.db.Select("id", "private_key_pem").
+		Where("private_key_pem IS NOT NULL AND private_key_pem <> '' AND private_key_enc IS NULL").
+		Find(&users).Error
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, u := range users {
+		err := k.db.Transaction(func(tx *gorm.DB) error {
+			sealed, err := k.seal(tx, *u.PrivateKeyPEM)
+			if err != nil {
+				return err
+			}
+
+			// Kiểm tra giải mã lại được trước khi xóa bản rõ
+			if opened, err := k.Open(sealed); err != nil || opened != *u.PrivateKeyPEM {
+				return fmt.Errorf("kiểm tra giải mã thất bại cho user %s", u.ID)
+			}
+
+			return tx.Model(&models.User{}).Where("id = ?", u.ID).Updates(map[string]interface{}{
+				"private_key_enc": sealed.PrivateKeyEnc,
+				"dek_enc":         sealed.DEKEnc,
+				"kek_version":     sealed.KEKVersion,
+				"private_key_pem": nil,
+			}).Error
+		})
+		if err != nil {
+			return migrated, fmt.Errorf("user %s: %w", u.ID, err)
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+// RotateKEK tạo KEK mới, bọc lại toàn bộ DEK bằng KEK mới và ngừng dùng KEK cũ, trong một transaction.
+// Private key không bị giải mã trong quá trình này.
+func (k *Keystore) RotateKEK() (newVersion int, rewrapped int, err error) {
+	err = k.db.Transaction(func(tx *gorm.DB) error {
+		// Khóa bảng: không cho EnsureKEK/RotateKEK khác chạy song song với lần xoay vòng này
+		if err := tx.Exec("LOCK TABLE system_keks IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.SystemKEK{}).Where("is_active").
+			Updates(map[string]interface{}{"is_active": false, "retired_at": now}).Error; err != nil {
+			return err
+		}
+
+		row, newKEK, err := k.createKEK(tx)
+		if err != nil {
+			return err
+		}
+		newVersion = row.Version
+
+		for _, table := range wrappedKeyTables {
+			n, err := k.rewrapTable(tx, table, newKEK, row.Version)
+			if err != nil {
+				return err
+			}
+			rewrapped += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return newVersion, rewrapped, nil
+}
+
+// wrappedKeyTables là các bảng có cặp cột dek_enc/kek_version cần bọc lại khi xoay vòng KEK
+var wrappedKeyTables = []string{"users", "certificate_authorities"}
+
+func (k *Keystore) rewrapTable(tx *gorm.DB, table string, newKEK []byte, newVersion int) (int, error) {
+	type wrapped struct {
+		ID         uuid.UUID
+		DEKEnc     string
+		KEKVersion int
+	}
+	var rows []wrapped
+	if err := tx.Table(table).
+		Select("id", "dek_enc", "kek_version").
+		Where("dek_enc IS NOT NULL").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scan(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	for _, r := range rows {
+		oldKEK, err := k.kek(tx, r.KEKVersion)
+		if err != nil {
+			return 0, fmt.Errorf("%s %s: %w", table, r.ID, err)
+		}
+		dekEnc, err := rewrapDEK(r.DEKEnc, oldKEK, newKEK)
+		if err != nil {
+			return 0, fmt.Errorf("%s %s: %w", table, r.ID, err)
+		}
+		if err := tx.Table(table).Where("id = ?", r.ID).Updates(map[string]interface{}{
+			"dek_enc":     dekEnc,
+			"kek_version": newVersion,
+		}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
@@ -1,12 +1,25 @@
 package middleware
 
 import (
+	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/policy"
 )
 
+const keyChoPhepTokenDangKy = "choPhepTokenDangKyHaiLop"
+
+// AllowEnrollmentToken đặt trước AuthMiddleware ở các endpoint thiết lập 2FA để nhận cả token đăng ký 2FA
+func AllowEnrollmentToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(keyChoPhepTokenDangKy, true)
+		c.Next()
+	}
+}
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -30,12 +43,55 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Token đăng ký 2FA chỉ qua được các route đã khai báo nhận nó (AllowEnrollmentToken)
+		if claims.Scope != "" && !(claims.Scope == ScopeDangKyHaiLop && c.GetBool(keyChoPhepTokenDangKy)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Cần bật xác thực 2 lớp và đăng nhập lại để sử dụng chức năng này"})
+			c.Abort()
+			return
+		}
+
+		// Token còn hạn chưa đủ: tài khoản bị khóa, đổi mật khẩu hoặc đăng xuất phải có hiệu lực ngay
+		if sessionValidator != nil {
+			if err := sessionValidator.Validate(claims); err != nil {
+				// Lý do cụ thể (phiên bị thu hồi, tài khoản bị khóa, lỗi CSDL...) chỉ ghi log, không trả cho client
+				log.Printf("Từ chối token của user %s: %v", claims.UserID, err)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token không hợp lệ hoặc đã hết hạn"})
+				c.Abort()
+				return
+			}
+		}
+
 		c.Set("userID", claims.UserID)
 		c.Set("roleID", claims.RoleID)
+		c.Set("sessionID", claims.SessionID)
 		if claims.DoanhNghiepID != nil {
 			c.Set("doanhNghiepID", *claims.DoanhNghiepID)
 		}
 
+		// Gắn Actor vào request context để tầng service áp dụng phạm vi dữ liệu (policy)
+		if roleResolver != nil {
+			roleName, err := roleResolver.NameOf(claims.RoleID)
+			if err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Không xác định được quyền của tài khoản"})
+				c.Abort()
+				return
+			}
+
+			actor := policy.Actor{UserID: claims.UserID, RoleName: roleName}
+			if claims.DoanhNghiepID != nil {
+				actor.DoanhNghiepID = *claims.DoanhNghiepID
+			}
+			// Tài khoản doanh nghiệp bắt buộc phải gắn với một doanh nghiệp
+			if actor.IsDoanhNghiep() && actor.DoanhNghiepID == uuid.Nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Tài khoản doanh nghiệp chưa được gắn với doanh nghiệp nào"})
+				c.Abort()
+				return
+			}
+
+			c.Set("roleName", roleName)
+			c.Request = c.Request.WithContext(policy.WithActor(c.Request.Context(), actor))
+		}
+
 		c.Next()
 	}
 }
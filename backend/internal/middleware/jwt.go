@@ -13,23 +13,66 @@ type JwtCustomClaims struct {
 	UserID        uuid.UUID  `json:"user_id"`
 	RoleID        uuid.UUID  `json:"role_id"`
 	DoanhNghiepID *uuid.UUID `json:"doanh_nghiep_id,omitempty"` // Có thể null nếu là Admin hệ thống
+	SessionID     uuid.UUID  `json:"sid"`                       // Phiên đăng nhập (bảng user_sessions) phát hành token này
+	// Rỗng: token đầy đủ. ScopeDangKyHaiLop: chỉ dùng được ở các endpoint thiết lập 2FA, không gắn phiên
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID, roleID uuid.UUID, doanhNghiepID *uuid.UUID) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "default_secret"
+// ScopeDangKyHaiLop là phạm vi token cấp khi cán bộ/quản trị chưa bật 2FA đăng nhập bằng mật khẩu
+const ScopeDangKyHaiLop = "totp_enrollment"
+
+const (
+	defaultAccessTokenTTL = 15 * time.Minute
+	enrollmentTokenTTL    = 10 * time.Minute
+)
+
+func init() {
+	// iat mặc định làm tròn xuống giây: token phát hành ngay sau khi thu hồi (cùng giây) sẽ bị coi là phát hành trước.
+	// Ghi iat tới micro giây, bằng độ chính xác của tokens_valid_after (TIMESTAMPTZ).
+	jwt.TimePrecision = time.Microsecond
+}
+
+// AccessTokenTTL: access token sống ngắn, client dùng refresh token để xin token mới.
+// Có thể cấu hình qua ACCESS_TOKEN_TTL (ví dụ "15m", "1h").
+func AccessTokenTTL() time.Duration {
+	if v := os.Getenv("ACCESS_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
 	}
+	return defaultAccessTokenTTL
+}
 
-	claims := &JwtCustomClaims{
+func GenerateToken(userID, roleID uuid.UUID, doanhNghiepID *uuid.UUID, sessionID uuid.UUID) (string, error) {
+	return signToken(&JwtCustomClaims{
 		UserID:        userID,
 		RoleID:        roleID,
 		DoanhNghiepID: doanhNghiepID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+		SessionID:     sessionID,
+	}, AccessTokenTTL())
+}
+
+// GenerateEnrollmentToken phát hành token chỉ dùng để thiết lập 2FA: không có refresh token, không gắn phiên
+func GenerateEnrollmentToken(userID, roleID uuid.UUID) (string, time.Duration, error) {
+	token, err := signToken(&JwtCustomClaims{
+		UserID: userID,
+		RoleID: roleID,
+		Scope:  ScopeDangKyHaiLop,
+	}, enrollmentTokenTTL)
+	return token, enrollmentTokenTTL, err
+}
+
+func signToken(claims *JwtCustomClaims, ttl time.Duration) (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "default_secret"
+	}
+
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -38,8 +81,14 @@ func GenerateToken(userID, roleID uuid.UUID, doanhNghiepID *uuid.UUID) (string,
 
 func ValidateToken(tokenString string) (*JwtCustomClaims, error) {
 	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "default_secret"
+	}
 
 	token, err := jwt.ParseWithClaims(tokenString, &JwtCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
 		return []byte(secret), nil
 	})
 
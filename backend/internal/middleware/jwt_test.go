@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Token phát hành sau mốc thu hồi trong cùng một giây phải có iat sau mốc đó
+func TestIssuedAtKeepsSubSecondPrecision(t *testing.T) {
+	thuHoi := time.Now()
+	time.Sleep(2 * time.Millisecond)
+
+	token, err := GenerateToken(uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), nil, uuid.Must(uuid.NewV4()))
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if !claims.IssuedAt.Time.After(thuHoi) {
+		t.Errorf("iat %s không sau mốc thu hồi %s", claims.IssuedAt.Time.Format(time.RFC3339Nano), thuHoi.Format(time.RFC3339Nano))
+	}
+}
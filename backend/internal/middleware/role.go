@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Tên role phải khớp với seed data trong bảng 'roles' (định nghĩa ở models để package policy dùng chung)
+const (
+	RoleAdmin       = models.RoleAdmin
+	RoleCanBo       = models.RoleCanBo
+	RoleDoanhNghiep = models.RoleDoanhNghiep
+)
+
+// khoangNapLaiRole là thời gian tối thiểu giữa hai lần nạp lại bảng roles khi gặp role_id lạ,
+// để token mang role_id không tồn tại không biến mỗi request thành một truy vấn CSDL.
+const khoangNapLaiRole = 30 * time.Second
+
+// RoleLoader đọc toàn bộ bảng 'roles'
+type RoleLoader func() ([]models.Role, error)
+
+// RoleResolver tra cứu tên role từ role_id (lấy từ JWT) và cache lại trong bộ nhớ,
+// vì bảng 'roles' gần như không bao giờ thay đổi.
+type RoleResolver struct {
+	load  RoleLoader
+	mu    sync.RWMutex
+	names map[uuid.UUID]string
+
+	// napMu tuần tự hóa việc nạp lại; napLuc là lần nạp gần nhất
+	napMu  sync.Mutex
+	napLuc time.Time
+}
+
+var roleResolver *RoleResolver
+
+// InitRoleResolver phải được gọi một lần khi khởi động server (sau khi kết nối CSDL)
+func InitRoleResolver(db *gorm.DB) *RoleResolver {
+	return InitRoleResolverWithLoader(func() ([]models.Role, error) {
+		var roles []models.Role
+		err := db.Find(&roles).Error
+		return roles, err
+	})
+}
+
+// InitRoleResolverWithLoader như InitRoleResolver nhưng đọc bảng roles qua load (dùng khi không có CSDL, ví dụ trong test)
+func InitRoleResolverWithLoader(load RoleLoader) *RoleResolver {
+	roleResolver = &RoleResolver{
+		load:  load,
+		names: make(map[uuid.UUID]string),
+	}
+	return roleResolver
+}
+
+func (r *RoleResolver) NameOf(roleID uuid.UUID) (string, error) {
+	r.mu.RLock()
+	name, ok := r.names[roleID]
+	r.mu.RUnlock()
+	if ok {
+		return name, nil
+	}
+
+	// Cache miss: nạp lại toàn bộ bảng roles (chỉ vài dòng), tối đa một lần mỗi khoangNapLaiRole
+	if err := r.reload(); err != nil {
+		return "", err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok = r.names[roleID]
+	if !ok {
+		return "", fmt.Errorf("role %s không tồn tại", roleID)
+	}
+	return name, nil
+}
+
+func (r *RoleResolver) reload() error {
+	r.napMu.Lock()
+	defer r.napMu.Unlock()
+	if !r.napLuc.IsZero() && time.Since(r.napLuc) < khoangNapLaiRole {
+		return nil
+	}
+
+	roles, err := r.load()
+	if err != nil {
+		return fmt.Errorf("lỗi khi nạp danh sách role: %w", err)
+	}
+	r.napLuc = time.Now()
+
+	names := make(map[uuid.UUID]string, len(roles))
+	for _, role := range roles {
+		names[role.ID] = role.Name
+	}
+
+	r.mu.Lock()
+	r.names = names
+	r.mu.Unlock()
+	return nil
+}
+
+// RequireRole chỉ cho phép request đi tiếp nếu user có một trong các role được liệt kê.
+// Phải đặt SAU AuthMiddleware (cần 'roleID' trong context).
+func RequireRole(roles ...string) gin.HandlerFunc {
+	allowed := make(map[string]struct{}, len(roles))
+	for _, role := range roles {
+		allowed[role] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		roleIDVal, exists := c.Get("roleID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Yêu cầu đăng nhập (Thiếu Token)"})
+			c.Abort()
+			return
+		}
+
+		if roleResolver == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Hệ thống phân quyền chưa được khởi tạo"})
+			c.Abort()
+			return
+		}
+
+		roleName, err := roleResolver.NameOf(roleIDVal.(uuid.UUID))
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Không xác định được quyền của tài khoản"})
+			c.Abort()
+			return
+		}
+
+		if _, ok := allowed[roleName]; !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Tài khoản không có quyền thực hiện thao tác này"})
+			c.Abort()
+			return
+		}
+
+		c.Set("roleName", roleName)
+		c.Next()
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+)
+
+func TestNameOfDoesNotReloadOnEveryUnknownRole(t *testing.T) {
+	roleID := uuid.Must(uuid.NewV4())
+	soLanNap := 0
+	r := InitRoleResolverWithLoader(func() ([]models.Role, error) {
+		soLanNap++
+		return []models.Role{{ID: roleID, Name: RoleCanBo}}, nil
+	})
+
+	if name, err := r.NameOf(roleID); err != nil || name != RoleCanBo {
+		t.Fatalf("NameOf = %q, %v; muốn %q", name, err, RoleCanBo)
+	}
+
+	khongTonTai := uuid.Must(uuid.NewV4())
+	for i := 0; i < 100; i++ {
+		if _, err := r.NameOf(khongTonTai); err == nil {
+			t.Fatal("role không tồn tại phải trả lỗi")
+		}
+	}
+	if soLanNap != 1 {
+		t.Errorf("nạp bảng roles %d lần, muốn 1", soLanNap)
+	}
+}
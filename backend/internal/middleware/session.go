@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	errTaiKhoanBiKhoa  = errors.New("tài khoản đã bị khóa")
+	errTokenDaThuHoi   = errors.New("token đã bị thu hồi, vui lòng đăng nhập lại")
+	errPhienKhongHopLe = errors.New("phiên đăng nhập không hợp lệ")
+)
+
+// SessionValidator kiểm tra phía server rằng access token (dù còn hạn) chưa bị thu hồi:
+// user còn active, token phát hành sau lần đổi mật khẩu/khóa gần nhất, và phiên chưa bị đăng xuất.
+type SessionValidator struct {
+	db *gorm.DB
+}
+
+var sessionValidator *SessionValidator
+
+// InitSessionValidator phải được gọi một lần khi khởi động server (sau khi kết nối CSDL)
+func InitSessionValidator(db *gorm.DB) *SessionValidator {
+	sessionValidator = &SessionValidator{db: db}
+	return sessionValidator
+}
+
+type sessionState struct {
+	IsActive         bool
+	TokensValidAfter *time.Time
+	SessionFound     bool
+	RevokedAt        *time.Time
+}
+
+func (v *SessionValidator) Validate(claims *JwtCustomClaims) error {
+	var state sessionState
+	err := v.db.Raw(`
+		SELECT u.is_active,
+		       u.tokens_valid_after,
+		       s.id IS NOT NULL AS session_found,
+		       s.revoked_at
+		FROM users u
+		LEFT JOIN user_sessions s ON s.id = ? AND s.user_id = u.id
+		WHERE u.id = ?`, claims.SessionID, claims.UserID).
+		Scan(&state).Error
+	if err != nil {
+		return err
+	}
+
+	if !state.IsActive {
+		return errTaiKhoanBiKhoa
+	}
+	if claims.IssuedAt == nil {
+		return errTokenDaThuHoi
+	}
+	// iat (micro giây) không sau mốc thu hồi thì token bị từ chối; token cũ có iat làm tròn giây
+	// luôn nhỏ hơn hoặc bằng thời điểm phát hành thật nên phép so sánh chỉ có thể nghiêng về phía từ chối
+	if state.TokensValidAfter != nil && !claims.IssuedAt.Time.After(*state.TokensValidAfter) {
+		return errTokenDaThuHoi
+	}
+	// Token đăng ký 2FA không gắn phiên; vẫn bị thu hồi qua tokens_valid_after
+	if claims.Scope == ScopeDangKyHaiLop {
+		return nil
+	}
+	if !state.SessionFound {
+		return errPhienKhongHopLe
+	}
+	if state.RevokedAt != nil {
+		return errTokenDaThuHoi
+	}
+	return nil
+}
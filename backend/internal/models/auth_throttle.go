@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	ThrottleScopeLogin = "login"
+	ThrottleScopeOTP   = "otp"
+	ThrottleScopeIP    = "ip"
+	// Mã TOTP xác nhận lại khi ký số, đếm theo user ID
+	ThrottleScopeTOTP = "totp"
+)
+
+type AuthThrottle struct {
+	Scope        string     `gorm:"type:varchar(20);primaryKey" json:"scope"`
+	Subject      string     `gorm:"type:varchar(255);primaryKey" json:"subject"`
+	FailCount    int        `gorm:"not null;default:0" json:"fail_count"`
+	LastFailedAt *time.Time `json:"last_failed_at,omitempty"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+}
+
+func (AuthThrottle) TableName() string {
+	return "auth_throttles"
+}
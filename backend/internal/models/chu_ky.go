@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Loại chữ ký trên giấy phép
+const (
+	LoaiChuKyNhay  = "KyNhay"  // Ký nháy của người soạn thảo/kiểm tra, chưa phát hành
+	LoaiChuKyChinh = "KyChinh" // Chữ ký của người có thẩm quyền, giấy phép chuyển sang DaKy
+)
+
+// ChuKy là một chữ ký trên giấy phép. Các chữ ký xếp theo ThuTu,
+// chữ ký sau phủ lên file đã mang các chữ ký trước.
+type ChuKy struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	GiayPhepID uuid.UUID `gorm:"type:uuid;not null" json:"giay_phep_id"`
+	ThuTu      int       `gorm:"not null" json:"thu_tu"`
+	LoaiChuKy  string    `gorm:"type:varchar(20);not null" json:"loai_chu_ky"`
+	NguoiKyID  uuid.UUID `gorm:"type:uuid;not null" json:"nguoi_ky_id"`
+	NgayKy     time.Time `gorm:"not null" json:"ngay_ky"`
+
+	// Hash file phát hành ngay sau chữ ký này
+	H2Hash           string     `gorm:"type:text;not null;column:h2_hash" json:"h2_hash"`
+	ChuKySo          string     `gorm:"type:text;not null;column:chu_ky_so" json:"chu_ky_so"`
+	PublicKeyNguoiKy *string    `gorm:"type:text;column:public_key_nguoi_ky" json:"public_key_nguoi_ky,omitempty"`
+	ChungThuID       *uuid.UUID `gorm:"type:uuid;column:chung_thu_id" json:"chung_thu_id,omitempty"`
+	ChuKyCMS         *string    `gorm:"type:text;column:chu_ky_cms" json:"-"`    // CMS SignedData detached (DER, base64)
+	DauThoiGian      *string    `gorm:"type:text;column:dau_thoi_gian" json:"-"` // TimeStampToken RFC 3161 trên ChuKySo
+	NhungPDF         bool       `gorm:"not null;column:nhung_pdf" json:"nhung_pdf"`
+	CreatedAt        time.Time  `json:"created_at"`
+
+	NguoiKy User `gorm:"foreignKey:NguoiKyID" json:"-"`
+}
+
+func (ChuKy) TableName() string {
+	return "chu_ky"
+}
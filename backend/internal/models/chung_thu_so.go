@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// CertificateAuthority là CA nội bộ; private key lưu dạng mã hóa phong bì (xem internal/keystore)
+type CertificateAuthority struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Ten           string     `gorm:"type:text;not null" json:"ten"`
+	Loai          string     `gorm:"type:varchar(10);not null;default:ca" json:"loai"` // "ca" hoặc "tsa"
+	CertPEM       string     `gorm:"type:text;not null;column:cert_pem" json:"cert_pem"`
+	PrivateKeyEnc string     `gorm:"type:text;not null;column:private_key_enc" json:"-"`
+	DEKEnc        string     `gorm:"type:text;not null;column:dek_enc" json:"-"`
+	KEKVersion    int        `gorm:"not null;column:kek_version" json:"-"`
+	CRLNumber     int64      `gorm:"not null;default:0;column:crl_number" json:"-"`
+	CRLDER        []byte     `gorm:"type:bytea;column:crl_der" json:"-"` // CRL đã ký gần nhất
+	CRLNextUpdate *time.Time `gorm:"column:crl_next_update" json:"-"`
+	IsActive      bool       `gorm:"not null;default:false" json:"is_active"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (CertificateAuthority) TableName() string {
+	return "certificate_authorities"
+}
+
+// ChungThuSo là chứng thư số X.509 CA nội bộ cấp cho cán bộ
+type ChungThuSo struct {
+	ID                   uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID               uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
+	CAID                 uuid.UUID  `gorm:"type:uuid;not null;column:ca_id" json:"ca_id"`
+	SerialNumber         string     `gorm:"type:varchar(64);not null;unique" json:"serial_number"`
+	SubjectCN            string     `gorm:"type:text;not null;column:subject_cn" json:"subject_cn"`
+	Email                string     `gorm:"type:varchar(255)" json:"email"`
+	CertPEM              string     `gorm:"type:text;not null;column:cert_pem" json:"cert_pem"`
+	ChainPEM             string     `gorm:"type:text;not null;column:chain_pem" json:"chain_pem"`
+	PublicKeyFingerprint string     `gorm:"type:varchar(64);not null" json:"public_key_fingerprint"`
+	NotBefore            time.Time  `gorm:"not null" json:"not_before"`
+	NotAfter             time.Time  `gorm:"not null" json:"not_after"`
+	RevokedAt            *time.Time `json:"revoked_at,omitempty"`
+	LyDoThuHoi           *string    `gorm:"type:text;column:ly_do_thu_hoi" json:"ly_do_thu_hoi,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+}
+
+func (ChungThuSo) TableName() string {
+	return "chung_thu_so"
+}
@@ -16,20 +16,23 @@ type GiayPhep struct {
 	TrangThaiGiayPhep string    `gorm:"not null" json:"trang_thai_giay_phep"`
 
 	FileDuongDan *string `json:"file_duong_dan,omitempty"`
+	// Bản PDF gốc trước khi nhúng chữ ký PAdES (FileDuongDan khi đó là bản đã ký)
+	FileGocDuongDan *string `gorm:"column:file_goc_duong_dan" json:"file_goc_duong_dan,omitempty"`
 
 	H1Hash              *string `gorm:"column:h1_hash" json:"h1_hash,omitempty"`
 	H2Hash              *string `gorm:"column:h2_hash" json:"h2_hash,omitempty"`
+	H2HashGoc           *string `gorm:"column:h2_hash_goc" json:"h2_hash_goc,omitempty"`
 	TrangThaiBlockchain *string `gorm:"default:'ChuaDongBo';column:trang_thai_blockchain" json:"trang_thai_blockchain,omitempty"`
 
-	ChuKySo          *string    `gorm:"type:text;column:chu_ky_so" json:"chu_ky_so,omitempty"`
-	NguoiKyID        *uuid.UUID `gorm:"type:uuid;column:nguoi_ky_id" json:"nguoi_ky_id,omitempty"`
-	NgayKy           *time.Time `gorm:"column:ngay_ky" json:"ngay_ky,omitempty"`
-	PublicKeyNguoiKy *string    `gorm:"type:text;column:public_key_nguoi_ky" json:"public_key_nguoi_ky,omitempty"`
+	// Dấu thời gian RFC 3161 (TimeStampToken DER, base64) trên lần đẩy (id, h1, h2) lên blockchain
+	DauThoiGianBlockchain *string `gorm:"type:text;column:dau_thoi_gian_blockchain" json:"-"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
 	HoSo HoSo `gorm:"foreignKey:HoSoID" json:"ho_so"`
+	// Các chữ ký theo thứ tự ký, chữ ký cuối cùng phủ file đang phát hành
+	ChuKys []ChuKy `gorm:"foreignKey:GiayPhepID" json:"-"`
 }
 
 func (GiayPhep) TableName() string {
@@ -16,12 +16,22 @@ type HoSo struct {
 	NgayHenTra         time.Time `gorm:"not null" json:"ngay_hen_tra"`
 	SoGiayPhepTheoHoSo string    `json:"so_giay_phep_theo_ho_so,omitempty"`
 	TrangThaiHoSo      string    `gorm:"not null" json:"trang_thai_ho_so"`
-	CreatedAt          time.Time `json:"created_at"`
-	UpdatedAt          time.Time `json:"updated_at"`
+	LyDoTraLai         *string   `gorm:"type:text" json:"ly_do_tra_lai,omitempty"`
+	SoLanBoSung        int       `gorm:"not null;default:0" json:"so_lan_bo_sung"`
+	// Đồng hồ thời hạn dừng khi hồ sơ bị trả lại: NgayTamDung là lúc bắt đầu lần dừng hiện tại,
+	// SoNgayTamDung là tổng số ngày làm việc đã dừng (được cộng vào hạn trả)
+	NgayTamDung   *time.Time `json:"ngay_tam_dung,omitempty"`
+	SoNgayTamDung int        `gorm:"not null;default:0" json:"so_ngay_tam_dung"`
+	// Cán bộ được phân công xử lý (thủ công hoặc tự động khi tiếp nhận)
+	CanBoXuLyID  *uuid.UUID `gorm:"type:uuid" json:"can_bo_xu_ly_id,omitempty"`
+	NgayPhanCong *time.Time `json:"ngay_phan_cong,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 
 	DoanhNghiep  DoanhNghiep   `gorm:"foreignKey:DoanhNghiepID" json:"doanh_nghiep"`
 	GiayPhep     *GiayPhep     `gorm:"foreignKey:HoSoID" json:"giay_phep,omitempty"`
 	HoSoTaiLieus []HoSoTaiLieu `gorm:"foreignKey:HoSoID" json:"ho_so_tai_lieus,omitempty"`
+	CanBoXuLy    *User         `gorm:"foreignKey:CanBoXuLyID" json:"-"`
 }
 
 func (HoSo) TableName() string {
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Loại sự kiện trong nhật ký xử lý hồ sơ
+const (
+	SuKienTaoHoSo         = "TaoHoSo"
+	SuKienCapNhatHoSo     = "CapNhatHoSo"
+	SuKienChuyenTrangThai = "ChuyenTrangThai"
+	SuKienNopTaiLieu      = "NopTaiLieu"
+	SuKienXoaTaiLieu      = "XoaTaiLieu"
+	SuKienCapGiayPhep     = "CapGiayPhep"
+	SuKienXoaGiayPhep     = "XoaGiayPhep"
+	SuKienPhanCong        = "PhanCong"
+)
+
+// HoSoLichSu là một sự kiện trong quá trình xử lý hồ sơ.
+// DuLieuTruoc/DuLieuSau là JSON các trường thay đổi (chỉ ghi phần liên quan tới sự kiện).
+type HoSoLichSu struct {
+	ID              uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	HoSoID          uuid.UUID  `gorm:"type:uuid;not null" json:"ho_so_id"`
+	LoaiSuKien      string     `gorm:"type:varchar(50);not null" json:"loai_su_kien"`
+	NguoiThucHienID *uuid.UUID `gorm:"type:uuid" json:"nguoi_thuc_hien_id,omitempty"`
+	TrangThaiTruoc  *string    `gorm:"type:varchar(50)" json:"trang_thai_truoc,omitempty"`
+	TrangThaiSau    *string    `gorm:"type:varchar(50)" json:"trang_thai_sau,omitempty"`
+	DuLieuTruoc     *string    `gorm:"type:jsonb" json:"du_lieu_truoc,omitempty"`
+	DuLieuSau       *string    `gorm:"type:jsonb" json:"du_lieu_sau,omitempty"`
+	GhiChu          *string    `gorm:"type:text" json:"ghi_chu,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+
+	NguoiThucHien *User `gorm:"foreignKey:NguoiThucHienID" json:"-"`
+}
+
+func (HoSoLichSu) TableName() string {
+	return "ho_so_lich_su"
+}
@@ -6,6 +6,8 @@ type HoSoTaiLieu struct {
 	ID            uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	HoSoID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_ho_so_loai_tai_lieu" json:"ho_so_id"`
 	LoaiTaiLieuID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_ho_so_loai_tai_lieu" json:"loai_tai_lieu_id"`
+	// Chép từ danh mục thủ tục lúc tạo hồ sơ
+	BatBuoc bool `gorm:"not null" json:"bat_buoc"`
 
 	HoSo        HoSo        `gorm:"foreignKey:HoSoID" json:"-"`
 	LoaiTaiLieu LoaiTaiLieu `gorm:"foreignKey:LoaiTaiLieuID" json:"loai_tai_lieu,omitempty"`
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// NgayNghiLe là một ngày nghỉ lễ, Tết; LaNgayLamBu đánh dấu ngày cuối tuần phải đi làm bù
+type NgayNghiLe struct {
+	Ngay        time.Time `gorm:"type:date;primaryKey" json:"ngay"`
+	Ten         string    `gorm:"type:text;not null" json:"ten"`
+	LaNgayLamBu bool      `gorm:"not null;default:false" json:"la_ngay_lam_bu"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (NgayNghiLe) TableName() string {
+	return "ngay_nghi_le"
+}
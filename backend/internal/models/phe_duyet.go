@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// BuocDuyet là một bước trong quy trình phê duyệt của một loại giấy phép.
+// Bước có ThuTu lớn nhất là bước ký số.
+type BuocDuyet struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	LoaiGiayPhep string    `gorm:"type:varchar(100);not null" json:"loai_giay_phep"`
+	ThuTu        int       `gorm:"not null" json:"thu_tu"`
+	TenBuoc      string    `gorm:"type:text;not null" json:"ten_buoc"`
+	ChucVu       string    `gorm:"type:varchar(50);not null" json:"chuc_vu"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (BuocDuyet) TableName() string {
+	return "buoc_duyet"
+}
+
+// Quyết định tại một bước phê duyệt
+const (
+	QuyetDinhDongY  = "DongY"
+	QuyetDinhTuChoi = "TuChoi"
+)
+
+// PheDuyetGiayPhep ghi nhận quyết định của một cán bộ tại một bước phê duyệt
+type PheDuyetGiayPhep struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	GiayPhepID   uuid.UUID `gorm:"type:uuid;not null" json:"giay_phep_id"`
+	ThuTu        int       `gorm:"not null" json:"thu_tu"`
+	TenBuoc      string    `gorm:"type:text;not null" json:"ten_buoc"`
+	ChucVu       string    `gorm:"type:varchar(50);not null" json:"chuc_vu"`
+	NguoiDuyetID uuid.UUID `gorm:"type:uuid;not null" json:"nguoi_duyet_id"`
+	QuyetDinh    string    `gorm:"type:varchar(20);not null" json:"quyet_dinh"`
+	YKien        *string   `gorm:"type:text" json:"y_kien,omitempty"`
+	H2Hash       string    `gorm:"type:text;not null;column:h2_hash" json:"h2_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	NguoiDuyet User `gorm:"foreignKey:NguoiDuyetID" json:"-"`
+}
+
+func (PheDuyetGiayPhep) TableName() string {
+	return "phe_duyet_giay_phep"
+}
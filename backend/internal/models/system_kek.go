@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SystemKEK là khóa mã hóa khóa (Key Encryption Key), lưu ở dạng đã mã hóa bằng APP_MASTER_KEY
+type SystemKEK struct {
+	Version      int        `gorm:"primaryKey;autoIncrement" json:"version"`
+	EncryptedKEK string     `gorm:"type:text;not null;column:encrypted_kek" json:"-"`
+	IsActive     bool       `gorm:"not null;default:false" json:"is_active"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RetiredAt    *time.Time `json:"retired_at,omitempty"`
+}
+
+func (SystemKEK) TableName() string {
+	return "system_keks"
+}
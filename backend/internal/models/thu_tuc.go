@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// ThuTuc là một thủ tục hành chính trong danh mục; HoSo.LoaiThuTuc lưu tên thủ tục
+type ThuTuc struct {
+	ID   uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Ten  string    `gorm:"type:varchar(255);not null;unique" json:"ten"`
+	MoTa *string   `gorm:"type:text" json:"mo_ta,omitempty"`
+	// Thời hạn giải quyết tính theo ngày làm việc
+	SoNgayLamViec int `gorm:"not null" json:"so_ngay_lam_viec"`
+	// Loại giấy phép được cấp khi hồ sơ được duyệt; nil: thủ tục không cấp giấy phép
+	LoaiGiayPhep *string `gorm:"type:varchar(100)" json:"loai_giay_phep,omitempty"`
+	// Thời hạn hiệu lực của giấy phép được cấp, tính theo tháng
+	SoThangHieuLuc *int      `json:"so_thang_hieu_luc,omitempty"`
+	HoatDong       bool      `gorm:"not null" json:"hoat_dong"`
+	ThuTuHienThi   int       `gorm:"not null;default:0" json:"thu_tu_hien_thi"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	TaiLieus []ThuTucTaiLieu `gorm:"foreignKey:ThuTucID" json:"tai_lieus"`
+}
+
+func (ThuTuc) TableName() string {
+	return "thu_tuc"
+}
+
+// ThuTucTaiLieu là một thành phần hồ sơ của thủ tục
+type ThuTucTaiLieu struct {
+	ID            uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ThuTucID      uuid.UUID `gorm:"type:uuid;not null" json:"thu_tuc_id"`
+	LoaiTaiLieuID uuid.UUID `gorm:"type:uuid;not null" json:"loai_tai_lieu_id"`
+	BatBuoc       bool      `gorm:"not null" json:"bat_buoc"`
+	ThuTu         int       `gorm:"not null;default:0" json:"thu_tu"`
+
+	LoaiTaiLieu LoaiTaiLieu `gorm:"foreignKey:LoaiTaiLieuID" json:"loai_tai_lieu"`
+}
+
+func (ThuTucTaiLieu) TableName() string {
+	return "thu_tuc_tai_lieu"
+}
@@ -6,6 +6,13 @@ import (
 	"github.com/gofrs/uuid"
 )
 
+// Tên role trong bảng 'roles' (seed data)
+const (
+	RoleAdmin       = "ADMIN"
+	RoleCanBo       = "CAN_BO"
+	RoleDoanhNghiep = "DOANH_NGHIEP"
+)
+
 type Role struct {
 	ID   uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	Name string    `gorm:"type:varchar(50);unique;not null" json:"name"`
@@ -21,13 +28,48 @@ type User struct {
 	RoleID        uuid.UUID  `gorm:"type:uuid;not null" json:"role_id"`
 	Role          Role       `gorm:"foreignKey:RoleID" json:"role"`
 	DoanhNghiepID *uuid.UUID `gorm:"type:uuid" json:"doanh_nghiep_id"`
+	// Chức vụ trong quy trình phê duyệt giấy phép (xem BuocDuyet)
+	ChucVu *string `gorm:"type:varchar(50);column:chuc_vu" json:"chuc_vu,omitempty"`
 
+	// Bản rõ (legacy) - chỉ còn tồn tại trước khi chạy cmd/migrate-private-keys
 	PrivateKeyPEM *string `gorm:"type:text;column:private_key_pem" json:"-"`
 	PublicKeyPEM  *string `gorm:"type:text;column:public_key_pem" json:"public_key_pem,omitempty"`
 
+	// Private key đã mã hóa bằng DEK riêng, DEK mã hóa bằng KEK phiên bản KEKVersion (xem internal/keystore)
+	PrivateKeyEnc *string `gorm:"type:text;column:private_key_enc" json:"-"`
+	DEKEnc        *string `gorm:"type:text;column:dek_enc" json:"-"`
+	KEKVersion    *int    `gorm:"column:kek_version" json:"-"`
+
+	// Signer dùng khi ký số: "db" (khóa trong CSDL) hoặc "pkcs11" (khóa trong HSM, nhãn HSMKeyLabel)
+	SignerType  string  `gorm:"type:varchar(20);column:signer_type;default:db" json:"signer_type"`
+	HSMKeyLabel *string `gorm:"type:varchar(255);column:hsm_key_label" json:"hsm_key_label,omitempty"`
+
 	OtpCode   *string    `gorm:"type:varchar(10);column:otp_code" json:"-"`
 	OtpExpiry *time.Time `gorm:"column:otp_expiry" json:"-"`
+	// Số lần nhập sai OTP hiện tại, vượt ngưỡng thì OTP bị hủy
+	OtpFailedCount int `gorm:"column:otp_failed_count;not null;default:0" json:"-"`
+
+	// Xác thực 2 lớp (TOTP). Secret lưu ở dạng mã hóa (base64 của AES-GCM)
+	TOTPSecretEnc *string `gorm:"type:text;column:totp_secret_enc" json:"-"`
+	TOTPEnabled   bool    `gorm:"column:totp_enabled;default:false" json:"totp_enabled"`
+	TOTPLastStep  *int64  `gorm:"column:totp_last_step" json:"-"`
+
+	// Access token có iat trước mốc này bị coi là đã thu hồi
+	TokensValidAfter *time.Time `gorm:"column:tokens_valid_after" json:"-"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// UserRecoveryCode là mã khôi phục 2FA dùng một lần
+type UserRecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
+	CodeHash  string     `gorm:"type:varchar(64);not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (UserRecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type UserSession struct {
+	ID               uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID           uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	RefreshTokenHash string     `gorm:"type:varchar(64);not null;unique" json:"-"`
+	UserAgent        string     `json:"user_agent,omitempty"`
+	IPAddress        string     `gorm:"type:varchar(64)" json:"ip_address,omitempty"`
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy       *uuid.UUID `gorm:"type:uuid" json:"replaced_by,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Trạng thái một vòng yêu cầu bổ sung
+const (
+	YeuCauBoSungChoBoSung = "ChoBoSung"
+	YeuCauBoSungDaBoSung  = "DaBoSung"
+)
+
+// YeuCauBoSung là một vòng trả lại hồ sơ để doanh nghiệp bổ sung tài liệu
+type YeuCauBoSung struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	HoSoID        uuid.UUID  `gorm:"type:uuid;not null" json:"ho_so_id"`
+	Lan           int        `gorm:"not null" json:"lan"`
+	NguoiYeuCauID *uuid.UUID `gorm:"type:uuid" json:"nguoi_yeu_cau_id,omitempty"`
+	NoiDung       string     `gorm:"type:text;not null" json:"noi_dung"`
+	TrangThai     string     `gorm:"type:varchar(20);not null" json:"trang_thai"`
+	NgayNopLai    *time.Time `json:"ngay_nop_lai,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	TaiLieus []YeuCauBoSungTaiLieu `gorm:"foreignKey:YeuCauBoSungID" json:"tai_lieus"`
+}
+
+func (YeuCauBoSung) TableName() string {
+	return "yeu_cau_bo_sung"
+}
+
+// YeuCauBoSungTaiLieu là một khe tài liệu cần bổ sung trong một vòng
+type YeuCauBoSungTaiLieu struct {
+	ID             uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	YeuCauBoSungID uuid.UUID  `gorm:"type:uuid;not null" json:"yeu_cau_bo_sung_id"`
+	HoSoTaiLieuID  uuid.UUID  `gorm:"type:uuid;not null" json:"ho_so_tai_lieu_id"`
+	LyDo           string     `gorm:"type:text;not null" json:"ly_do"`
+	DaBoSung       bool       `gorm:"not null" json:"da_bo_sung"`
+	NgayBoSung     *time.Time `json:"ngay_bo_sung,omitempty"`
+
+	HoSoTaiLieu HoSoTaiLieu `gorm:"foreignKey:HoSoTaiLieuID" json:"-"`
+}
+
+func (YeuCauBoSungTaiLieu) TableName() string {
+	return "yeu_cau_bo_sung_tai_lieu"
+}
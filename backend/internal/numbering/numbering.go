@@ -0,0 +1,161 @@
+// Package numbering dựng số hiệu (mã hồ sơ, số giấy phép) từ mẫu cấu hình và số thứ tự trong năm.
+//
+// Mẫu gồm chữ thường và các trường:
+//
+//	{seq}    số thứ tự trong năm
+//	{seq:N}  số thứ tự, thêm số 0 phía trước cho đủ N chữ số (N từ 1 đến 12)
+//	{year}   năm 4 chữ số
+//	{yy}     năm 2 chữ số
+//
+// Ví dụ: "{seq}/{year}/GP-BCY" -> "15/2025/GP-BCY", "HS-{year}-{seq:6}" -> "HS-2025-000015".
+//
+// Số thứ tự chạy lại từ 1 mỗi năm nên mẫu phải có năm ({year} hoặc {yy}), nếu không số hiệu sẽ lặp lại từ năm sau.
+package numbering
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrMauThieuSeq      = errors.New("mẫu số hiệu phải có trường {seq}")
+	ErrMauThieuNam      = errors.New("mẫu số hiệu phải có trường {year} hoặc {yy}")
+	ErrMauTruongKhongRo = errors.New("mẫu số hiệu có trường không hỗ trợ")
+)
+
+type loaiPhan int
+
+const (
+	phanChu loaiPhan = iota
+	phanSeq
+	phanNam
+	phanNam2
+)
+
+type phan struct {
+	loai  loaiPhan
+	chu   string
+	doDai int
+}
+
+// Mau là mẫu số hiệu đã kiểm tra cú pháp
+type Mau struct {
+	goc  string
+	phan []phan
+	// khop nhận mọi số hiệu mẫu có thể sinh ra, với bất kỳ số thứ tự và năm nào
+	khop *regexp.Regexp
+}
+
+// Parse kiểm tra cú pháp mẫu; mẫu bắt buộc có đúng một trường {seq} để số hiệu không trùng trong năm
+func Parse(s string) (*Mau, error) {
+	m := &Mau{goc: s}
+	soSeq, soNam := 0, 0
+	con := s
+	for con != "" {
+		mo := strings.IndexByte(con, '{')
+		if mo < 0 {
+			m.phan = append(m.phan, phan{loai: phanChu, chu: con})
+			break
+		}
+		if mo > 0 {
+			m.phan = append(m.phan, phan{loai: phanChu, chu: con[:mo]})
+		}
+		dong := strings.IndexByte(con[mo:], '}')
+		if dong < 0 {
+			return nil, fmt.Errorf("%w: thiếu '}' trong %q", ErrMauTruongKhongRo, s)
+		}
+		truong := con[mo+1 : mo+dong]
+		p, err := parseTruong(truong)
+		if err != nil {
+			return nil, err
+		}
+		switch p.loai {
+		case phanSeq:
+			soSeq++
+		case phanNam, phanNam2:
+			soNam++
+		}
+		m.phan = append(m.phan, p)
+		con = con[mo+dong+1:]
+	}
+	if soSeq != 1 {
+		return nil, fmt.Errorf("%w: %q", ErrMauThieuSeq, s)
+	}
+	if soNam == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrMauThieuNam, s)
+	}
+	m.khop = m.bieuThuc()
+	return m, nil
+}
+
+func (m *Mau) bieuThuc() *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, p := range m.phan {
+		switch p.loai {
+		case phanChu:
+			b.WriteString(regexp.QuoteMeta(p.chu))
+		case phanSeq:
+			if p.doDai > 0 {
+				fmt.Fprintf(&b, `\d{%d,}`, p.doDai)
+			} else {
+				b.WriteString(`\d+`)
+			}
+		case phanNam:
+			b.WriteString(`\d{4}`)
+		case phanNam2:
+			b.WriteString(`\d{2}`)
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func parseTruong(truong string) (phan, error) {
+	switch truong {
+	case "seq":
+		return phan{loai: phanSeq}, nil
+	case "year":
+		return phan{loai: phanNam}, nil
+	case "yy":
+		return phan{loai: phanNam2}, nil
+	}
+	if doDai, ok := strings.CutPrefix(truong, "seq:"); ok {
+		n, err := strconv.Atoi(doDai)
+		if err != nil || n < 1 || n > 12 {
+			return phan{}, fmt.Errorf("%w: {%s}", ErrMauTruongKhongRo, truong)
+		}
+		return phan{loai: phanSeq, doDai: n}, nil
+	}
+	return phan{}, fmt.Errorf("%w: {%s}", ErrMauTruongKhongRo, truong)
+}
+
+// Format dựng số hiệu từ số thứ tự và năm
+func (m *Mau) Format(seq int64, nam int) string {
+	var b strings.Builder
+	for _, p := range m.phan {
+		switch p.loai {
+		case phanChu:
+			b.WriteString(p.chu)
+		case phanSeq:
+			fmt.Fprintf(&b, "%0*d", p.doDai, seq)
+		case phanNam:
+			fmt.Fprintf(&b, "%04d", nam)
+		case phanNam2:
+			fmt.Fprintf(&b, "%02d", nam%100)
+		}
+	}
+	return b.String()
+}
+
+// Match cho biết s có dạng số hiệu mẫu sinh ra hay không (với bất kỳ số thứ tự và năm nào)
+func (m *Mau) Match(s string) bool {
+	return m.khop.MatchString(s)
+}
+
+func (m *Mau) String() string {
+	return m.goc
+}
@@ -0,0 +1,73 @@
+package numbering
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseKhongHopLe(t *testing.T) {
+	tests := []struct {
+		mau     string
+		wantErr error
+	}{
+		{mau: "", wantErr: ErrMauThieuSeq},
+		{mau: "GP-{year}", wantErr: ErrMauThieuSeq},
+		{mau: "{seq}-{seq}/{year}", wantErr: ErrMauThieuSeq},
+		{mau: "{seq}/GP-BCY", wantErr: ErrMauThieuNam},
+		{mau: "HS-{seq:6}", wantErr: ErrMauThieuNam},
+		{mau: "{seq}/{nam}", wantErr: ErrMauTruongKhongRo},
+		{mau: "{seq}/{year", wantErr: ErrMauTruongKhongRo},
+		{mau: "{seq:0}/{year}", wantErr: ErrMauTruongKhongRo},
+		{mau: "{seq:13}/{year}", wantErr: ErrMauTruongKhongRo},
+		{mau: "{seq:x}/{year}", wantErr: ErrMauTruongKhongRo},
+		{mau: "{seq:}/{year}", wantErr: ErrMauTruongKhongRo},
+	}
+	for _, tc := range tests {
+		if _, err := Parse(tc.mau); !errors.Is(err, tc.wantErr) {
+			t.Errorf("Parse(%q): err = %v, muốn %v", tc.mau, err, tc.wantErr)
+		}
+	}
+}
+
+func TestFormatMatch(t *testing.T) {
+	tests := []struct {
+		mau  string
+		seq  int64
+		nam  int
+		want string
+		// khongKhop là các chuỗi gần giống nhưng mẫu không thể sinh ra
+		khongKhop []string
+	}{
+		{mau: "{seq}/{year}/GP-BCY", seq: 15, nam: 2025, want: "15/2025/GP-BCY",
+			khongKhop: []string{"15/25/GP-BCY", "15/2025/GP-BCYX", "a/2025/GP-BCY", "/2025/GP-BCY"}},
+		{mau: "HS-{year}-{seq:6}", seq: 15, nam: 2025, want: "HS-2025-000015",
+			khongKhop: []string{"HS-2025-15", "HS-2025-00015", "hs-2025-000015"}},
+		{mau: "HS-{year}-{seq:3}", seq: 12345, nam: 2025, want: "HS-2025-12345"},
+		{mau: "{yy}.{seq:4}", seq: 7, nam: 2031, want: "31.0007",
+			khongKhop: []string{"31-0007", "2031.0007"}},
+		{mau: "{seq}/{yy}", seq: 1, nam: 2000, want: "1/00"},
+		{mau: "GP(1).{seq}+{year}", seq: 2, nam: 2025, want: "GP(1).2+2025",
+			khongKhop: []string{"GP1.2+2025", "GP(1)x2+2025"}},
+	}
+	for _, tc := range tests {
+		m, err := Parse(tc.mau)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.mau, err)
+		}
+		if m.String() != tc.mau {
+			t.Errorf("String() = %q, muốn %q", m.String(), tc.mau)
+		}
+		got := m.Format(tc.seq, tc.nam)
+		if got != tc.want {
+			t.Errorf("%q.Format(%d, %d) = %q, muốn %q", tc.mau, tc.seq, tc.nam, got, tc.want)
+		}
+		if !m.Match(got) {
+			t.Errorf("%q không khớp số hiệu chính nó sinh ra %q", tc.mau, got)
+		}
+		for _, s := range tc.khongKhop {
+			if m.Match(s) {
+				t.Errorf("%q không được khớp %q", tc.mau, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,409 @@
+// Package pades nhúng chữ ký CMS vào file PDF theo PAdES baseline (ETSI EN 319 142-1, mức B-B):
+// chữ ký được thêm bằng một bản cập nhật tăng dần (incremental update) nên nội dung gốc giữ nguyên byte,
+// và bất kỳ trình đọc PDF nào (Adobe Reader, Foxit...) cũng tự kiểm tra được.
+package pades
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+var (
+	ErrKhongPhaiPDF       = errors.New("file giấy phép không phải PDF")
+	ErrPDFDaMaHoa         = errors.New("không hỗ trợ ký PDF đã đặt mật khẩu/mã hóa")
+	ErrKhongCoChuKyPDF    = errors.New("file PDF không chứa chữ ký nhúng")
+	ErrByteRangeKhongDu   = errors.New("chữ ký PDF không phủ toàn bộ file (có nội dung được thêm sau khi ký)")
+	ErrChuKyVuotKichThuoc = errors.New("chữ ký CMS lớn hơn vùng dành sẵn trong PDF")
+)
+
+// contentsSize là số byte dành sẵn cho CMS trong /Contents (chứng thư + chuỗi CA + dấu thời gian)
+const contentsSize = 16384
+
+// Độ rộng cố định của mảng /ByteRange để có thể điền giá trị sau khi đã biết vị trí /Contents
+const byteRangePlaceholder = "[0 0000000000 0000000000 0000000000]"
+
+// Options là thông tin hiển thị trong bảng chữ ký của trình đọc PDF
+type Options struct {
+	Name        string
+	Reason      string
+	Location    string
+	ContactInfo string
+	SigningTime time.Time
+}
+
+// SignFunc nhận SHA-256 của phần file được ký (mọi byte trừ /Contents) và trả về CMS SignedData (DER)
+type SignFunc func(digest []byte) ([]byte, error)
+
+// IsPDF nhận diện PDF qua phần header %PDF-
+func IsPDF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("%PDF-"))
+}
+
+// Sign thêm một chữ ký PAdES (SubFilter ETSI.CAdES.detached) vào PDF và trả về file đã ký
+func Sign(pdf []byte, opts Options, sign SignFunc) ([]byte, error) {
+	if !IsPDF(pdf) {
+		return nil, ErrKhongPhaiPDF
+	}
+	prevXRef, err := lastStartXRef(pdf)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+	ctx, err := api.ReadContext(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, fmt.Errorf("không đọc được cấu trúc PDF: %w", err)
+	}
+	xrt := ctx.XRefTable
+	if xrt.Encrypt != nil {
+		return nil, ErrPDFDaMaHoa
+	}
+	if xrt.Root == nil || xrt.Size == nil {
+		return nil, errors.New("PDF thiếu /Root hoặc /Size trong trailer")
+	}
+	if err := xrt.EnsurePageCount(); err != nil {
+		return nil, err
+	}
+
+	u := &update{nextObjNr: *xrt.Size, objects: map[int]string{}}
+
+	sigNr := u.newObject()
+	widgetNr := u.newObject()
+	sigRef := types.NewIndirectRef(sigNr, 0)
+	widgetRef := types.NewIndirectRef(widgetNr, 0)
+
+	// Trường chữ ký ẩn (Rect 0) gắn với trang 1
+	pageDict, pageRef, _, err := xrt.PageDict(1, false)
+	if err != nil {
+		return nil, fmt.Errorf("không đọc được trang 1: %w", err)
+	}
+	if err := u.appendToArray(xrt, pageDict, "Annots", *widgetRef); err != nil {
+		return nil, err
+	}
+	u.setObject(pageRef, pageDict.PDFString())
+
+	catalog, err := xrt.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	fieldCount, err := u.addSignatureField(xrt, catalog, *widgetRef)
+	if err != nil {
+		return nil, err
+	}
+	u.setObject(xrt.Root, catalog.PDFString())
+
+	u.objects[widgetNr] = fmt.Sprintf("<</Type/Annot/Subtype/Widget/FT/Sig/Rect[0 0 0 0]/F 132/T%s/V %s/P %s>>",
+		pdfText(fmt.Sprintf("ChuKySo%d", fieldCount+1)), sigRef.PDFString(), pageRef.PDFString())
+
+	signingTime := opts.SigningTime
+	if signingTime.IsZero() {
+		signingTime = time.Now()
+	}
+	var sigDict strings.Builder
+	sigDict.WriteString("<</Type/Sig/Filter/Adobe.PPKLite/SubFilter/ETSI.CAdES.detached")
+	sigDict.WriteString("/ByteRange" + byteRangePlaceholder)
+	sigDict.WriteString("/Contents<" + strings.Repeat("0", contentsSize*2) + ">")
+	sigDict.WriteString("/M" + pdfDate(signingTime))
+	for _, kv := range [][2]string{{"Name", opts.Name}, {"Reason", opts.Reason}, {"Location", opts.Location}, {"ContactInfo", opts.ContactInfo}} {
+		if kv[1] != "" {
+			sigDict.WriteString("/" + kv[0] + pdfText(kv[1]))
+		}
+	}
+	sigDict.WriteString(">>")
+	u.objects[sigNr] = sigDict.String()
+
+	out, err := u.write(pdf, ctx, prevXRef)
+	if err != nil {
+		return nil, err
+	}
+
+	// Điền /ByteRange rồi ký trên mọi byte ngoài /Contents
+	sigStart := bytes.Index(out[len(pdf):], []byte(fmt.Sprintf("\n%d 0 obj\n", sigNr)))
+	if sigStart < 0 {
+		return nil, errors.New("không tìm thấy từ điển chữ ký vừa ghi")
+	}
+	sigStart += len(pdf)
+	brPos := sigStart + bytes.Index(out[sigStart:], []byte(byteRangePlaceholder))
+	contentsPos := sigStart + bytes.Index(out[sigStart:], []byte("/Contents<")) + len("/Contents")
+	contentsEnd := contentsPos + contentsSize*2 + 2
+
+	byteRange := fmt.Sprintf("[0 %d %d %d]", contentsPos, contentsEnd, len(out)-contentsEnd)
+	copy(out[brPos:], byteRange+strings.Repeat(" ", len(byteRangePlaceholder)-len(byteRange)))
+
+	h := sha256.New()
+	h.Write(out[:contentsPos])
+	h.Write(out[contentsEnd:])
+	cmsDER, err := sign(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	if len(cmsDER) > contentsSize {
+		return nil, ErrChuKyVuotKichThuoc
+	}
+	copy(out[contentsPos+1:], strings.ToUpper(hex.EncodeToString(cmsDER)))
+	return out, nil
+}
+
+// Signature là một chữ ký nhúng trong PDF
+type Signature struct {
+	CMS    []byte // CMS SignedData (DER) trong /Contents
+	Digest []byte // SHA-256 của vùng /ByteRange
+	End    int    // Vị trí kết thúc vùng được ký: chữ ký phủ bản sửa đổi pdf[:End]
+}
+
+// ExtractAll lấy mọi chữ ký nhúng theo thứ tự xuất hiện trong file. Mỗi chữ ký chỉ phủ bản sửa đổi
+// tại thời điểm ký (pdf[:End]); các bản cập nhật tăng dần sau đó do chữ ký sau phủ.
+func ExtractAll(pdf []byte) ([]Signature, error) {
+	matches := byteRangeRe.FindAllSubmatchIndex(pdf, -1)
+	if len(matches) == 0 {
+		return nil, ErrKhongCoChuKyPDF
+	}
+
+	sigs := make([]Signature, 0, len(matches))
+	for _, m := range matches {
+		var r [4]int
+		for i := range r {
+			v, err := strconv.Atoi(string(pdf[m[2+2*i]:m[3+2*i]]))
+			if err != nil {
+				return nil, ErrKhongCoChuKyPDF
+			}
+			r[i] = v
+		}
+		if r[0] != 0 || r[1] <= 0 || r[2] <= r[1] || r[2]+r[3] > len(pdf) {
+			return nil, errors.New("/ByteRange không hợp lệ")
+		}
+		if pdf[r[1]] != '<' || pdf[r[2]-1] != '>' {
+			return nil, errors.New("/ByteRange không trỏ đúng vào /Contents")
+		}
+
+		cmsDER, err := hex.DecodeString(string(pdf[r[1]+1 : r[2]-1]))
+		if err != nil {
+			return nil, fmt.Errorf("/Contents không hợp lệ: %w", err)
+		}
+		h := sha256.New()
+		h.Write(pdf[:r[1]])
+		h.Write(pdf[r[2] : r[2]+r[3]])
+		sigs = append(sigs, Signature{CMS: cmsDER, Digest: h.Sum(nil), End: r[2] + r[3]})
+	}
+	return sigs, nil
+}
+
+// Extract lấy CMS của chữ ký cuối cùng trong PDF cùng SHA-256 của vùng /ByteRange.
+// Yêu cầu /ByteRange phủ tới cuối file, tức không có bản cập nhật nào sau chữ ký.
+func Extract(pdf []byte) (cmsDER []byte, digest []byte, err error) {
+	sigs, err := ExtractAll(pdf)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := sigs[len(sigs)-1]
+	if last.End != len(pdf) {
+		return nil, nil, ErrByteRangeKhongDu
+	}
+	return last.CMS, last.Digest, nil
+}
+
+var byteRangeRe = regexp.MustCompile(`/ByteRange\s*\[\s*(\d+)\s+(\d+)\s+(\d+)\s+(\d+)\s*\]`)
+
+var startXRefRe = regexp.MustCompile(`startxref\s+(\d+)`)
+
+func lastStartXRef(pdf []byte) (int64, error) {
+	matches := startXRefRe.FindAllSubmatch(pdf, -1)
+	if len(matches) == 0 {
+		return 0, errors.New("PDF thiếu startxref")
+	}
+	return strconv.ParseInt(string(matches[len(matches)-1][1]), 10, 64)
+}
+
+// update gom các object mới/sửa đổi cho bản cập nhật tăng dần
+type update struct {
+	nextObjNr int
+	objects   map[int]string // số object -> nội dung (thế hệ giữ nguyên như bản gốc)
+	gens      map[int]int
+}
+
+func (u *update) newObject() int {
+	nr := u.nextObjNr
+	u.nextObjNr++
+	return nr
+}
+
+func (u *update) setObject(ref *types.IndirectRef, body string) {
+	nr := ref.ObjectNumber.Value()
+	u.objects[nr] = body
+	if g := ref.GenerationNumber.Value(); g != 0 {
+		if u.gens == nil {
+			u.gens = map[int]int{}
+		}
+		u.gens[nr] = g
+	}
+}
+
+// appendToArray thêm item vào mảng d[key]; nếu mảng là object gián tiếp thì ghi lại chính object đó
+func (u *update) appendToArray(xrt *model.XRefTable, d types.Dict, key string, item types.Object) error {
+	entry, ok := d.Find(key)
+	if !ok || entry == nil {
+		d[key] = types.Array{item}
+		return nil
+	}
+	if ref, isRef := entry.(types.IndirectRef); isRef {
+		arr, err := xrt.DereferenceArray(ref)
+		if err != nil {
+			return err
+		}
+		arr = append(arr, item)
+		u.setObject(&ref, arr.PDFString())
+		return nil
+	}
+	arr, isArr := entry.(types.Array)
+	if !isArr {
+		return fmt.Errorf("/%s không phải mảng", key)
+	}
+	d[key] = append(arr, item)
+	return nil
+}
+
+// addSignatureField đưa widget chữ ký vào /AcroForm /Fields và bật SigFlags; trả về số trường đã có trước đó
+func (u *update) addSignatureField(xrt *model.XRefTable, catalog types.Dict, widget types.IndirectRef) (int, error) {
+	entry, ok := catalog.Find("AcroForm")
+	if !ok || entry == nil {
+		catalog["AcroForm"] = types.Dict{"Fields": types.Array{widget}, "SigFlags": types.Integer(3)}
+		return 0, nil
+	}
+
+	acroForm, err := xrt.DereferenceDict(entry)
+	if err != nil {
+		return 0, err
+	}
+	if acroForm == nil {
+		acroForm = types.Dict{}
+	}
+	existing, _ := xrt.DereferenceArray(acroForm["Fields"])
+	if err := u.appendToArray(xrt, acroForm, "Fields", widget); err != nil {
+		return 0, err
+	}
+	acroForm["SigFlags"] = types.Integer(3)
+
+	if ref, isRef := entry.(types.IndirectRef); isRef {
+		u.setObject(&ref, acroForm.PDFString())
+	} else {
+		catalog["AcroForm"] = acroForm
+	}
+	return len(existing), nil
+}
+
+// write nối các object vào cuối file kèm bảng xref mới (cùng kiểu với bản gốc: bảng thường hoặc xref stream)
+func (u *update) write(pdf []byte, ctx *model.Context, prevXRef int64) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(pdf)
+	if !bytes.HasSuffix(pdf, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+
+	nrs := make([]int, 0, len(u.objects)+1)
+	for nr := range u.objects {
+		nrs = append(nrs, nr)
+	}
+	sort.Ints(nrs)
+
+	offsets := map[int]int{}
+	for _, nr := range nrs {
+		buf.WriteByte('\n')
+		offsets[nr] = buf.Len()
+		fmt.Fprintf(&buf, "%d %d obj\n%s\nendobj", nr, u.gens[nr], u.objects[nr])
+	}
+	buf.WriteByte('\n')
+
+	xrt := ctx.XRefTable
+	trailer := fmt.Sprintf("/Root %s/Prev %d", xrt.Root.PDFString(), prevXRef)
+	if xrt.Info != nil {
+		trailer += "/Info " + xrt.Info.PDFString()
+	}
+	if len(xrt.ID) == 2 {
+		trailer += "/ID" + xrt.ID.PDFString()
+	}
+
+	xrefOffset := buf.Len()
+	if ctx.Read.UsingXRefStreams {
+		xrefNr := u.newObject()
+		offsets[xrefNr] = xrefOffset
+		nrs = append(nrs, xrefNr)
+
+		var data bytes.Buffer
+		for _, nr := range nrs {
+			data.WriteByte(1)
+			binary.Write(&data, binary.BigEndian, uint32(offsets[nr]))
+			binary.Write(&data, binary.BigEndian, uint16(u.gens[nr]))
+		}
+		fmt.Fprintf(&buf, "%d 0 obj\n<</Type/XRef/Size %d/W[1 4 2]/Index%s%s/Length %d>>\nstream\n",
+			xrefNr, u.nextObjNr, xrefIndex(nrs), trailer, data.Len())
+		buf.Write(data.Bytes())
+		buf.WriteString("\nendstream\nendobj\n")
+	} else {
+		buf.WriteString("xref\n")
+		for _, run := range xrefRuns(nrs) {
+			fmt.Fprintf(&buf, "%d %d\n", run[0], len(run))
+			for _, nr := range run {
+				fmt.Fprintf(&buf, "%010d %05d n\r\n", offsets[nr], u.gens[nr])
+			}
+		}
+		fmt.Fprintf(&buf, "trailer\n<</Size %d%s>>\n", u.nextObjNr, trailer)
+	}
+	fmt.Fprintf(&buf, "startxref\n%d\n%%%%EOF\n", xrefOffset)
+	return buf.Bytes(), nil
+}
+
+// xrefRuns chia danh sách số object (đã sắp xếp) thành các đoạn liên tiếp
+func xrefRuns(nrs []int) [][]int {
+	var runs [][]int
+	for i, nr := range nrs {
+		if i == 0 || nr != nrs[i-1]+1 {
+			runs = append(runs, nil)
+		}
+		runs[len(runs)-1] = append(runs[len(runs)-1], nr)
+	}
+	return runs
+}
+
+func xrefIndex(nrs []int) string {
+	parts := []string{}
+	for _, run := range xrefRuns(nrs) {
+		parts = append(parts, fmt.Sprintf("%d %d", run[0], len(run)))
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// pdfText mã hóa chuỗi (có dấu tiếng Việt) thành hex string UTF-16BE kèm BOM
+func pdfText(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, r := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// pdfDate định dạng ngày giờ theo PDF: (D:YYYYMMDDHHmmSS+HH'mm')
+func pdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("(D:%s%c%02d'%02d')", t.Format("20060102150405"), sign, offset/3600, (offset%3600)/60)
+}
@@ -0,0 +1,176 @@
+package pades
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/cms"
+	"github.com/vnkmasc/KmaERM/backend/internal/pkitest"
+	"github.com/vnkmasc/KmaERM/backend/internal/signer"
+)
+
+// pdfMau dựng một PDF một trang tối thiểu với bảng xref đúng vị trí từng đối tượng
+func pdfMau() []byte {
+	noiDung := "BT /F1 12 Tf 72 760 Td (Giay phep 15/2025/GP-BCY) Tj ET"
+	objs := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(noiDung), noiDung),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objs))
+	for i, o := range objs {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, xref)
+	return b.Bytes()
+}
+
+type nguoiKy struct {
+	sgn  signer.Signer
+	cert *x509.Certificate
+}
+
+func taoNguoiKy(t *testing.T, ca *pkitest.ChungThu, ten string) nguoiKy {
+	t.Helper()
+	leaf := pkitest.NguoiKy(t, ca, ten)
+	return nguoiKy{sgn: leaf.Signer(t), cert: leaf.Cert}
+}
+
+func kyPDF(t *testing.T, pdf []byte, nk nguoiKy, ca *pkitest.ChungThu) []byte {
+	t.Helper()
+	daKy, err := Sign(pdf, Options{Name: nk.cert.Subject.CommonName, Reason: "Ký số giấy phép", SigningTime: time.Now()},
+		func(digest []byte) ([]byte, error) {
+			return cms.SignDigest(digest, nk.sgn, nk.cert, []*x509.Certificate{ca.Cert}, cms.SignOptions{})
+		})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return daKy
+}
+
+func TestSignExtractVerify(t *testing.T) {
+	ca := pkitest.CA(t, "KmaERM Test CA")
+	nk := taoNguoiKy(t, ca, "Nguyễn Văn A")
+	goc := pdfMau()
+
+	daKy := kyPDF(t, goc, nk, ca)
+	if !bytes.HasPrefix(daKy, goc) {
+		t.Error("chữ ký phải được thêm bằng bản cập nhật tăng dần, giữ nguyên các byte của file gốc")
+	}
+
+	cmsDER, digest, err := Extract(daKy)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	res, err := cms.VerifyDigest(cmsDER, digest)
+	if err != nil {
+		t.Fatalf("cms.VerifyDigest: %v", err)
+	}
+	if !res.Certificate.Equal(nk.cert) {
+		t.Error("chứng thư người ký không khớp")
+	}
+
+	// Thêm nội dung sau chữ ký thì chữ ký không còn phủ toàn bộ file
+	if _, _, err := Extract(append(append([]byte(nil), daKy...), "\n% them sau khi ky\n"...)); !errors.Is(err, ErrByteRangeKhongDu) {
+		t.Errorf("nội dung thêm sau ký: err = %v, muốn ErrByteRangeKhongDu", err)
+	}
+
+	// Sửa một byte trong vùng được ký
+	hong := append([]byte(nil), daKy...)
+	i := bytes.Index(hong, []byte("Giay phep"))
+	hong[i] = 'X'
+	cmsDER, digest, err = Extract(hong)
+	if err != nil {
+		t.Fatalf("Extract file bị sửa: %v", err)
+	}
+	if _, err := cms.VerifyDigest(cmsDER, digest); !errors.Is(err, cms.ErrDigestKhongKhop) {
+		t.Errorf("file bị sửa: err = %v, muốn cms.ErrDigestKhongKhop", err)
+	}
+
+	if _, err := Sign([]byte("không phải pdf"), Options{}, nil); !errors.Is(err, ErrKhongPhaiPDF) {
+		t.Errorf("err = %v, muốn ErrKhongPhaiPDF", err)
+	}
+	if _, _, err := Extract(goc); !errors.Is(err, ErrKhongCoChuKyPDF) {
+		t.Errorf("PDF chưa ký: err = %v, muốn ErrKhongCoChuKyPDF", err)
+	}
+
+	kiemTraBangOpenSSL(t, daKy, ca.Cert)
+}
+
+// Ký nháy rồi ký chính: mỗi chữ ký phủ đúng bản sửa đổi tại thời điểm ký và vẫn kiểm tra được
+func TestNhieuChuKy(t *testing.T) {
+	ca := pkitest.CA(t, "KmaERM Test CA")
+	nhay := taoNguoiKy(t, ca, "Chuyên viên")
+	chinh := taoNguoiKy(t, ca, "Lãnh đạo")
+
+	motChuKy := kyPDF(t, pdfMau(), nhay, ca)
+	haiChuKy := kyPDF(t, motChuKy, chinh, ca)
+
+	sigs, err := ExtractAll(haiChuKy)
+	if err != nil {
+		t.Fatalf("ExtractAll: %v", err)
+	}
+	if len(sigs) != 2 {
+		t.Fatalf("có %d chữ ký, muốn 2", len(sigs))
+	}
+	if sigs[0].End != len(motChuKy) || sigs[1].End != len(haiChuKy) {
+		t.Errorf("End = %d, %d; muốn %d, %d", sigs[0].End, sigs[1].End, len(motChuKy), len(haiChuKy))
+	}
+	for i, nk := range []nguoiKy{nhay, chinh} {
+		res, err := cms.VerifyDigest(sigs[i].CMS, sigs[i].Digest)
+		if err != nil {
+			t.Fatalf("chữ ký %d: %v", i+1, err)
+		}
+		if !res.Certificate.Equal(nk.cert) {
+			t.Errorf("chữ ký %d: sai người ký", i+1)
+		}
+	}
+}
+
+// kiemTraBangOpenSSL tách /Contents và vùng /ByteRange ra file rồi kiểm tra bằng openssl cms -verify,
+// giống cách bên thứ ba tự kiểm tra chữ ký PAdES
+func kiemTraBangOpenSSL(t *testing.T, pdf []byte, ca *x509.Certificate) {
+	t.Helper()
+	m := byteRangeRe.FindSubmatch(pdf)
+	var r [4]int
+	for i := range r {
+		fmt.Sscan(string(m[i+1]), &r[i])
+	}
+	sigs, err := ExtractAll(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Bỏ phần đệm 0 sau cấu trúc DER trong /Contents
+	var raw asn1.RawValue
+	if _, err := asn1.Unmarshal(sigs[len(sigs)-1].CMS, &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	pkitest.OpenSSL(t, map[string][]byte{
+		"signature.p7s":  raw.FullBytes,
+		"byte-range.bin": append(append([]byte(nil), pdf[r[0]:r[0]+r[1]]...), pdf[r[2]:r[2]+r[3]]...),
+		"ca.pem":         pkitest.PEM(ca),
+	}, "cms", "-verify", "-binary", "-inform", "DER",
+		"-in", "signature.p7s",
+		"-content", "byte-range.bin",
+		"-CAfile", "ca.pem",
+		"-purpose", "any",
+		"-out", os.DevNull)
+}
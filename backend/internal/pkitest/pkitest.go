@@ -0,0 +1,147 @@
+// Package pkitest dựng CA, chứng thư và người ký tạm thời cho test của các package ca, cms, pades và tsa.
+// Chỉ được import từ file _test.go.
+package pkitest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/signer"
+)
+
+// ChungThu là chứng thư kèm khóa bí mật của nó
+type ChungThu struct {
+	Key  *rsa.PrivateKey
+	Cert *x509.Certificate
+}
+
+// Tao cấp chứng thư theo tmpl; issuer = nil thì chứng thư tự ký
+func Tao(t testing.TB, tmpl *x509.Certificate, issuer *ChungThu) *ChungThu {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.SerialNumber == nil {
+		tmpl.SerialNumber = soSeri(t)
+	}
+	parent, parentKey := tmpl, key
+	if issuer != nil {
+		parent, parentKey = issuer.Cert, issuer.Key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ChungThu{Key: key, Cert: cert}
+}
+
+// CA dựng CA tự ký còn hạn, giống CA nội bộ (package ca)
+func CA(t testing.TB, ten string) *ChungThu {
+	t.Helper()
+	now := time.Now()
+	return Tao(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: ten},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil)
+}
+
+// NguoiKy cấp chứng thư ký số cho cán bộ, có hiệu lực từ một giờ trước tới một ngày sau
+func NguoiKy(t testing.TB, ca *ChungThu, ten string) *ChungThu {
+	t.Helper()
+	now := time.Now()
+	return Tao(t, &x509.Certificate{
+		Subject:        pkix.Name{CommonName: ten},
+		EmailAddresses: []string{"canbo@example.vn"},
+		NotBefore:      now.Add(-time.Hour),
+		NotAfter:       now.Add(24 * time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+	}, ca)
+}
+
+// TSA cấp chứng thư giống ca.EnsureTimestamping: EKU timeStamping duy nhất, critical (RFC 3161 mục 2.3)
+func TSA(t testing.TB, ca *ChungThu) *ChungThu {
+	t.Helper()
+	ekuDER, err := asn1.Marshal([]asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 8}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	return Tao(t, &x509.Certificate{
+		Subject:         pkix.Name{CommonName: "KmaERM Test TSA"},
+		NotBefore:       now.Add(-time.Hour),
+		NotAfter:        now.Add(24 * time.Hour),
+		KeyUsage:        x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+		ExtraExtensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{2, 5, 29, 37}, Critical: true, Value: ekuDER}},
+	}, ca)
+}
+
+// Signer bọc khóa của chứng thư thành signer.Signer, đi qua đúng đường đọc khóa PEM của hệ thống
+func (c *ChungThu) Signer(t testing.TB) signer.Signer {
+	t.Helper()
+	sgn, err := signer.NewKeySigner(string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(c.Key)})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sgn
+}
+
+// PEM mã hóa chứng thư để truyền cho công cụ bên ngoài
+func PEM(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+// OpenSSL ghi files vào thư mục tạm rồi chạy openssl; đối số trùng tên một file được thay bằng đường dẫn của file đó.
+// Không có openssl trên máy thì bỏ qua phần kiểm tra này (không đánh trượt test).
+func OpenSSL(t testing.TB, files map[string][]byte, args ...string) {
+	t.Helper()
+	openssl, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Log("không có openssl, bỏ qua kiểm tra bằng openssl")
+		return
+	}
+
+	dir := t.TempDir()
+	for ten, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, ten), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, a := range args {
+		if _, ok := files[a]; ok {
+			args[i] = filepath.Join(dir, a)
+		}
+	}
+
+	out, err := exec.Command(openssl, args...).CombinedOutput()
+	if err != nil {
+		t.Errorf("openssl %s: %v\n%s", args[0], err, out)
+	}
+}
+
+func soSeri(t testing.TB) *big.Int {
+	t.Helper()
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serial
+}
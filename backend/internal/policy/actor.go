@@ -0,0 +1,70 @@
+package policy
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+)
+
+// ErrKhongCoActor: request không đi qua AuthMiddleware nên không xác định được phạm vi dữ liệu
+var ErrKhongCoActor = errors.New("không xác định được người dùng của request")
+
+// Actor là người dùng đang thực hiện request (được AuthMiddleware gắn vào context).
+type Actor struct {
+	UserID   uuid.UUID
+	RoleName string
+	// Chỉ có giá trị với tài khoản doanh nghiệp
+	DoanhNghiepID uuid.UUID
+}
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFrom(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
+
+// IsDoanhNghiep: tài khoản doanh nghiệp chỉ được thấy dữ liệu của chính mình,
+// CAN_BO/ADMIN được truy cập toàn bộ.
+func (a Actor) IsDoanhNghiep() bool {
+	return a.RoleName == models.RoleDoanhNghiep
+}
+
+// IsTenantScoped cho biết request hiện tại có bị giới hạn theo doanh nghiệp hay không
+func IsTenantScoped(ctx context.Context) bool {
+	actor, ok := ActorFrom(ctx)
+	return ok && actor.IsDoanhNghiep()
+}
+
+// ScopeDoanhNghiepID ép bộ lọc doanh nghiệp về doanh nghiệp của user (nếu là tài khoản doanh nghiệp),
+// bỏ qua giá trị client gửi lên. Các role khác giữ nguyên giá trị yêu cầu.
+// Không có Actor thì từ chối (ErrKhongCoActor) thay vì trả về bộ lọc yêu cầu.
+func ScopeDoanhNghiepID(ctx context.Context, requested uuid.UUID) (uuid.UUID, error) {
+	actor, ok := ActorFrom(ctx)
+	if !ok {
+		return uuid.Nil, ErrKhongCoActor
+	}
+	if !actor.IsDoanhNghiep() {
+		return requested, nil
+	}
+	return actor.DoanhNghiepID, nil
+}
+
+// CanAccessDoanhNghiep kiểm tra user có được đọc dữ liệu thuộc doanh nghiệp này hay không; không có Actor thì không được.
+// Khi trả về false, tầng trên nên báo "không tìm thấy" (404) để không lộ sự tồn tại của dữ liệu.
+func CanAccessDoanhNghiep(ctx context.Context, doanhNghiepID uuid.UUID) bool {
+	actor, ok := ActorFrom(ctx)
+	if !ok {
+		return false
+	}
+	if !actor.IsDoanhNghiep() {
+		return true
+	}
+	return actor.DoanhNghiepID == doanhNghiepID
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type BoDemSoHieuRepository interface {
+	// TangBoDem tăng bộ đếm (loai, nam) thêm 1 và trả về giá trị mới. Dòng bộ đếm bị khóa tới khi
+	// transaction của db kết thúc, rollback thì số được hoàn lại.
+	TangBoDem(ctx context.Context, db *gorm.DB, loai string, nam int) (int64, error)
+}
+
+type boDemSoHieuRepo struct{}
+
+func NewBoDemSoHieuRepository() BoDemSoHieuRepository {
+	return &boDemSoHieuRepo{}
+}
+
+func (r *boDemSoHieuRepo) TangBoDem(ctx context.Context, db *gorm.DB, loai string, nam int) (int64, error) {
+	var giaTri int64
+	err := db.WithContext(ctx).Raw(`
+		INSERT INTO bo_dem_so_hieu (loai, nam, gia_tri, updated_at)
+		VALUES (?, ?, 1, NOW())
+		ON CONFLICT (loai, nam) DO UPDATE
+		SET gia_tri = bo_dem_so_hieu.gia_tri + 1, updated_at = NOW()
+		RETURNING gia_tri`, loai, nam).
+		Scan(&giaTri).Error
+	return giaTri, err
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/vnkmasc/KmaERM/backend/internal/dto"
 	"github.com/vnkmasc/KmaERM/backend/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GiayPhepRepository interface {
@@ -14,8 +15,11 @@ type GiayPhepRepository interface {
 	UpdateGiayPhep(ctx context.Context, db *gorm.DB, giayPhep *models.GiayPhep) error
 	DeleteGiayPhep(ctx context.Context, db *gorm.DB, giayPhepID uuid.UUID) error
 	GetGiayPhepByID(ctx context.Context, db *gorm.DB, giayPhepID uuid.UUID) (*models.GiayPhep, error)
+	GetGiayPhepForUpdate(ctx context.Context, db *gorm.DB, giayPhepID uuid.UUID) (*models.GiayPhep, error)
 	GetGiayPhepByHoSoID(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) (*models.GiayPhep, error)
 	CheckHoSoExists(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) (bool, error)
+	ExistsSoGiayPhep(ctx context.Context, db *gorm.DB, soGiayPhep string) (bool, error)
+	CreateChuKy(ctx context.Context, db *gorm.DB, chuKy *models.ChuKy) error
 	ListGiayPhep(
 		ctx context.Context,
 		db *gorm.DB,
@@ -37,7 +41,8 @@ func (r *giayPhepRepo) CreateGiayPhep(ctx context.Context, db *gorm.DB, giayPhep
 }
 
 func (r *giayPhepRepo) UpdateGiayPhep(ctx context.Context, db *gorm.DB, giayPhep *models.GiayPhep) error {
-	return db.WithContext(ctx).Save(giayPhep).Error
+	// Chữ ký chỉ được thêm qua CreateChuKy, không ghi lại khi lưu giấy phép
+	return db.WithContext(ctx).Omit("ChuKys").Save(giayPhep).Error
 }
 
 func (r *giayPhepRepo) DeleteGiayPhep(ctx context.Context, db *gorm.DB, giayPhepID uuid.UUID) error {
@@ -48,10 +53,22 @@ func (r *giayPhepRepo) GetGiayPhepByID(ctx context.Context, db *gorm.DB, giayPhe
 	var giayPhep models.GiayPhep
 	err := db.WithContext(ctx).
 		Preload("HoSo.DoanhNghiep"). // Preload lồng: GiayPhep -> HoSo -> DoanhNghiep
+		Preload("ChuKys", orderChuKy).
+		Preload("ChuKys.NguoiKy").
 		First(&giayPhep, "id = ?", giayPhepID).Error
 	return &giayPhep, err
 }
 
+// GetGiayPhepForUpdate khóa dòng giấy phép (SELECT ... FOR UPDATE) rồi đọc kèm các chữ ký, phải gọi trong transaction
+func (r *giayPhepRepo) GetGiayPhepForUpdate(ctx context.Context, db *gorm.DB, giayPhepID uuid.UUID) (*models.GiayPhep, error) {
+	var giayPhep models.GiayPhep
+	// Khóa riêng dòng giấy phép trước, để FOR UPDATE không lan sang các câu preload
+	if err := db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&giayPhep, "id = ?", giayPhepID).Error; err != nil {
+		return &giayPhep, err
+	}
+	return r.GetGiayPhepByID(ctx, db, giayPhepID)
+}
+
 func (r *giayPhepRepo) GetGiayPhepByHoSoID(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) (*models.GiayPhep, error) {
 	var giayPhep models.GiayPhep
 	err := db.WithContext(ctx).Where("ho_so_id = ?", hoSoID).First(&giayPhep).Error
@@ -121,6 +138,7 @@ func (r *giayPhepRepo) ListGiayPhep(
 
 	// 6. Preload (Không đổi)
 	query = query.Preload("HoSo.DoanhNghiep")
+	query = query.Preload("ChuKys", orderChuKy).Preload("ChuKys.NguoiKy")
 	query = query.Order("giay_phep.created_at DESC")
 
 	// 7. Thực thi
@@ -142,3 +160,18 @@ func (r *giayPhepRepo) CheckHoSoExists(ctx context.Context, db *gorm.DB, hoSoID
 	}
 	return count > 0, nil
 }
+
+func (r *giayPhepRepo) ExistsSoGiayPhep(ctx context.Context, db *gorm.DB, soGiayPhep string) (bool, error) {
+	var count int64
+	err := db.WithContext(ctx).Model(&models.GiayPhep{}).Where("so_giay_phep = ?", soGiayPhep).Count(&count).Error
+	return count > 0, err
+}
+
+// orderChuKy sắp các chữ ký của giấy phép theo thứ tự ký
+func orderChuKy(db *gorm.DB) *gorm.DB {
+	return db.Order("thu_tu")
+}
+
+func (r *giayPhepRepo) CreateChuKy(ctx context.Context, db *gorm.DB, chuKy *models.ChuKy) error {
+	return db.WithContext(ctx).Omit("NguoiKy").Create(chuKy).Error
+}
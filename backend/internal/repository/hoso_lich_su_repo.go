@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type HoSoLichSuRepository interface {
+	Create(ctx context.Context, db *gorm.DB, suKien *models.HoSoLichSu) error
+	ListByHoSoID(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) ([]models.HoSoLichSu, error)
+}
+
+type hoSoLichSuRepo struct{}
+
+func NewHoSoLichSuRepository() HoSoLichSuRepository {
+	return &hoSoLichSuRepo{}
+}
+
+func (r *hoSoLichSuRepo) Create(ctx context.Context, db *gorm.DB, suKien *models.HoSoLichSu) error {
+	return db.WithContext(ctx).Omit("NguoiThucHien").Create(suKien).Error
+}
+
+// ListByHoSoID trả về nhật ký theo thứ tự thời gian (cũ -> mới)
+func (r *hoSoLichSuRepo) ListByHoSoID(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) ([]models.HoSoLichSu, error) {
+	var list []models.HoSoLichSu
+	err := db.WithContext(ctx).
+		Preload("NguoiThucHien").
+		Where("ho_so_id = ?", hoSoID).
+		Order("created_at ASC, id ASC").
+		Find(&list).Error
+	return list, err
+}
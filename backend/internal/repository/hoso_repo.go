@@ -7,14 +7,18 @@ import (
 	"github.com/vnkmasc/KmaERM/backend/internal/dto"
 	"github.com/vnkmasc/KmaERM/backend/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type HoSoRepository interface {
 	CreateHoSo(ctx context.Context, db *gorm.DB, hoSo *models.HoSo) error
 	GetHoSoDetails(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) (*models.HoSo, error)
 	GetHoSoByID(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) (*models.HoSo, error)
+	GetHoSoForUpdate(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) (*models.HoSo, error)
+	ExistsMaHoSo(ctx context.Context, db *gorm.DB, maHoSo string) (bool, error)
 	UpdateHoSo(ctx context.Context, db *gorm.DB, hoSo *models.HoSo) error
 	ListHoSo(ctx context.Context, db *gorm.DB, doanhNghiepID uuid.UUID, params *dto.HoSoSearchParams, page int, pageSize int) ([]models.HoSo, int64, error)
+	ListHoSoCuaCanBo(ctx context.Context, db *gorm.DB, canBoID uuid.UUID, trangThai []string, page int, pageSize int) ([]models.HoSo, int64, error)
 	DeleteHoSo(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) error
 }
 
@@ -36,6 +40,7 @@ func (r *hoSoRepo) GetHoSoDetails(ctx context.Context, db *gorm.DB, hoSoID uuid.
 
 	err := db.WithContext(ctx).
 		Preload("DoanhNghiep").
+		Preload("CanBoXuLy").
 		Preload("HoSoTaiLieus.LoaiTaiLieu").
 		Preload("HoSoTaiLieus.TaiLieus").
 		First(&hoSo, "id = ?", hoSoID).Error
@@ -108,7 +113,7 @@ func (r *hoSoRepo) ListHoSo(
 
 	// 6. Preload thông tin DoanhNghiep (giống GetHoSoDetails)
 	// Chúng ta KHÔNG preload HoSoTaiLieus... vì đây là list view, sẽ rất chậm
-	query = query.Preload("DoanhNghiep")
+	query = query.Preload("DoanhNghiep").Preload("CanBoXuLy")
 
 	// 7. Sắp xếp (ORDER BY) - Bạn có thể thêm param cho việc này
 	// Tạm thời sắp xếp theo ngày tạo mới nhất
@@ -123,12 +128,56 @@ func (r *hoSoRepo) ListHoSo(
 	return hoSos, total, nil
 }
 
+// ListHoSoCuaCanBo là hàng đợi của một cán bộ: hạn trả sớm nhất trước, hồ sơ chưa có hạn trả xếp cuối
+func (r *hoSoRepo) ListHoSoCuaCanBo(
+	ctx context.Context,
+	db *gorm.DB,
+	canBoID uuid.UUID,
+	trangThai []string,
+	page int,
+	pageSize int,
+) ([]models.HoSo, int64, error) {
+	var hoSos []models.HoSo
+	var total int64
+
+	query := db.WithContext(ctx).Model(&models.HoSo{}).
+		Where("can_bo_xu_ly_id = ? AND trang_thai_ho_so IN ?", canBoID, trangThai)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.
+		Preload("DoanhNghiep").
+		Preload("CanBoXuLy").
+		Order("CASE WHEN ngay_hen_tra > '1900-01-01' THEN 0 ELSE 1 END, ngay_hen_tra ASC, created_at ASC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&hoSos).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return hoSos, total, nil
+}
+
 func (r *hoSoRepo) GetHoSoByID(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) (*models.HoSo, error) {
 	var hoSo models.HoSo
 	err := db.WithContext(ctx).First(&hoSo, "id = ?", hoSoID).Error
 	return &hoSo, err
 }
 
+// GetHoSoForUpdate khóa dòng hồ sơ (SELECT ... FOR UPDATE), phải gọi trong transaction
+func (r *hoSoRepo) GetHoSoForUpdate(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) (*models.HoSo, error) {
+	var hoSo models.HoSo
+	err := db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&hoSo, "id = ?", hoSoID).Error
+	return &hoSo, err
+}
+
+func (r *hoSoRepo) ExistsMaHoSo(ctx context.Context, db *gorm.DB, maHoSo string) (bool, error) {
+	var count int64
+	err := db.WithContext(ctx).Model(&models.HoSo{}).Where("ma_ho_so = ?", maHoSo).Count(&count).Error
+	return count > 0, err
+}
+
 func (r *hoSoRepo) UpdateHoSo(ctx context.Context, db *gorm.DB, hoSo *models.HoSo) error {
 	return db.WithContext(ctx).Save(hoSo).Error
 }
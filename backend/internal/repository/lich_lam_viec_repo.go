@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LichLamViecRepository interface {
+	ListNgayNghi(ctx context.Context, db *gorm.DB, tu time.Time, den time.Time) ([]models.NgayNghiLe, error)
+	UpsertNgayNghi(ctx context.Context, db *gorm.DB, ngayNghi *models.NgayNghiLe) error
+	DeleteNgayNghi(ctx context.Context, db *gorm.DB, ngay time.Time) (int64, error)
+}
+
+type lichLamViecRepo struct{}
+
+func NewLichLamViecRepository() LichLamViecRepository {
+	return &lichLamViecRepo{}
+}
+
+// ListNgayNghi trả về các ngày nghỉ lễ, ngày làm bù trong khoảng [tu, den] theo thứ tự ngày
+func (r *lichLamViecRepo) ListNgayNghi(ctx context.Context, db *gorm.DB, tu time.Time, den time.Time) ([]models.NgayNghiLe, error) {
+	var list []models.NgayNghiLe
+	err := db.WithContext(ctx).
+		Where("ngay BETWEEN ? AND ?", tu.Format("2006-01-02"), den.Format("2006-01-02")).
+		Order("ngay ASC").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *lichLamViecRepo) UpsertNgayNghi(ctx context.Context, db *gorm.DB, ngayNghi *models.NgayNghiLe) error {
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "ngay"}},
+		DoUpdates: clause.AssignmentColumns([]string{"ten", "la_ngay_lam_bu", "updated_at"}),
+	}).Create(ngayNghi).Error
+}
+
+func (r *lichLamViecRepo) DeleteNgayNghi(ctx context.Context, db *gorm.DB, ngay time.Time) (int64, error) {
+	result := db.WithContext(ctx).Where("ngay = ?", ngay.Format("2006-01-02")).Delete(&models.NgayNghiLe{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PhanCongRepository interface {
+	// KhoaPhanCong tuần tự hóa các lần phân công tự động đến hết transaction hiện tại
+	KhoaPhanCong(ctx context.Context, db *gorm.DB) error
+	// ChonCanBoItViecNhat: cán bộ đang giữ ít hồ sơ chưa giải quyết xong nhất, hòa thì người lâu chưa được giao nhất
+	ChonCanBoItViecNhat(ctx context.Context, db *gorm.DB, trangThaiDangXuLy []string) (uuid.UUID, error)
+	// ChonCanBoXoayVong: cán bộ lâu chưa được giao hồ sơ nhất (chưa từng được giao thì trước hết)
+	ChonCanBoXoayVong(ctx context.Context, db *gorm.DB) (uuid.UUID, error)
+	GetCanBo(ctx context.Context, db *gorm.DB, userID uuid.UUID) (*models.User, error)
+}
+
+type phanCongRepo struct{}
+
+func NewPhanCongRepository() PhanCongRepository {
+	return &phanCongRepo{}
+}
+
+func (r *phanCongRepo) KhoaPhanCong(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(hashtext('phan_cong_ho_so'))").Error
+}
+
+// canBoDangHoatDong: tài khoản cán bộ (không gồm quản trị) đang hoạt động
+func canBoDangHoatDong(ctx context.Context, db *gorm.DB) *gorm.DB {
+	return db.WithContext(ctx).
+		Table("users AS u").
+		Joins("JOIN roles r ON r.id = u.role_id").
+		Where("r.name = 'CAN_BO' AND u.is_active")
+}
+
+func (r *phanCongRepo) ChonCanBoItViecNhat(ctx context.Context, db *gorm.DB, trangThaiDangXuLy []string) (uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := canBoDangHoatDong(ctx, db).
+		Select("u.id").
+		// Order(gorm.Expr(...)) bị gorm bỏ qua, và một Order(string) phía sau sẽ ghi đè clause.OrderBy có Expression,
+		// nên toàn bộ thứ tự phải nằm trong một biểu thức
+		Order(clause.OrderBy{Expression: gorm.Expr(
+			"(SELECT COUNT(*) FROM ho_so hs WHERE hs.can_bo_xu_ly_id = u.id AND hs.trang_thai_ho_so IN ?) ASC, "+
+				"(SELECT MAX(hs.ngay_phan_cong) FROM ho_so hs WHERE hs.can_bo_xu_ly_id = u.id) ASC NULLS FIRST, u.id ASC",
+			trangThaiDangXuLy)}).
+		Limit(1).
+		Pluck("u.id", &ids).Error
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(ids) == 0 {
+		return uuid.Nil, gorm.ErrRecordNotFound
+	}
+	return ids[0], nil
+}
+
+func (r *phanCongRepo) ChonCanBoXoayVong(ctx context.Context, db *gorm.DB) (uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := canBoDangHoatDong(ctx, db).
+		Select("u.id").
+		Order("(SELECT MAX(hs.ngay_phan_cong) FROM ho_so hs WHERE hs.can_bo_xu_ly_id = u.id) ASC NULLS FIRST, u.id ASC").
+		Limit(1).
+		Pluck("u.id", &ids).Error
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(ids) == 0 {
+		return uuid.Nil, gorm.ErrRecordNotFound
+	}
+	return ids[0], nil
+}
+
+func (r *phanCongRepo) GetCanBo(ctx context.Context, db *gorm.DB, userID uuid.UUID) (*models.User, error) {
+	var user models.User
+	err := db.WithContext(ctx).Preload("Role").First(&user, "id = ?", userID).Error
+	return &user, err
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Không cần Postgres thật: DryRun chỉ dựng câu SQL, callback ghi lại câu đó để kiểm tra
+func TestChonCanBoItViecNhatSapXepTheoSoHoSo(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sql string
+	var vars []any
+	if err := db.Callback().Query().After("gorm:query").Register("test:ghi_sql", func(tx *gorm.DB) {
+		sql, vars = tx.Statement.SQL.String(), tx.Statement.Vars
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	trangThai := []string{"MoiTao", "DangXuLy"}
+	_, _ = NewPhanCongRepository().ChonCanBoItViecNhat(context.Background(), db, trangThai)
+
+	orderBy := sql[strings.Index(sql, "ORDER BY"):]
+	iDem, iMax := strings.Index(orderBy, "COUNT(*)"), strings.Index(orderBy, "MAX(hs.ngay_phan_cong)")
+	if iDem < 0 || iMax < 0 || iDem > iMax {
+		t.Fatalf("ORDER BY phải xếp theo số hồ sơ đang giữ trước, rồi mới đến lần phân công gần nhất:\n%s", sql)
+	}
+	if !strings.Contains(sql, "hs.trang_thai_ho_so IN ($1,$2)") || len(vars) < 2 || vars[0] != "MoiTao" || vars[1] != "DangXuLy" {
+		t.Errorf("trạng thái đang xử lý không được truyền vào truy vấn: %s %v", sql, vars)
+	}
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type PheDuyetRepository interface {
+	ListBuocDuyet(ctx context.Context, db *gorm.DB, loaiGiayPhep string) ([]models.BuocDuyet, error)
+	ReplaceQuyTrinh(ctx context.Context, db *gorm.DB, loaiGiayPhep string, cacBuoc []models.BuocDuyet) error
+	ListPheDuyet(ctx context.Context, db *gorm.DB, giayPhepID uuid.UUID) ([]models.PheDuyetGiayPhep, error)
+	CreatePheDuyet(ctx context.Context, db *gorm.DB, pheDuyet *models.PheDuyetGiayPhep) error
+}
+
+type pheDuyetRepo struct{}
+
+func NewPheDuyetRepository() PheDuyetRepository {
+	return &pheDuyetRepo{}
+}
+
+// ListBuocDuyet trả về quy trình của một loại giấy phép theo thứ tự; loaiGiayPhep rỗng thì trả về mọi quy trình
+func (r *pheDuyetRepo) ListBuocDuyet(ctx context.Context, db *gorm.DB, loaiGiayPhep string) ([]models.BuocDuyet, error) {
+	var list []models.BuocDuyet
+	query := db.WithContext(ctx)
+	if loaiGiayPhep != "" {
+		query = query.Where("loai_giay_phep = ?", loaiGiayPhep)
+	}
+	err := query.Order("loai_giay_phep ASC, thu_tu ASC").Find(&list).Error
+	return list, err
+}
+
+// ReplaceQuyTrinh xóa quy trình cũ và ghi các bước mới (gọi trong transaction)
+func (r *pheDuyetRepo) ReplaceQuyTrinh(ctx context.Context, db *gorm.DB, loaiGiayPhep string, cacBuoc []models.BuocDuyet) error {
+	if err := db.WithContext(ctx).Where("loai_giay_phep = ?", loaiGiayPhep).Delete(&models.BuocDuyet{}).Error; err != nil {
+		return err
+	}
+	if len(cacBuoc) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Create(&cacBuoc).Error
+}
+
+func (r *pheDuyetRepo) ListPheDuyet(ctx context.Context, db *gorm.DB, giayPhepID uuid.UUID) ([]models.PheDuyetGiayPhep, error) {
+	var list []models.PheDuyetGiayPhep
+	err := db.WithContext(ctx).
+		Preload("NguoiDuyet").
+		Where("giay_phep_id = ?", giayPhepID).
+		Order("created_at ASC").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *pheDuyetRepo) CreatePheDuyet(ctx context.Context, db *gorm.DB, pheDuyet *models.PheDuyetGiayPhep) error {
+	return db.WithContext(ctx).Create(pheDuyet).Error
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SessionRepository interface {
+	Create(tx *gorm.DB, session *models.UserSession) error
+	GetByTokenHashForUpdate(tx *gorm.DB, tokenHash string) (*models.UserSession, error)
+	MarkRotated(tx *gorm.DB, id uuid.UUID, replacedBy uuid.UUID) error
+	Revoke(tx *gorm.DB, id uuid.UUID) error
+	RevokeAllByUser(tx *gorm.DB, userID uuid.UUID) error
+}
+
+type sessionRepo struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepo{db: db}
+}
+
+func (r *sessionRepo) conn(tx *gorm.DB) *gorm.DB {
+	if tx == nil {
+		return r.db
+	}
+	return tx
+}
+
+func (r *sessionRepo) Create(tx *gorm.DB, session *models.UserSession) error {
+	return r.conn(tx).Create(session).Error
+}
+
+// GetByTokenHashForUpdate khóa dòng (SELECT ... FOR UPDATE) để 2 request refresh
+// đồng thời không thể cùng xoay vòng một token.
+func (r *sessionRepo) GetByTokenHashForUpdate(tx *gorm.DB, tokenHash string) (*models.UserSession, error) {
+	var session models.UserSession
+	err := r.conn(tx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("refresh_token_hash = ?", tokenHash).
+		First(&session).Error
+	return &session, err
+}
+
+func (r *sessionRepo) MarkRotated(tx *gorm.DB, id uuid.UUID, replacedBy uuid.UUID) error {
+	return r.conn(tx).Model(&models.UserSession{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"revoked_at":  time.Now(),
+			"replaced_by": replacedBy,
+		}).Error
+}
+
+func (r *sessionRepo) Revoke(tx *gorm.DB, id uuid.UUID) error {
+	return r.conn(tx).Model(&models.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionRepo) RevokeAllByUser(tx *gorm.DB, userID uuid.UUID) error {
+	return r.conn(tx).Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
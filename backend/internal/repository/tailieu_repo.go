@@ -10,6 +10,7 @@ import (
 
 type TaiLieuRepository interface {
 	GetLoaiTaiLieuByTen(ctx context.Context, db *gorm.DB, ten string) (*models.LoaiTaiLieu, error)
+	CreateLoaiTaiLieu(ctx context.Context, db *gorm.DB, loaiTaiLieu *models.LoaiTaiLieu) error
 	CreateHoSoTaiLieu(ctx context.Context, db *gorm.DB, hstl *models.HoSoTaiLieu) error
 	CreateTaiLieu(ctx context.Context, db *gorm.DB, taiLieu *models.TaiLieu) error
 	ListLoaiTaiLieu(ctx context.Context, db *gorm.DB, tenTaiLieu []string) ([]models.LoaiTaiLieu, error)
@@ -17,6 +18,8 @@ type TaiLieuRepository interface {
 	DeleteTaiLieu(ctx context.Context, db *gorm.DB, taiLieuID uuid.UUID) error
 
 	ListFilePathsByHoSoID(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) ([]string, error)
+	// ListKheBatBuocConTrong trả về các khe tài liệu bắt buộc của hồ sơ chưa có tài liệu nào
+	ListKheBatBuocConTrong(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) ([]models.HoSoTaiLieu, error)
 }
 
 type taiLieuRepo struct{}
@@ -44,6 +47,10 @@ func (r *taiLieuRepo) GetLoaiTaiLieuByTen(ctx context.Context, db *gorm.DB, ten
 	return &loaiTaiLieu, err
 }
 
+func (r *taiLieuRepo) CreateLoaiTaiLieu(ctx context.Context, db *gorm.DB, loaiTaiLieu *models.LoaiTaiLieu) error {
+	return db.WithContext(ctx).Create(loaiTaiLieu).Error
+}
+
 func (r *taiLieuRepo) CreateHoSoTaiLieu(ctx context.Context, db *gorm.DB, hstl *models.HoSoTaiLieu) error {
 	return db.WithContext(ctx).Create(hstl).Error
 }
@@ -54,7 +61,10 @@ func (r *taiLieuRepo) CreateTaiLieu(ctx context.Context, db *gorm.DB, taiLieu *m
 
 func (r *taiLieuRepo) GetTaiLieuByID(ctx context.Context, db *gorm.DB, taiLieuID uuid.UUID) (*models.TaiLieu, error) {
 	var taiLieu models.TaiLieu
-	err := db.WithContext(ctx).First(&taiLieu, "id = ?", taiLieuID).Error
+	// Preload hồ sơ gốc để tầng service kiểm tra doanh nghiệp sở hữu
+	err := db.WithContext(ctx).
+		Preload("HoSoTaiLieu.HoSo").
+		First(&taiLieu, "id = ?", taiLieuID).Error
 	return &taiLieu, err
 }
 
@@ -74,3 +84,14 @@ func (r *taiLieuRepo) ListFilePathsByHoSoID(ctx context.Context, db *gorm.DB, ho
 	}
 	return paths, nil
 }
+
+func (r *taiLieuRepo) ListKheBatBuocConTrong(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) ([]models.HoSoTaiLieu, error) {
+	var list []models.HoSoTaiLieu
+	err := db.WithContext(ctx).
+		Joins("LoaiTaiLieu").
+		Where("ho_so_tai_lieu.ho_so_id = ? AND ho_so_tai_lieu.bat_buoc = TRUE", hoSoID).
+		Where("NOT EXISTS (SELECT 1 FROM tai_lieu tl WHERE tl.ho_so_tai_lieu_id = ho_so_tai_lieu.id)").
+		Order(`"LoaiTaiLieu".ten ASC`).
+		Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"gorm.io/gorm"
+)
+
+type ThongKeRepository interface {
+	// DemSLA đếm hồ sơ đang giải quyết theo phân loại SLA tại thời điểm now
+	DemSLA(ctx context.Context, db *gorm.DB, now time.Time, mocSapDenHan time.Time) (*dto.SoLuongSLA, error)
+	DemSLATheoCanBo(ctx context.Context, db *gorm.DB, now time.Time, mocSapDenHan time.Time) ([]dto.SLATheoCanBo, error)
+	DemSLATheoThuTuc(ctx context.Context, db *gorm.DB, now time.Time, mocSapDenHan time.Time) ([]dto.SLATheoThuTuc, error)
+	ListQuaHan(ctx context.Context, db *gorm.DB, now time.Time, loaiThuTuc string, canBoID uuid.UUID, page int, pageSize int) ([]dto.HoSoQuaHanResponse, int64, error)
+}
+
+type thongKeRepo struct{}
+
+func NewThongKeRepository() ThongKeRepository {
+	return &thongKeRepo{}
+}
+
+const joinCanBoXuLy = "LEFT JOIN users cb ON cb.id = hs.can_bo_xu_ly_id"
+
+// Phân loại SLA từng hồ sơ; tham số: now, mocSapDenHan
+const phanLoaiSLA = `CASE
+		WHEN hs.trang_thai_ho_so = 'BiTraLai' THEN 'TamDung'
+		WHEN hs.ngay_hen_tra < ? THEN 'QuaHan'
+		WHEN hs.ngay_hen_tra <= ? THEN 'SapDenHan'
+		ELSE 'DungHan'
+	END`
+
+const demTheoSLA = `COUNT(*) FILTER (WHERE t.sla = 'DungHan') AS dung_han,
+	COUNT(*) FILTER (WHERE t.sla = 'SapDenHan') AS sap_den_han,
+	COUNT(*) FILTER (WHERE t.sla = 'QuaHan') AS qua_han,
+	COUNT(*) FILTER (WHERE t.sla = 'TamDung') AS tam_dung,
+	COUNT(*) AS tong`
+
+// hoSoDangGiaiQuyet: hồ sơ đã tiếp nhận và chưa có kết quả. Hồ sơ cũ chưa có hạn trả (giá trị zero) được bỏ qua.
+func hoSoDangGiaiQuyet(ctx context.Context, db *gorm.DB) *gorm.DB {
+	return db.WithContext(ctx).
+		Table("ho_so AS hs").
+		Joins(joinCanBoXuLy).
+		Where("hs.trang_thai_ho_so IN ?", []string{"DaTiepNhan", "DangXuLy", "BiTraLai"}).
+		Where("hs.trang_thai_ho_so = 'BiTraLai' OR hs.ngay_hen_tra > '1900-01-01'")
+}
+
+func (r *thongKeRepo) phanLoai(ctx context.Context, db *gorm.DB, now time.Time, mocSapDenHan time.Time) *gorm.DB {
+	sub := hoSoDangGiaiQuyet(ctx, db).
+		Select("hs.loai_thu_tuc, cb.id AS can_bo_id, cb.full_name AS ho_ten, "+phanLoaiSLA+" AS sla", now, mocSapDenHan)
+	return db.WithContext(ctx).Table("(?) AS t", sub)
+}
+
+func (r *thongKeRepo) DemSLA(ctx context.Context, db *gorm.DB, now time.Time, mocSapDenHan time.Time) (*dto.SoLuongSLA, error) {
+	var result dto.SoLuongSLA
+	err := r.phanLoai(ctx, db, now, mocSapDenHan).Select(demTheoSLA).Scan(&result).Error
+	return &result, err
+}
+
+func (r *thongKeRepo) DemSLATheoCanBo(ctx context.Context, db *gorm.DB, now time.Time, mocSapDenHan time.Time) ([]dto.SLATheoCanBo, error) {
+	var list []dto.SLATheoCanBo
+	err := r.phanLoai(ctx, db, now, mocSapDenHan).
+		Select("t.can_bo_id, t.ho_ten, " + demTheoSLA).
+		Group("t.can_bo_id, t.ho_ten").
+		Order("qua_han DESC, sap_den_han DESC, t.ho_ten ASC").
+		Scan(&list).Error
+	return list, err
+}
+
+func (r *thongKeRepo) DemSLATheoThuTuc(ctx context.Context, db *gorm.DB, now time.Time, mocSapDenHan time.Time) ([]dto.SLATheoThuTuc, error) {
+	var list []dto.SLATheoThuTuc
+	err := r.phanLoai(ctx, db, now, mocSapDenHan).
+		Select("t.loai_thu_tuc, " + demTheoSLA).
+		Group("t.loai_thu_tuc").
+		Order("qua_han DESC, t.loai_thu_tuc ASC").
+		Scan(&list).Error
+	return list, err
+}
+
+// ListQuaHan trả về hồ sơ quá hạn, quá hạn lâu nhất trước
+func (r *thongKeRepo) ListQuaHan(
+	ctx context.Context,
+	db *gorm.DB,
+	now time.Time,
+	loaiThuTuc string,
+	canBoID uuid.UUID,
+	page int,
+	pageSize int,
+) ([]dto.HoSoQuaHanResponse, int64, error) {
+	query := hoSoDangGiaiQuyet(ctx, db).
+		Where("hs.trang_thai_ho_so <> 'BiTraLai' AND hs.ngay_hen_tra < ?", now)
+	if loaiThuTuc != "" {
+		query = query.Where("hs.loai_thu_tuc = ?", loaiThuTuc)
+	}
+	if canBoID != uuid.Nil {
+		query = query.Where("hs.can_bo_xu_ly_id = ?", canBoID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []dto.HoSoQuaHanResponse
+	err := query.
+		Joins("JOIN doanh_nghiep dn ON dn.id = hs.doanh_nghiep_id").
+		Select(`hs.id, hs.ma_ho_so, hs.loai_thu_tuc, hs.doanh_nghiep_id, dn.ten_doanh_nghiep_vi,
+			hs.trang_thai_ho_so, hs.ngay_tiep_nhan, hs.ngay_hen_tra,
+			CEIL(EXTRACT(EPOCH FROM (?::timestamptz - hs.ngay_hen_tra)) / 86400)::int AS so_ngay_qua_han,
+			cb.id AS can_bo_id, cb.full_name AS ho_ten_can_bo`, now).
+		Order("hs.ngay_hen_tra ASC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Scan(&list).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type ThrottleRepository interface {
+	Get(scope, subject string) (*models.AuthThrottle, error)
+	RegisterFailure(scope, subject string, window time.Duration) (int, error)
+	LockUntil(scope, subject string, until time.Time) error
+	Reset(scope, subject string) error
+}
+
+type throttleRepo struct {
+	db *gorm.DB
+}
+
+func NewThrottleRepository(db *gorm.DB) ThrottleRepository {
+	return &throttleRepo{db: db}
+}
+
+// Get trả về bản ghi rỗng (FailCount = 0) nếu subject chưa từng sai
+func (r *throttleRepo) Get(scope, subject string) (*models.AuthThrottle, error) {
+	var t models.AuthThrottle
+	err := r.db.Where("scope = ? AND subject = ?", scope, subject).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.AuthThrottle{Scope: scope, Subject: subject}, nil
+	}
+	return &t, err
+}
+
+// RegisterFailure tăng bộ đếm (atomic, an toàn khi nhiều request song song) và trả về số lần sai hiện tại.
+// Lần sai cuối cách đây quá 'window' thì bộ đếm bắt đầu lại từ 1.
+func (r *throttleRepo) RegisterFailure(scope, subject string, window time.Duration) (int, error) {
+	now := time.Now()
+	var count int
+	err := r.db.Raw(`
+		INSERT INTO auth_throttles (scope, subject, fail_count, last_failed_at)
+		VALUES (?, ?, 1, ?)
+		ON CONFLICT (scope, subject) DO UPDATE SET
+			fail_count = CASE
+				WHEN auth_throttles.last_failed_at IS NULL OR auth_throttles.last_failed_at < ? THEN 1
+				ELSE auth_throttles.fail_count + 1
+			END,
+			last_failed_at = EXCLUDED.last_failed_at
+		RETURNING fail_count`, scope, subject, now, now.Add(-window)).
+		Scan(&count).Error
+	return count, err
+}
+
+func (r *throttleRepo) LockUntil(scope, subject string, until time.Time) error {
+	return r.db.Model(&models.AuthThrottle{}).
+		Where("scope = ? AND subject = ?", scope, subject).
+		Update("locked_until", until).Error
+}
+
+func (r *throttleRepo) Reset(scope, subject string) error {
+	return r.db.Where("scope = ? AND subject = ?", scope, subject).
+		Delete(&models.AuthThrottle{}).Error
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type ThuTucRepository interface {
+	ListThuTuc(ctx context.Context, db *gorm.DB, chiHoatDong bool) ([]models.ThuTuc, error)
+	GetThuTucByID(ctx context.Context, db *gorm.DB, id uuid.UUID) (*models.ThuTuc, error)
+	GetThuTucByTen(ctx context.Context, db *gorm.DB, ten string) (*models.ThuTuc, error)
+	CreateThuTuc(ctx context.Context, db *gorm.DB, thuTuc *models.ThuTuc) error
+	UpdateThuTuc(ctx context.Context, db *gorm.DB, thuTuc *models.ThuTuc) error
+	ReplaceTaiLieu(ctx context.Context, db *gorm.DB, thuTucID uuid.UUID, taiLieus []models.ThuTucTaiLieu) error
+	DeleteThuTuc(ctx context.Context, db *gorm.DB, id uuid.UUID) error
+	// CountHoSo đếm hồ sơ đã nộp theo thủ tục (theo tên)
+	CountHoSo(ctx context.Context, db *gorm.DB, ten string) (int64, error)
+	CountLoaiTaiLieu(ctx context.Context, db *gorm.DB, ids []uuid.UUID) (int64, error)
+}
+
+type thuTucRepo struct{}
+
+func NewThuTucRepository() ThuTucRepository {
+	return &thuTucRepo{}
+}
+
+func preloadThanhPhan(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("TaiLieus", func(db *gorm.DB) *gorm.DB { return db.Order("thu_tu ASC") }).
+		Preload("TaiLieus.LoaiTaiLieu")
+}
+
+func (r *thuTucRepo) ListThuTuc(ctx context.Context, db *gorm.DB, chiHoatDong bool) ([]models.ThuTuc, error) {
+	var list []models.ThuTuc
+	query := preloadThanhPhan(db.WithContext(ctx))
+	if chiHoatDong {
+		query = query.Where("hoat_dong = TRUE")
+	}
+	err := query.Order("thu_tu_hien_thi ASC, ten ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *thuTucRepo) GetThuTucByID(ctx context.Context, db *gorm.DB, id uuid.UUID) (*models.ThuTuc, error) {
+	var thuTuc models.ThuTuc
+	err := preloadThanhPhan(db.WithContext(ctx)).First(&thuTuc, "id = ?", id).Error
+	return &thuTuc, err
+}
+
+func (r *thuTucRepo) GetThuTucByTen(ctx context.Context, db *gorm.DB, ten string) (*models.ThuTuc, error) {
+	var thuTuc models.ThuTuc
+	err := preloadThanhPhan(db.WithContext(ctx)).First(&thuTuc, "ten = ?", ten).Error
+	return &thuTuc, err
+}
+
+// CreateThuTuc tạo thủ tục kèm các thành phần hồ sơ trong TaiLieus
+func (r *thuTucRepo) CreateThuTuc(ctx context.Context, db *gorm.DB, thuTuc *models.ThuTuc) error {
+	return db.WithContext(ctx).Omit("TaiLieus.LoaiTaiLieu").Create(thuTuc).Error
+}
+
+func (r *thuTucRepo) UpdateThuTuc(ctx context.Context, db *gorm.DB, thuTuc *models.ThuTuc) error {
+	return db.WithContext(ctx).Omit("TaiLieus").Save(thuTuc).Error
+}
+
+// ReplaceTaiLieu thay toàn bộ thành phần hồ sơ của thủ tục (gọi trong transaction)
+func (r *thuTucRepo) ReplaceTaiLieu(ctx context.Context, db *gorm.DB, thuTucID uuid.UUID, taiLieus []models.ThuTucTaiLieu) error {
+	if err := db.WithContext(ctx).Where("thu_tuc_id = ?", thuTucID).Delete(&models.ThuTucTaiLieu{}).Error; err != nil {
+		return err
+	}
+	if len(taiLieus) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Omit("LoaiTaiLieu").Create(&taiLieus).Error
+}
+
+func (r *thuTucRepo) DeleteThuTuc(ctx context.Context, db *gorm.DB, id uuid.UUID) error {
+	return db.WithContext(ctx).Delete(&models.ThuTuc{}, "id = ?", id).Error
+}
+
+func (r *thuTucRepo) CountHoSo(ctx context.Context, db *gorm.DB, ten string) (int64, error) {
+	var count int64
+	err := db.WithContext(ctx).Model(&models.HoSo{}).Where("loai_thu_tuc = ?", ten).Count(&count).Error
+	return count, err
+}
+
+func (r *thuTucRepo) CountLoaiTaiLieu(ctx context.Context, db *gorm.DB, ids []uuid.UUID) (int64, error) {
+	var count int64
+	err := db.WithContext(ctx).Model(&models.LoaiTaiLieu{}).Where("id IN ?", ids).Count(&count).Error
+	return count, err
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/gofrs/uuid"
 	"github.com/vnkmasc/KmaERM/backend/internal/models"
 	"gorm.io/gorm"
@@ -10,9 +12,22 @@ type UserRepository interface {
 	Create(tx *gorm.DB, user *models.User) error
 	GetByEmail(email string) (*models.User, error)
 	GetRoleByName(name string) (*models.Role, error)
-	UpdatePassword(id uuid.UUID, newHash string) error
+	UpdatePassword(tx *gorm.DB, id uuid.UUID, newHash string) error
 	GetByID(id uuid.UUID) (*models.User, error)
 	Update(user *models.User) error
+	RevokeTokens(tx *gorm.DB, id uuid.UUID, at time.Time) error
+	SetActive(tx *gorm.DB, id uuid.UUID, active bool) error
+
+	SetTOTPSecret(tx *gorm.DB, id uuid.UUID, secretEnc *string, enabled bool) error
+	MarkTOTPStepUsed(id uuid.UUID, step int64) (bool, error)
+	ReplaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error)
+
+	UpdateSigner(tx *gorm.DB, id uuid.UUID, signerType string, hsmKeyLabel *string, publicKeyPEM string) error
+	SetChucVu(id uuid.UUID, chucVu *string) error
+
+	IncrementOTPFailure(id uuid.UUID) (int, error)
+	InvalidateOTP(id uuid.UUID) error
 }
 
 type userRepo struct {
@@ -41,7 +56,7 @@ func (r *userRepo) GetByEmail(email string) (*models.User, error) {
 
 func (r *userRepo) GetByID(id uuid.UUID) (*models.User, error) {
 	var u models.User
-	err := r.db.First(&u, id).Error
+	err := r.db.Preload("Role").First(&u, id).Error
 	return &u, err
 }
 
@@ -51,10 +66,114 @@ func (r *userRepo) GetRoleByName(name string) (*models.Role, error) {
 	return &role, err
 }
 
-func (r *userRepo) UpdatePassword(id uuid.UUID, newHash string) error {
-	return r.db.Model(&models.User{}).Where("id = ?", id).Update("password_hash", newHash).Error
+func (r *userRepo) UpdatePassword(tx *gorm.DB, id uuid.UUID, newHash string) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.User{}).Where("id = ?", id).Update("password_hash", newHash).Error
 }
 
 func (r *userRepo) Update(user *models.User) error {
 	return r.db.Save(user).Error
 }
+
+// RevokeTokens đặt mốc tokens_valid_after: mọi access token phát hành trước 'at' bị từ chối
+func (r *userRepo) RevokeTokens(tx *gorm.DB, id uuid.UUID, at time.Time) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.User{}).Where("id = ?", id).Update("tokens_valid_after", at).Error
+}
+
+func (r *userRepo) SetActive(tx *gorm.DB, id uuid.UUID, active bool) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models.User{}).Where("id = ?", id).Update("is_active", active)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetTOTPSecret lưu (hoặc xóa khi secretEnc = nil) secret TOTP và trạng thái bật 2FA
+func (r *userRepo) SetTOTPSecret(tx *gorm.DB, id uuid.UUID, secretEnc *string, enabled bool) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"totp_secret_enc": secretEnc,
+		"totp_enabled":    enabled,
+		"totp_last_step":  nil,
+	}).Error
+}
+
+// MarkTOTPStepUsed ghi nhận bước thời gian đã dùng. Trả về false nếu mã ở bước này (hoặc cũ hơn)
+// đã được dùng trước đó, tức là request đang phát lại mã cũ.
+func (r *userRepo) MarkTOTPStepUsed(id uuid.UUID, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)", id, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *userRepo) ReplaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID, codeHashes []string) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	codes := make([]models.UserRecoveryCode, 0, len(codeHashes))
+	for _, h := range codeHashes {
+		codes = append(codes, models.UserRecoveryCode{UserID: userID, CodeHash: h})
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseRecoveryCode đánh dấu mã đã dùng; trả về false nếu mã không tồn tại hoặc đã dùng
+func (r *userRepo) UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	result := r.db.Model(&models.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// IncrementOTPFailure tăng bộ đếm OTP sai (atomic) và trả về giá trị mới
+func (r *userRepo) IncrementOTPFailure(id uuid.UUID) (int, error) {
+	var count int
+	err := r.db.Raw(`UPDATE users SET otp_failed_count = otp_failed_count + 1 WHERE id = ? RETURNING otp_failed_count`, id).
+		Scan(&count).Error
+	return count, err
+}
+
+// InvalidateOTP hủy OTP hiện tại, người dùng phải yêu cầu mã mới
+func (r *userRepo) InvalidateOTP(id uuid.UUID) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"otp_code":         nil,
+		"otp_expiry":       nil,
+		"otp_failed_count": 0,
+	}).Error
+}
+
+func (r *userRepo) UpdateSigner(tx *gorm.DB, id uuid.UUID, signerType string, hsmKeyLabel *string, publicKeyPEM string) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"signer_type":    signerType,
+		"hsm_key_label":  hsmKeyLabel,
+		"public_key_pem": publicKeyPEM,
+	}).Error
+}
+
+func (r *userRepo) SetChucVu(id uuid.UUID, chucVu *string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("chuc_vu", chucVu).Error
+}
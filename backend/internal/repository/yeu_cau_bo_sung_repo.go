@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type YeuCauBoSungRepository interface {
+	Create(ctx context.Context, db *gorm.DB, yeuCau *models.YeuCauBoSung) error
+	GetDangMo(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) (*models.YeuCauBoSung, error)
+	ListByHoSoID(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) ([]models.YeuCauBoSung, error)
+	DanhDauDaBoSung(ctx context.Context, db *gorm.DB, hoSoTaiLieuID uuid.UUID, at time.Time) error
+	BoDanhDauBoSung(ctx context.Context, db *gorm.DB, hoSoTaiLieuID uuid.UUID) error
+	Dong(ctx context.Context, db *gorm.DB, yeuCauID uuid.UUID, at time.Time) error
+	CountKheTaiLieu(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID, hoSoTaiLieuIDs []uuid.UUID) (int64, error)
+}
+
+type yeuCauBoSungRepo struct{}
+
+func NewYeuCauBoSungRepository() YeuCauBoSungRepository {
+	return &yeuCauBoSungRepo{}
+}
+
+// Create ghi vòng yêu cầu cùng các khe tài liệu cần bổ sung (gọi trong transaction)
+func (r *yeuCauBoSungRepo) Create(ctx context.Context, db *gorm.DB, yeuCau *models.YeuCauBoSung) error {
+	if err := db.WithContext(ctx).Omit("TaiLieus").Create(yeuCau).Error; err != nil {
+		return err
+	}
+	for i := range yeuCau.TaiLieus {
+		yeuCau.TaiLieus[i].YeuCauBoSungID = yeuCau.ID
+	}
+	if len(yeuCau.TaiLieus) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Omit("HoSoTaiLieu").Create(&yeuCau.TaiLieus).Error
+}
+
+func preloadKheTaiLieu(db *gorm.DB) *gorm.DB {
+	return db.Preload("TaiLieus.HoSoTaiLieu.LoaiTaiLieu")
+}
+
+// GetDangMo trả về vòng đang chờ bổ sung của hồ sơ (gorm.ErrRecordNotFound nếu không có)
+func (r *yeuCauBoSungRepo) GetDangMo(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) (*models.YeuCauBoSung, error) {
+	var yeuCau models.YeuCauBoSung
+	err := preloadKheTaiLieu(db.WithContext(ctx)).
+		Where("ho_so_id = ? AND trang_thai = ?", hoSoID, models.YeuCauBoSungChoBoSung).
+		First(&yeuCau).Error
+	return &yeuCau, err
+}
+
+// ListByHoSoID trả về mọi vòng yêu cầu bổ sung của hồ sơ, vòng mới nhất trước
+func (r *yeuCauBoSungRepo) ListByHoSoID(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID) ([]models.YeuCauBoSung, error) {
+	var list []models.YeuCauBoSung
+	err := preloadKheTaiLieu(db.WithContext(ctx)).
+		Where("ho_so_id = ?", hoSoID).
+		Order("lan DESC").
+		Find(&list).Error
+	return list, err
+}
+
+// DanhDauDaBoSung đánh dấu khe tài liệu đã được bổ sung trong vòng đang chờ bổ sung (nếu khe đó bị yêu cầu)
+func (r *yeuCauBoSungRepo) DanhDauDaBoSung(ctx context.Context, db *gorm.DB, hoSoTaiLieuID uuid.UUID, at time.Time) error {
+	return db.WithContext(ctx).
+		Model(&models.YeuCauBoSungTaiLieu{}).
+		Where("ho_so_tai_lieu_id = ? AND da_bo_sung = FALSE", hoSoTaiLieuID).
+		Where("yeu_cau_bo_sung_id IN (?)", db.Model(&models.YeuCauBoSung{}).Select("id").Where("trang_thai = ?", models.YeuCauBoSungChoBoSung)).
+		Updates(map[string]any{"da_bo_sung": true, "ngay_bo_sung": at}).Error
+}
+
+// BoDanhDauBoSung trả khe về chưa bổ sung trong vòng đang chờ khi khe không còn tài liệu nộp sau lúc yêu cầu
+func (r *yeuCauBoSungRepo) BoDanhDauBoSung(ctx context.Context, db *gorm.DB, hoSoTaiLieuID uuid.UUID) error {
+	return db.WithContext(ctx).Exec(`
+		UPDATE yeu_cau_bo_sung_tai_lieu t
+		SET da_bo_sung = FALSE, ngay_bo_sung = NULL
+		FROM yeu_cau_bo_sung y
+		WHERE t.yeu_cau_bo_sung_id = y.id
+		  AND y.trang_thai = ?
+		  AND t.ho_so_tai_lieu_id = ?
+		  AND t.da_bo_sung
+		  AND NOT EXISTS (
+			SELECT 1 FROM tai_lieu tl
+			WHERE tl.ho_so_tai_lieu_id = t.ho_so_tai_lieu_id AND tl.created_at >= y.created_at
+		  )`, models.YeuCauBoSungChoBoSung, hoSoTaiLieuID).Error
+}
+
+func (r *yeuCauBoSungRepo) Dong(ctx context.Context, db *gorm.DB, yeuCauID uuid.UUID, at time.Time) error {
+	return db.WithContext(ctx).
+		Model(&models.YeuCauBoSung{}).
+		Where("id = ?", yeuCauID).
+		Updates(map[string]any{"trang_thai": models.YeuCauBoSungDaBoSung, "ngay_nop_lai": at}).Error
+}
+
+// CountKheTaiLieu đếm số khe trong danh sách thuộc đúng hồ sơ
+func (r *yeuCauBoSungRepo) CountKheTaiLieu(ctx context.Context, db *gorm.DB, hoSoID uuid.UUID, hoSoTaiLieuIDs []uuid.UUID) (int64, error) {
+	var count int64
+	err := db.WithContext(ctx).
+		Model(&models.HoSoTaiLieu{}).
+		Where("ho_so_id = ? AND id IN ?", hoSoID, hoSoTaiLieuIDs).
+		Count(&count).Error
+	return count, err
+}
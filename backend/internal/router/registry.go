@@ -0,0 +1,157 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+)
+
+// Access là mức truy cập của một endpoint
+type Access int
+
+const (
+	// Public: không cần đăng nhập, chỉ dùng cho endpoint đọc (GET)
+	Public Access = iota
+	// PublicWrite: endpoint ghi nhưng bắt buộc phải public (đăng nhập, quên mật khẩu...).
+	// Phải khai báo tường minh để self-check không báo lỗi.
+	PublicWrite
+	// Protected: cần đăng nhập (mọi role)
+	Protected
+	// RoleRestricted: cần đăng nhập và có một trong các role trong Roles
+	RoleRestricted
+	// TOTPEnrollment: như Protected nhưng nhận cả token đăng ký 2FA, chỉ dùng cho các bước thiết lập 2FA
+	TOTPEnrollment
+)
+
+func (a Access) String() string {
+	switch a {
+	case Public:
+		return "public"
+	case PublicWrite:
+		return "public-write"
+	case Protected:
+		return "protected"
+	case RoleRestricted:
+		return "role"
+	case TOTPEnrollment:
+		return "totp-enrollment"
+	}
+	return fmt.Sprintf("access(%d)", int(a))
+}
+
+type Route struct {
+	Method  string
+	Path    string // Tương đối so với prefix của Registry, ví dụ "/ho-so/:id"
+	Access  Access
+	Roles   []string
+	Handler gin.HandlerFunc
+}
+
+// Registry là nơi DUY NHẤT khai báo endpoint của API cùng mức truy cập của chúng.
+type Registry struct {
+	prefix string
+	routes []Route
+}
+
+func NewRegistry(prefix string) *Registry {
+	return &Registry{prefix: prefix}
+}
+
+func (r *Registry) Add(routes ...Route) {
+	r.routes = append(r.routes, routes...)
+}
+
+func (r *Registry) Routes() []Route {
+	return r.routes
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Validate kiểm tra bảng route trước khi đăng ký:
+// endpoint ghi không được public (trừ PublicWrite), route theo role phải có role, không trùng lặp.
+func (r *Registry) Validate() error {
+	var errs []error
+	seen := make(map[string]struct{}, len(r.routes))
+
+	for _, rt := range r.routes {
+		key := rt.Method + " " + r.prefix + rt.Path
+
+		if _, dup := seen[key]; dup {
+			errs = append(errs, fmt.Errorf("%s: khai báo trùng lặp", key))
+		}
+		seen[key] = struct{}{}
+
+		if rt.Handler == nil {
+			errs = append(errs, fmt.Errorf("%s: thiếu handler", key))
+		}
+		if rt.Access == Public && isMutating(rt.Method) {
+			errs = append(errs, fmt.Errorf("%s: endpoint ghi không được để public", key))
+		}
+		if rt.Access == PublicWrite && !isMutating(rt.Method) {
+			errs = append(errs, fmt.Errorf("%s: PublicWrite chỉ dùng cho endpoint ghi, hãy dùng Public", key))
+		}
+		if rt.Access == RoleRestricted && len(rt.Roles) == 0 {
+			errs = append(errs, fmt.Errorf("%s: route giới hạn role nhưng không khai báo role nào", key))
+		}
+		if rt.Access != RoleRestricted && len(rt.Roles) > 0 {
+			errs = append(errs, fmt.Errorf("%s: khai báo role nhưng mức truy cập là %s", key, rt.Access))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Mount kiểm tra bảng route, đăng ký vào engine rồi đối chiếu lại toàn bộ route của engine:
+// nếu có endpoint ghi nào được đăng ký ngoài Registry (tức không qua kiểm soát truy cập) thì báo lỗi.
+func (r *Registry) Mount(engine *gin.Engine, authMiddleware gin.HandlerFunc) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	group := engine.Group(r.prefix)
+	guarded := make(map[string]bool, len(r.routes))
+
+	for _, rt := range r.routes {
+		var chain []gin.HandlerFunc
+		switch rt.Access {
+		case Protected:
+			chain = append(chain, authMiddleware)
+		case RoleRestricted:
+			chain = append(chain, authMiddleware, middleware.RequireRole(rt.Roles...))
+		case TOTPEnrollment:
+			chain = append(chain, middleware.AllowEnrollmentToken(), authMiddleware)
+		}
+		chain = append(chain, rt.Handler)
+
+		group.Handle(rt.Method, rt.Path, chain...)
+		guarded[rt.Method+" "+joinPath(r.prefix, rt.Path)] = rt.Access != Public
+	}
+
+	var errs []error
+	for _, info := range engine.Routes() {
+		if !isMutating(info.Method) {
+			continue
+		}
+		if ok, declared := guarded[info.Method+" "+info.Path]; !declared || !ok {
+			errs = append(errs, fmt.Errorf("%s %s: endpoint ghi không được khai báo trong route registry", info.Method, info.Path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func joinPath(prefix, path string) string {
+	if path == "" {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(path, "/")
+}
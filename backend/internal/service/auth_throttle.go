@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/repository"
+)
+
+var ErrTamKhoaDangNhap = errors.New("đăng nhập sai quá nhiều lần, vui lòng thử lại sau")
+
+// LockedError cho biết thời điểm được thử lại (handler trả về header Retry-After)
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s (mở khóa lúc %s)", ErrTamKhoaDangNhap.Error(), e.Until.Format("15:04:05 02/01/2006"))
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrTamKhoaDangNhap
+}
+
+func (e *LockedError) RetryAfter() time.Duration {
+	return time.Until(e.Until)
+}
+
+// throttlePolicy: sai tới ngưỡng Threshold thì khóa BaseLock, mỗi lần sai tiếp theo khóa gấp đôi (tối đa MaxLock).
+// Bộ đếm tự reset nếu không sai thêm lần nào trong Window.
+type throttlePolicy struct {
+	Threshold int
+	BaseLock  time.Duration
+	MaxLock   time.Duration
+	Window    time.Duration
+}
+
+var throttlePolicies = map[string]throttlePolicy{
+	models.ThrottleScopeLogin: {Threshold: 5, BaseLock: time.Minute, MaxLock: time.Hour, Window: time.Hour},
+	models.ThrottleScopeOTP:   {Threshold: 5, BaseLock: 5 * time.Minute, MaxLock: 24 * time.Hour, Window: 24 * time.Hour},
+	models.ThrottleScopeTOTP:  {Threshold: 5, BaseLock: 5 * time.Minute, MaxLock: 24 * time.Hour, Window: 24 * time.Hour},
+	// Một IP có thể là NAT của cả cơ quan nên ngưỡng cao hơn
+	models.ThrottleScopeIP: {Threshold: 30, BaseLock: time.Minute, MaxLock: time.Hour, Window: 15 * time.Minute},
+}
+
+func (p throttlePolicy) lockDuration(failCount int) time.Duration {
+	if failCount < p.Threshold {
+		return 0
+	}
+	d := p.BaseLock
+	for i := p.Threshold; i < failCount && d < p.MaxLock; i++ {
+		d *= 2
+	}
+	if d > p.MaxLock {
+		d = p.MaxLock
+	}
+	return d
+}
+
+// authThrottle đếm số lần sai theo tài khoản (email) và theo IP
+type authThrottle struct {
+	repo repository.ThrottleRepository
+}
+
+func throttleSubject(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Check trả về *LockedError nếu một trong các subject đang bị khóa
+func (t *authThrottle) Check(scope, email, ip string) error {
+	keys := [][2]string{{scope, throttleSubject(email)}}
+	if ip != "" {
+		keys = append(keys, [2]string{models.ThrottleScopeIP, ip})
+	}
+
+	for _, k := range keys {
+		state, err := t.repo.Get(k[0], k[1])
+		if err != nil {
+			return err
+		}
+		if state.LockedUntil != nil && time.Now().Before(*state.LockedUntil) {
+			return &LockedError{Until: *state.LockedUntil}
+		}
+	}
+	return nil
+}
+
+// Fail ghi nhận một lần sai; lỗi ghi bộ đếm chỉ được log để không che lỗi đăng nhập gốc
+func (t *authThrottle) Fail(scope, email, ip string) {
+	t.fail(scope, throttleSubject(email))
+	if ip != "" {
+		t.fail(models.ThrottleScopeIP, ip)
+	}
+}
+
+func (t *authThrottle) fail(scope, subject string) {
+	tp := throttlePolicies[scope]
+
+	count, err := t.repo.RegisterFailure(scope, subject, tp.Window)
+	if err != nil {
+		log.Printf("Lỗi ghi bộ đếm đăng nhập sai (%s/%s): %v", scope, subject, err)
+		return
+	}
+	if d := tp.lockDuration(count); d > 0 {
+		if err := t.repo.LockUntil(scope, subject, time.Now().Add(d)); err != nil {
+			log.Printf("Lỗi khóa tạm thời (%s/%s): %v", scope, subject, err)
+		}
+	}
+}
+
+// Success xóa bộ đếm của tài khoản. Bộ đếm IP không reset để kẻ tấn công
+// không thể "xóa dấu" bằng một tài khoản hợp lệ của chính mình.
+func (t *authThrottle) Success(scope, email string) {
+	if err := t.repo.Reset(scope, throttleSubject(email)); err != nil {
+		log.Printf("Lỗi reset bộ đếm (%s/%s): %v", scope, email, err)
+	}
+}
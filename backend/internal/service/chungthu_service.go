@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/ca"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/repository"
+	"github.com/vnkmasc/KmaERM/backend/internal/signer"
+	"gorm.io/gorm"
+)
+
+// ChungThuService quản lý chứng thư số do CA nội bộ cấp cho cán bộ
+type ChungThuService interface {
+	// CapLai cấp chứng thư mới cho khóa ký hiện tại của user, thu hồi các chứng thư cũ
+	CapLai(userID uuid.UUID) (*models.ChungThuSo, error)
+	ListByUser(userID uuid.UUID) ([]models.ChungThuSo, error)
+	ThuHoi(id uuid.UUID, lyDo string) error
+	RootCertPEM() (string, error)
+	CRL() ([]byte, error)
+}
+
+type chungThuService struct {
+	db       *gorm.DB
+	userRepo repository.UserRepository
+	signers  *signer.Provider
+	ca       *ca.Authority
+}
+
+func NewChungThuService(db *gorm.DB, userRepo repository.UserRepository, signers *signer.Provider, authority *ca.Authority) ChungThuService {
+	return &chungThuService{db: db, userRepo: userRepo, signers: signers, ca: authority}
+}
+
+func (s *chungThuService) getUser(userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNguoiDungKhongTonTai
+	}
+	return user, err
+}
+
+func (s *chungThuService) CapLai(userID uuid.UUID) (*models.ChungThuSo, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lấy public key từ signer đang cấu hình (CSDL hoặc HSM) để chứng thư khớp khóa thực sự dùng khi ký
+	sgn, err := s.signers.ForUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	var ct *models.ChungThuSo
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.ca.RevokeAllByUser(tx, userID, "Cấp lại chứng thư"); err != nil {
+			return err
+		}
+		issued, err := s.ca.Issue(tx, user, sgn.Public())
+		ct = issued
+		return err
+	})
+	return ct, err
+}
+
+func (s *chungThuService) ListByUser(userID uuid.UUID) ([]models.ChungThuSo, error) {
+	if _, err := s.getUser(userID); err != nil {
+		return nil, err
+	}
+	return s.ca.ListByUser(userID)
+}
+
+func (s *chungThuService) ThuHoi(id uuid.UUID, lyDo string) error {
+	return s.ca.Revoke(nil, id, lyDo)
+}
+
+func (s *chungThuService) RootCertPEM() (string, error) {
+	return s.ca.RootCertPEM()
+}
+
+func (s *chungThuService) CRL() ([]byte, error) {
+	return s.ca.CRL()
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/calendar"
+	"github.com/vnkmasc/KmaERM/backend/internal/numbering"
+	"github.com/vnkmasc/KmaERM/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Loại số hiệu, mỗi loại có bộ đếm riêng theo năm
+const (
+	SoHieuMaHoSo     = "ma_ho_so"
+	SoHieuSoGiayPhep = "so_giay_phep"
+)
+
+// Mẫu mặc định, đổi qua MAU_MA_HO_SO và MAU_SO_GIAY_PHEP (cú pháp: package numbering)
+const (
+	MauMaHoSoMacDinh     = "HS-{year}-{seq:6}"
+	MauSoGiayPhepMacDinh = "{seq}/{year}/GP-BCY"
+)
+
+// DanhSo cấp số hiệu liên tục theo năm. CapSo phải được gọi trong transaction tạo bản ghi mang số hiệu:
+// transaction rollback thì số được hoàn lại nên dãy số không bị trùng, không bị lỗ hổng.
+// daDung kiểm tra số hiệu đã có bản ghi mang số đó chưa (nhập tay, hoặc trùng do đổi mẫu giữa năm); số đã dùng bị bỏ qua.
+type DanhSo interface {
+	CapSo(ctx context.Context, tx *gorm.DB, loai string, thoiDiem time.Time, daDung func(soHieu string) (bool, error)) (string, error)
+	// KhopMau cho biết soHieu có dạng số hiệu hệ thống cấp theo mẫu của loại hay không
+	KhopMau(loai string, soHieu string) bool
+}
+
+type danhSoService struct {
+	repo repository.BoDemSoHieuRepository
+	mau  map[string]*numbering.Mau
+}
+
+// NewDanhSoService đọc mẫu số hiệu từ biến môi trường; mẫu sai cú pháp trả về lỗi để dừng server
+func NewDanhSoService(repo repository.BoDemSoHieuRepository) (DanhSo, error) {
+	s := &danhSoService{repo: repo, mau: map[string]*numbering.Mau{}}
+	for loai, cauHinh := range map[string][2]string{
+		SoHieuMaHoSo:     {"MAU_MA_HO_SO", MauMaHoSoMacDinh},
+		SoHieuSoGiayPhep: {"MAU_SO_GIAY_PHEP", MauSoGiayPhepMacDinh},
+	} {
+		giaTri := os.Getenv(cauHinh[0])
+		if giaTri == "" {
+			giaTri = cauHinh[1]
+		}
+		mau, err := numbering.Parse(giaTri)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cauHinh[0], err)
+		}
+		s.mau[loai] = mau
+	}
+	return s, nil
+}
+
+// CapSo lấy số chưa dùng kế tiếp của loại số hiệu trong năm (giờ Việt Nam) chứa thoiDiem
+func (s *danhSoService) CapSo(ctx context.Context, tx *gorm.DB, loai string, thoiDiem time.Time, daDung func(soHieu string) (bool, error)) (string, error) {
+	mau, ok := s.mau[loai]
+	if !ok {
+		return "", fmt.Errorf("loại số hiệu không xác định: %s", loai)
+	}
+	nam := thoiDiem.In(calendar.ViTri).Year()
+	for {
+		seq, err := s.repo.TangBoDem(ctx, tx, loai, nam)
+		if err != nil {
+			return "", fmt.Errorf("lỗi khi cấp số hiệu %s: %w", loai, err)
+		}
+		soHieu := mau.Format(seq, nam)
+		trung, err := daDung(soHieu)
+		if err != nil {
+			return "", fmt.Errorf("lỗi khi kiểm tra số hiệu %s: %w", soHieu, err)
+		}
+		if !trung {
+			return soHieu, nil
+		}
+	}
+}
+
+func (s *danhSoService) KhopMau(loai string, soHieu string) bool {
+	mau, ok := s.mau[loai]
+	return ok && mau.Match(soHieu)
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/vnkmasc/KmaERM/backend/internal/calendar"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/policy"
+	"github.com/vnkmasc/KmaERM/backend/pkg/blockchain"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrThuTucKhongCapGiayPhep = errors.New("thủ tục của hồ sơ không cấp giấy phép")
+	ErrSoGiayPhepDaTonTai     = errors.New("số giấy phép đã tồn tại")
+	ErrSoGiayPhepTrungMau     = errors.New("số giấy phép nhập tay không được có dạng số do hệ thống cấp, hãy bỏ trống để hệ thống cấp số")
+)
+
+// TrangThaiGiayPhepHieuLuc là trạng thái của giấy phép vừa cấp
+const TrangThaiGiayPhepHieuLuc = "HieuLuc"
+
+// kiemTraSoNhapTay: số nhập tay có dạng số theo mẫu sẽ chiếm mất một số bộ đếm sắp cấp
+func (s *giayPhepService) kiemTraSoNhapTay(soGiayPhep string) error {
+	if s.danhSo.KhopMau(SoHieuSoGiayPhep, soGiayPhep) {
+		return ErrSoGiayPhepTrungMau
+	}
+	return nil
+}
+
+// taoGiayPhep cấp số (nếu chưa có), tính h1, lưu giấy phép và ghi sự kiện cấp giấy phép vào nhật ký hồ sơ.
+// sk chỉ cần các trường trạng thái khi việc cấp giấy phép cũng chuyển trạng thái hồ sơ.
+func (s *giayPhepService) taoGiayPhep(ctx context.Context, tx *gorm.DB, giayPhep *models.GiayPhep, sk suKienHoSo) error {
+	if giayPhep.SoGiayPhep == "" {
+		soGiayPhep, err := s.danhSo.CapSo(ctx, tx, SoHieuSoGiayPhep, time.Now(), func(so string) (bool, error) {
+			return s.gpRepo.ExistsSoGiayPhep(ctx, tx, so)
+		})
+		if err != nil {
+			return err
+		}
+		giayPhep.SoGiayPhep = soGiayPhep
+	} else if err := s.kiemTraSoNhapTay(giayPhep.SoGiayPhep); err != nil {
+		return err
+	}
+
+	h1Hash, err := blockchain.CalculateDataHash(
+		giayPhep.HoSoID.String(),
+		giayPhep.LoaiGiayPhep,
+		giayPhep.SoGiayPhep,
+		giayPhep.NgayHieuLuc.Format(time.RFC3339),
+		giayPhep.NgayHetHan.Format(time.RFC3339),
+		giayPhep.TrangThaiGiayPhep,
+	)
+	if err != nil {
+		return fmt.Errorf("lỗi khi tính toán h1 hash: %w", err)
+	}
+	giayPhep.H1Hash = &h1Hash
+
+	if err := s.gpRepo.CreateGiayPhep(ctx, tx, giayPhep); err != nil {
+		return err
+	}
+	sk.loai = models.SuKienCapGiayPhep
+	sk.sau = map[string]any{
+		"giay_phep_id":   giayPhep.ID,
+		"so_giay_phep":   giayPhep.SoGiayPhep,
+		"loai_giay_phep": giayPhep.LoaiGiayPhep,
+		"ngay_hieu_luc":  giayPhep.NgayHieuLuc,
+		"ngay_het_han":   giayPhep.NgayHetHan,
+	}
+	return ghiLichSuHoSo(ctx, tx, s.lichSuRepo, giayPhep.HoSoID, sk)
+}
+
+// loiTaoGiayPhep đổi lỗi ràng buộc của CSDL khi tạo giấy phép thành lỗi nghiệp vụ
+func loiTaoGiayPhep(err error, giayPhep *models.GiayPhep) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code == "23505" { // unique_violation
+			if pgErr.ConstraintName == "giay_phep_ho_so_id_key" {
+				return ErrHoSoDaCoGiayPhep
+			}
+			if pgErr.ConstraintName == "giay_phep_so_giay_phep_key" {
+				return fmt.Errorf("%w: '%s'", ErrSoGiayPhepDaTonTai, giayPhep.SoGiayPhep)
+			}
+			return fmt.Errorf("vi phạm ràng buộc duy nhất: %s", pgErr.Detail)
+		}
+		if pgErr.Code == "23503" { // foreign_key_violation
+			if pgErr.ConstraintName == "giay_phep_ho_so_id_fkey" {
+				return ErrHoSoKhongTimThay // Trả về lỗi nghiệp vụ
+			}
+		}
+	}
+	return fmt.Errorf("lỗi khi tạo giấy phép: %w", err)
+}
+
+// capPhepChoHoSo tạo giấy phép cho hồ sơ trong cùng transaction chuyển hồ sơ sang DaCapPhep: khóa dòng hồ sơ,
+// kiểm tra quyền và trạng thái theo máy trạng thái, thủ tục của hồ sơ phải cấp giấy phép.
+// dung dựng giấy phép từ hồ sơ đã khóa và thủ tục; số giấy phép được gắn vào hồ sơ.
+func (s *giayPhepService) capPhepChoHoSo(
+	ctx context.Context,
+	hoSoID uuid.UUID,
+	dung func(hoSo *models.HoSo, thuTuc *models.ThuTuc) (models.GiayPhep, error),
+) (*models.GiayPhep, error) {
+	ct := mayTrangThaiHoSo[ThaoTacCapGiayPhep]
+	actor, _ := policy.ActorFrom(ctx)
+	if !slices.Contains(ct.roles, actor.RoleName) {
+		return nil, ErrKhongCoQuyenThaoTac
+	}
+
+	var giayPhep models.GiayPhep
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		hoSo, err := s.hosoRepo.GetHoSoForUpdate(ctx, tx, hoSoID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrHoSoKhongTimThay
+			}
+			return err
+		}
+		if !slices.Contains(ct.tu, hoSo.TrangThaiHoSo) {
+			return fmt.Errorf("%w (%s -> %s, hiện tại: %s)", ErrChuyenTrangThaiKhongHopLe, ThaoTacCapGiayPhep, ct.den, hoSo.TrangThaiHoSo)
+		}
+		exists, err := s.gpRepo.CheckHoSoExists(ctx, tx, hoSo.ID)
+		if err != nil {
+			return fmt.Errorf("lỗi khi kiểm tra hồ sơ: %w", err)
+		}
+		if exists {
+			return ErrHoSoDaCoGiayPhep
+		}
+
+		thuTuc, err := s.thuTucRepo.GetThuTucByTen(ctx, tx, hoSo.LoaiThuTuc)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLoaiThuTucKhongHopLe
+			}
+			return err
+		}
+		if thuTuc.LoaiGiayPhep == nil {
+			return ErrThuTucKhongCapGiayPhep
+		}
+
+		if giayPhep, err = dung(hoSo, thuTuc); err != nil {
+			return err
+		}
+		if err := s.taoGiayPhep(ctx, tx, &giayPhep, suKienHoSo{
+			trangThaiTruoc: hoSo.TrangThaiHoSo,
+			trangThaiSau:   ct.den,
+		}); err != nil {
+			return err
+		}
+
+		hoSo.SoGiayPhepTheoHoSo = giayPhep.SoGiayPhep
+		hoSo.TrangThaiHoSo = ct.den
+		return s.hosoRepo.UpdateHoSo(ctx, tx, hoSo)
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, loiTaoGiayPhep(err, &giayPhep)
+		}
+		return nil, err
+	}
+	return &giayPhep, nil
+}
+
+// CapGiayPhepTheoHoSo cấp giấy phép cho hồ sơ đã duyệt: loại giấy phép và thời hạn hiệu lực lấy từ danh mục thủ tục,
+// số giấy phép cấp theo mẫu.
+func (s *giayPhepService) CapGiayPhepTheoHoSo(ctx context.Context, hoSoID uuid.UUID, req *dto.CapGiayPhepRequest) (*dto.GiayPhepResponse, error) {
+	ngayHieuLuc := calendar.NgayCua(time.Now())
+	if req.NgayHieuLuc != nil {
+		ngayHieuLuc = calendar.NgayCua(*req.NgayHieuLuc)
+	}
+
+	giayPhep, err := s.capPhepChoHoSo(ctx, hoSoID, func(hoSo *models.HoSo, thuTuc *models.ThuTuc) (models.GiayPhep, error) {
+		if thuTuc.SoThangHieuLuc == nil {
+			return models.GiayPhep{}, ErrThieuThoiHanGiayPhep
+		}
+		trangThaiBC := TrangThaiBCChuaDongBo
+		return models.GiayPhep{
+			HoSoID:              hoSo.ID,
+			LoaiGiayPhep:        *thuTuc.LoaiGiayPhep,
+			NgayHieuLuc:         ngayHieuLuc,
+			NgayHetHan:          ngayHieuLuc.AddDate(0, *thuTuc.SoThangHieuLuc, 0),
+			TrangThaiGiayPhep:   TrangThaiGiayPhepHieuLuc,
+			TrangThaiBlockchain: &trangThaiBC,
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetGiayPhepByID(ctx, giayPhep.ID)
+}
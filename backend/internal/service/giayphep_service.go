@@ -2,6 +2,11 @@ package service
 
 import (
 	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,28 +19,43 @@ import (
 
 	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/vnkmasc/KmaERM/backend/internal/ca"
+	"github.com/vnkmasc/KmaERM/backend/internal/cms"
 	"github.com/vnkmasc/KmaERM/backend/internal/dto"
 	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/pades"
+	"github.com/vnkmasc/KmaERM/backend/internal/policy"
 	"github.com/vnkmasc/KmaERM/backend/internal/repository"
+	"github.com/vnkmasc/KmaERM/backend/internal/signer"
+	"github.com/vnkmasc/KmaERM/backend/internal/tsa"
 	"github.com/vnkmasc/KmaERM/backend/pkg/blockchain"
-	"github.com/vnkmasc/KmaERM/backend/utils"
 	"gorm.io/gorm"
 )
 
 var (
-	ErrGiayPhepKhongTimThay = errors.New("không tìm thấy giấy phép")
-	ErrHoSoDaCoGiayPhep     = errors.New("hồ sơ này đã được cấp giấy phép")
-	ErrGiayPhepDaBiThuHoi   = errors.New("giấy phép này đã bị thu hồi hoặc hết hạn, không thể thao tác")
-	ErrGiayPhepDangHieuLuc  = errors.New("giấy phép đang có hiệu lực, không thể xóa")
+	ErrGiayPhepKhongTimThay  = errors.New("không tìm thấy giấy phép")
+	ErrHoSoDaCoGiayPhep      = errors.New("hồ sơ này đã được cấp giấy phép")
+	ErrGiayPhepDaBiThuHoi    = errors.New("giấy phép này đã bị thu hồi hoặc hết hạn, không thể thao tác")
+	ErrGiayPhepDangHieuLuc   = errors.New("giấy phép đang có hiệu lực, không thể xóa")
+	ErrXoaGiayPhepDaPhatHanh = errors.New("giấy phép đã ký số hoặc đã đồng bộ blockchain, không thể xóa")
 
 	ErrGiayPhepDaDongBo       = errors.New("giấy phép này đã được đồng bộ lên blockchain")
 	ErrGiayPhepChuaDuHash     = errors.New("giấy phép thiếu h1 (hash dữ liệu) hoặc h2 (hash file)")
 	ErrBlockchainOffline      = errors.New("dịch vụ blockchain không khả dụng (chế độ offline)")
 	ErrAssetKhongTonTaiTrenBC = errors.New("asset (giấy phép) không tồn tại trên blockchain")
+
+	ErrFileGiayPhepBiThayDoi = errors.New("file giấy phép trên máy chủ không khớp H2Hash, không thể ký")
+	ErrGiayPhepDaKy          = errors.New("giấy phép đã ký số, không thể thay file")
+	ErrChuaCoChuKyCMS        = errors.New("giấy phép chưa có chữ ký CMS (chưa ký hoặc ký trước khi hỗ trợ .p7s)")
+	ErrDaKyGiayPhep          = errors.New("bạn đã ký giấy phép này, mỗi người chỉ ký một lần")
+	ErrChuKyDongThoi         = errors.New("giấy phép vừa được người khác ký, vui lòng thử lại")
+	ErrGiayPhepDaKyChinh     = errors.New("giấy phép này đã được ký rồi")
+	ErrSuaGiayPhepDaKy       = errors.New("giấy phép đã có chữ ký số, không thể sửa thông tin")
 )
 
 type GiayPhepService interface {
 	CreateGiayPhep(ctx context.Context, req *dto.CreateGiayPhepRequest) (*dto.GiayPhepResponse, error)
+	CapGiayPhepTheoHoSo(ctx context.Context, hoSoID uuid.UUID, req *dto.CapGiayPhepRequest) (*dto.GiayPhepResponse, error)
 	UpdateGiayPhep(ctx context.Context, giayPhepID uuid.UUID, req *dto.UpdateGiayPhepRequest) (*models.GiayPhep, error)
 	DeleteGiayPhep(ctx context.Context, giayPhepID uuid.UUID) error
 	GetGiayPhepByID(ctx context.Context, giayPhepID uuid.UUID) (*dto.GiayPhepResponse, error)
@@ -43,30 +63,56 @@ type GiayPhepService interface {
 	UploadGiayPhepFile(ctx context.Context, giayPhepID uuid.UUID, tempFilePath string, fileName string) (*dto.GiayPhepResponse, error)
 	PushToBlockchain(ctx context.Context, giayPhepID uuid.UUID) error
 	VerifyGiayPhep(ctx context.Context, giayPhepID uuid.UUID) (*dto.VerifyGiayPhepResponse, error)
-	KySoGiayPhep(ctx context.Context, giayPhepID uuid.UUID, userID uuid.UUID) error
+	KySoGiayPhep(ctx context.Context, giayPhepID uuid.UUID, userID uuid.UUID, totpCode string, loaiChuKy string) (*dto.ChuKyResponse, error)
+	VerifyChuKy(ctx context.Context, giayPhepID uuid.UUID) (*dto.VerifySignatureResponse, error)
+	GetChuKyCMS(ctx context.Context, giayPhepID uuid.UUID) ([]byte, error)
 }
 
 type giayPhepService struct {
 	db           *gorm.DB
 	gpRepo       repository.GiayPhepRepository
 	hosoRepo     repository.HoSoRepository
+	lichSuRepo   repository.HoSoLichSuRepository
 	fabricClient *blockchain.FabricClient
 	userRepo     repository.UserRepository
+	stepUp       StepUpVerifier
+	approvals    ApprovalGate
+	signers      *signer.Provider
+	ca           *ca.Authority
+	tsa          *tsa.Stamper
+	danhSo       DanhSo
+	thuTucRepo   repository.ThuTucRepository
 }
 
 func NewGiayPhepService(
 	db *gorm.DB,
 	gpRepo repository.GiayPhepRepository,
 	hosoRepo repository.HoSoRepository,
+	lichSuRepo repository.HoSoLichSuRepository,
 	userRepo repository.UserRepository,
 	fabricClient *blockchain.FabricClient,
+	stepUp StepUpVerifier,
+	approvals ApprovalGate,
+	signers *signer.Provider,
+	authority *ca.Authority,
+	stamper *tsa.Stamper,
+	danhSo DanhSo,
+	thuTucRepo repository.ThuTucRepository,
 ) GiayPhepService {
 	return &giayPhepService{
 		db:           db,
 		gpRepo:       gpRepo,
 		hosoRepo:     hosoRepo,
+		lichSuRepo:   lichSuRepo,
 		userRepo:     userRepo,
 		fabricClient: fabricClient,
+		stepUp:       stepUp,
+		approvals:    approvals,
+		signers:      signers,
+		ca:           authority,
+		tsa:          stamper,
+		danhSo:       danhSo,
+		thuTucRepo:   thuTucRepo,
 	}
 }
 
@@ -76,68 +122,29 @@ const (
 	TrangThaiBCLoiDongBo  = "LoiDongBo"
 )
 
+// CreateGiayPhep nhập giấy phép với các trường do cán bộ điền; như CapGiayPhepTheoHoSo, hồ sơ phải đã duyệt
+// và được chuyển sang DaCapPhep trong cùng transaction.
 func (s *giayPhepService) CreateGiayPhep(ctx context.Context, req *dto.CreateGiayPhepRequest) (*dto.GiayPhepResponse, error) {
-	exists, err := s.gpRepo.CheckHoSoExists(ctx, s.db, req.HoSoID)
-	if err != nil {
-		return nil, fmt.Errorf("lỗi khi kiểm tra hồ sơ: %w", err)
-	}
-	if exists {
-		return nil, ErrHoSoDaCoGiayPhep
-	}
-
-	h1Hash, err := blockchain.CalculateDataHash(
-		req.HoSoID.String(),
-		req.LoaiGiayPhep,
-		req.SoGiayPhep,
-		req.NgayHieuLuc.Format(time.RFC3339),
-		req.NgayHetHan.Format(time.RFC3339),
-		req.TrangThaiGiayPhep,
-	)
+	giayPhep, err := s.capPhepChoHoSo(ctx, req.HoSoID, func(hoSo *models.HoSo, _ *models.ThuTuc) (models.GiayPhep, error) {
+		trangThaiBC := TrangThaiBCChuaDongBo
+		return models.GiayPhep{
+			HoSoID:              hoSo.ID,
+			LoaiGiayPhep:        req.LoaiGiayPhep,
+			SoGiayPhep:          strings.TrimSpace(req.SoGiayPhep),
+			NgayHieuLuc:         req.NgayHieuLuc,
+			NgayHetHan:          req.NgayHetHan,
+			TrangThaiGiayPhep:   req.TrangThaiGiayPhep,
+			TrangThaiBlockchain: &trangThaiBC,
+		}, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("lỗi khi tính toán h1 hash: %w", err)
-	}
-	h1HashStr := h1Hash
-
-	trangThaiBC := TrangThaiBCChuaDongBo
-
-	// 3. Map DTO -> Model
-	giayPhep := models.GiayPhep{
-		HoSoID:              req.HoSoID,
-		LoaiGiayPhep:        req.LoaiGiayPhep,
-		SoGiayPhep:          req.SoGiayPhep,
-		NgayHieuLuc:         req.NgayHieuLuc,
-		NgayHetHan:          req.NgayHetHan,
-		TrangThaiGiayPhep:   req.TrangThaiGiayPhep,
-		H1Hash:              &h1HashStr,
-		TrangThaiBlockchain: &trangThaiBC,
-	}
-
-	// 4. Lưu CSDL
-	if err := s.gpRepo.CreateGiayPhep(ctx, s.db, &giayPhep); err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == "23505" { // unique_violation
-				if pgErr.ConstraintName == "giay_phep_ho_so_id_key" {
-					return nil, ErrHoSoDaCoGiayPhep
-				}
-				if pgErr.ConstraintName == "giay_phep_so_giay_phep_key" {
-					return nil, fmt.Errorf("số giấy phép '%s' đã tồn tại", req.SoGiayPhep)
-				}
-				return nil, fmt.Errorf("vi phạm ràng buộc duy nhất: %s", pgErr.Detail)
-			}
-			if pgErr.Code == "23503" { // foreign_key_violation
-				if pgErr.ConstraintName == "giay_phep_ho_so_id_fkey" {
-					return nil, ErrHoSoKhongTimThay // Trả về lỗi nghiệp vụ
-				}
-			}
-		}
-		return nil, fmt.Errorf("lỗi khi tạo giấy phép: %w", err)
+		return nil, err
 	}
-
-	// 5. Trả về DTO (Sửa: Trả về DTO, không phải model)
 	return s.GetGiayPhepByID(ctx, giayPhep.ID)
 }
 
+// UpdateGiayPhep sửa thông tin giấy phép chưa có chữ ký nào; đổi số giấy phép thì hồ sơ đang gắn số cũ
+// được gắn số mới trong cùng transaction
 func (s *giayPhepService) UpdateGiayPhep(ctx context.Context, giayPhepID uuid.UUID, req *dto.UpdateGiayPhepRequest) (*models.GiayPhep, error) {
 	giayPhep, err := s.gpRepo.GetGiayPhepByID(ctx, s.db, giayPhepID)
 	if err != nil {
@@ -146,40 +153,81 @@ func (s *giayPhepService) UpdateGiayPhep(ctx context.Context, giayPhepID uuid.UU
 		}
 		return nil, fmt.Errorf("lỗi khi tìm giấy phép: %w", err)
 	}
-
-	h1Hash, err := blockchain.CalculateDataHash(
-		giayPhep.HoSoID.String(),
-		req.SoGiayPhep,
-		req.LoaiGiayPhep,
-		req.NgayHieuLuc.Format(time.RFC3339),
-		req.NgayHetHan.Format(time.RFC3339),
-		req.TrangThaiGiayPhep,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("lỗi khi tính toán h1 hash: %w", err)
+	if req.SoGiayPhep != giayPhep.SoGiayPhep {
+		if err := s.kiemTraSoNhapTay(req.SoGiayPhep); err != nil {
+			return nil, err
+		}
 	}
 
-	giayPhep.LoaiGiayPhep = req.LoaiGiayPhep
-	giayPhep.SoGiayPhep = req.SoGiayPhep
-	giayPhep.NgayHieuLuc = req.NgayHieuLuc
-	giayPhep.NgayHetHan = req.NgayHetHan
-	giayPhep.TrangThaiGiayPhep = req.TrangThaiGiayPhep
-	giayPhep.H1Hash = &h1Hash
-	// H2Hash và FileDuongDan không bị ảnh hưởng
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		hoSo, err := s.hosoRepo.GetHoSoForUpdate(ctx, tx, giayPhep.HoSoID)
+		if err != nil {
+			return err
+		}
+		// Đọc lại dưới khóa: giấy phép có thể vừa được ký
+		giayPhep, err = s.gpRepo.GetGiayPhepForUpdate(ctx, tx, giayPhepID)
+		if err != nil {
+			return err
+		}
+		// Nội dung đã ký (H1, số, thời hạn) không được sửa, kể cả khi chỉ có chữ ký nháy
+		if len(giayPhep.ChuKys) > 0 {
+			return ErrSuaGiayPhepDaKy
+		}
 
-	if err := s.gpRepo.UpdateGiayPhep(ctx, s.db, giayPhep); err != nil {
+		h1Hash, err := blockchain.CalculateDataHash(
+			giayPhep.HoSoID.String(),
+			req.SoGiayPhep,
+			req.LoaiGiayPhep,
+			req.NgayHieuLuc.Format(time.RFC3339),
+			req.NgayHetHan.Format(time.RFC3339),
+			giayPhep.TrangThaiGiayPhep,
+		)
+		if err != nil {
+			return fmt.Errorf("lỗi khi tính toán h1 hash: %w", err)
+		}
+
+		soCu := giayPhep.SoGiayPhep
+		giayPhep.LoaiGiayPhep = req.LoaiGiayPhep
+		giayPhep.SoGiayPhep = req.SoGiayPhep
+		giayPhep.NgayHieuLuc = req.NgayHieuLuc
+		giayPhep.NgayHetHan = req.NgayHetHan
+		giayPhep.H1Hash = &h1Hash
+		// H2Hash và FileDuongDan không bị ảnh hưởng
+
+		if err := s.gpRepo.UpdateGiayPhep(ctx, tx, giayPhep); err != nil {
+			return err
+		}
+		if soCu == giayPhep.SoGiayPhep || hoSo.SoGiayPhepTheoHoSo != soCu {
+			return nil
+		}
+		hoSo.SoGiayPhepTheoHoSo = giayPhep.SoGiayPhep
+		if err := s.hosoRepo.UpdateHoSo(ctx, tx, hoSo); err != nil {
+			return err
+		}
+		return ghiLichSuHoSo(ctx, tx, s.lichSuRepo, hoSo.ID, suKienHoSo{
+			loai:  models.SuKienCapNhatHoSo,
+			truoc: map[string]any{"so_giay_phep_theo_ho_so": soCu},
+			sau:   map[string]any{"so_giay_phep_theo_ho_so": giayPhep.SoGiayPhep},
+		})
+	})
+	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			if pgErr.ConstraintName == "giay_phep_so_giay_phep_key" {
-				return nil, fmt.Errorf("số giấy phép '%s' đã tồn tại", req.SoGiayPhep)
+				return nil, fmt.Errorf("%w: '%s'", ErrSoGiayPhepDaTonTai, req.SoGiayPhep)
 			}
 		}
+		if errors.Is(err, ErrSuaGiayPhepDaKy) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("lỗi khi cập nhật giấy phép: %w", err)
 	}
 
 	return giayPhep, nil
 }
 
+// DeleteGiayPhep xóa giấy phép không còn hiệu lực, chưa ký và chưa đồng bộ blockchain. Hồ sơ đã chuyển sang
+// DaCapPhep nhờ giấy phép này được trả về DaDuyet và bỏ số giấy phép trong cùng transaction, để có thể cấp lại.
 func (s *giayPhepService) DeleteGiayPhep(ctx context.Context, giayPhepID uuid.UUID) error {
 	giayPhep, err := s.gpRepo.GetGiayPhepByID(ctx, s.db, giayPhepID)
 	if err != nil {
@@ -189,12 +237,54 @@ func (s *giayPhepService) DeleteGiayPhep(ctx context.Context, giayPhepID uuid.UU
 		return err
 	}
 
-	if giayPhep.TrangThaiGiayPhep == "HieuLuc" || giayPhep.TrangThaiGiayPhep == "SapHetHan" {
-		return ErrGiayPhepDangHieuLuc
-	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		hoSo, err := s.hosoRepo.GetHoSoForUpdate(ctx, tx, giayPhep.HoSoID)
+		if err != nil {
+			return err
+		}
+		// Đọc lại dưới khóa: giấy phép có thể vừa được sửa hoặc ký
+		giayPhep, err = s.gpRepo.GetGiayPhepForUpdate(ctx, tx, giayPhepID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGiayPhepKhongTimThay
+			}
+			return err
+		}
+		if giayPhep.TrangThaiGiayPhep == "HieuLuc" || giayPhep.TrangThaiGiayPhep == "SapHetHan" {
+			return ErrGiayPhepDangHieuLuc
+		}
+		// Giấy phép đã ký hoặc đã neo lên blockchain là văn bản đã phát hành: xóa sẽ kéo theo chu_ky, phe_duyet
+		// và mở đường cấp lại cho hồ sơ
+		if len(giayPhep.ChuKys) > 0 ||
+			(giayPhep.TrangThaiBlockchain != nil && *giayPhep.TrangThaiBlockchain == TrangThaiBCDaDongBo) {
+			return ErrXoaGiayPhepDaPhatHanh
+		}
 
-	if err := s.gpRepo.DeleteGiayPhep(ctx, s.db, giayPhepID); err != nil {
-		return fmt.Errorf("lỗi khi xóa CSDL: %w", err)
+		if err := s.gpRepo.DeleteGiayPhep(ctx, tx, giayPhepID); err != nil {
+			return fmt.Errorf("lỗi khi xóa CSDL: %w", err)
+		}
+
+		sk := suKienHoSo{
+			loai: models.SuKienXoaGiayPhep,
+			truoc: map[string]any{
+				"giay_phep_id":            giayPhep.ID,
+				"so_giay_phep":            giayPhep.SoGiayPhep,
+				"so_giay_phep_theo_ho_so": hoSo.SoGiayPhepTheoHoSo,
+			},
+		}
+		// Mỗi hồ sơ chỉ có một giấy phép: bỏ số giấy phép của hồ sơ, hồ sơ đã cấp phép quay về DaDuyet
+		if hoSo.TrangThaiHoSo == TrangThaiHoSoDaCapPhep {
+			sk.trangThaiTruoc, sk.trangThaiSau = hoSo.TrangThaiHoSo, TrangThaiHoSoDaDuyet
+			hoSo.TrangThaiHoSo = TrangThaiHoSoDaDuyet
+		}
+		hoSo.SoGiayPhepTheoHoSo = ""
+		if err := s.hosoRepo.UpdateHoSo(ctx, tx, hoSo); err != nil {
+			return err
+		}
+		return ghiLichSuHoSo(ctx, tx, s.lichSuRepo, hoSo.ID, sk)
+	})
+	if err != nil {
+		return err
 	}
 
 	if giayPhep.FileDuongDan != nil && *giayPhep.FileDuongDan != "" {
@@ -224,6 +314,10 @@ func (s *giayPhepService) GetGiayPhepByID(ctx context.Context, giayPhepID uuid.U
 		}
 		return nil, err
 	}
+	// Giấy phép của doanh nghiệp khác: coi như không tồn tại
+	if !policy.CanAccessDoanhNghiep(ctx, giayPhep.HoSo.DoanhNghiepID) {
+		return nil, ErrGiayPhepKhongTimThay
+	}
 	// 2. Chuyển đổi model -> DTO response
 	return s.mapGiayPhepToResponse(ctx, giayPhep)
 }
@@ -236,7 +330,13 @@ func (s *giayPhepService) ListGiayPhep(
 	pageSize int,
 ) (*dto.GiayPhepListResponse, error) {
 
-	// 1. Gọi Repository (Đã sửa để Preload HoSo)
+	// 1. Tài khoản doanh nghiệp luôn bị ép lọc theo doanh nghiệp của mình
+	doanhNghiepID, err := policy.ScopeDoanhNghiepID(ctx, doanhNghiepID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Gọi Repository (Đã sửa để Preload HoSo)
 	giayPheps, total, err := s.gpRepo.ListGiayPhep(ctx, s.db, doanhNghiepID, params, page, pageSize)
 	if err != nil {
 		return nil, fmt.Errorf("lỗi khi lấy danh sách: %w", err)
@@ -285,6 +385,9 @@ func (s *giayPhepService) UploadGiayPhepFile(
 	if giayPhep.TrangThaiGiayPhep == "ThuHoi" || giayPhep.TrangThaiGiayPhep == "DaHetHan" {
 		return nil, ErrGiayPhepDaBiThuHoi
 	}
+	if giayPhep.TrangThaiGiayPhep == "DaKy" || len(giayPhep.ChuKys) > 0 {
+		return nil, ErrGiayPhepDaKy
+	}
 	h2Hash, err := blockchain.CalculateFileHash(tempFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("lỗi khi tính h2 hash: %w", err)
@@ -349,6 +452,13 @@ func (s *giayPhepService) mapGiayPhepToResponse(ctx context.Context, giayPhep *m
 	if giayPhep.H2Hash != nil {
 		resp.H2Hash = giayPhep.H2Hash
 	}
+	resp.FileGocDuongDan = giayPhep.FileGocDuongDan
+	resp.H2HashGoc = giayPhep.H2HashGoc
+
+	resp.ChuKy = make([]dto.ChuKyResponse, len(giayPhep.ChuKys))
+	for i := range giayPhep.ChuKys {
+		resp.ChuKy[i] = mapChuKy(&giayPhep.ChuKys[i])
+	}
 
 	if giayPhep.HoSo.ID != uuid.Nil {
 		resp.HoSo = &giayPhep.HoSo
@@ -389,6 +499,12 @@ func (s *giayPhepService) PushToBlockchain(ctx context.Context, giayPhepID uuid.
 	h1Str := *giayPhep.H1Hash
 	h2Str := *giayPhep.H2Hash
 
+	// Dấu thời gian RFC 3161 xác nhận thời điểm bộ (id, h1, h2) được đưa lên blockchain
+	token, err := s.tsa.Stamp(ctx, blockchainAnchorData(giayPhepIDStr, h1Str, h2Str))
+	if err != nil {
+		return err
+	}
+
 	_, err = s.fabricClient.SubmitTransaction(chaincodeFunc, giayPhepIDStr, h1Str, h2Str)
 
 	if err != nil {
@@ -400,8 +516,10 @@ func (s *giayPhepService) PushToBlockchain(ctx context.Context, giayPhepID uuid.
 		return fmt.Errorf("lỗi khi submit Fabric: %w", err)
 	}
 
-	statusSuccess := "TrangThaiBCDaDongBo"
+	statusSuccess := TrangThaiBCDaDongBo
 	giayPhep.TrangThaiBlockchain = &statusSuccess
+	dauThoiGian := base64.StdEncoding.EncodeToString(token)
+	giayPhep.DauThoiGianBlockchain = &dauThoiGian
 	if err := s.gpRepo.UpdateGiayPhep(ctx, s.db, giayPhep); err != nil {
 		return fmt.Errorf("fabric thành công, nhưng lỗi cập nhật CSDL: %w", err)
 	}
@@ -410,6 +528,28 @@ func (s *giayPhepService) PushToBlockchain(ctx context.Context, giayPhepID uuid.
 	return nil
 }
 
+// blockchainAnchorData là dữ liệu được đóng dấu thời gian khi đẩy lên blockchain
+func blockchainAnchorData(id, h1, h2 string) []byte {
+	return []byte(id + "|" + h1 + "|" + h2)
+}
+
+// verifyToken kiểm tra dấu thời gian (base64) cấp cho data và trả về thông tin hiển thị
+func (s *giayPhepService) verifyToken(tokenB64 string, data []byte) (*tsa.Token, *dto.DauThoiGianInfo, error) {
+	token, err := base64.StdEncoding.DecodeString(tokenB64)
+	if err != nil {
+		return nil, nil, tsa.ErrDauThoiGianKhongHopLe
+	}
+	tok, err := s.tsa.Verify(token, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tok, &dto.DauThoiGianInfo{
+		ThoiDiem:     tok.GenTime,
+		TSA:          tok.Certificate.Subject.CommonName,
+		SerialNumber: tok.SerialNumber.Text(16),
+	}, nil
+}
+
 func (s *giayPhepService) VerifyGiayPhep(ctx context.Context, giayPhepID uuid.UUID) (*dto.VerifyGiayPhepResponse, error) {
 	giayPhepDB, err := s.gpRepo.GetGiayPhepByID(ctx, s.db, giayPhepID)
 	if err != nil {
@@ -455,7 +595,17 @@ func (s *giayPhepService) VerifyGiayPhep(ctx context.Context, giayPhepID uuid.UU
 	resp.IsH1Matched = (resp.H1HashDB == resp.H1HashBC)
 	resp.IsH2Matched = (resp.H2HashDB == resp.H2HashBC)
 
-	if resp.IsH1Matched && resp.IsH2Matched {
+	// Giấy phép đẩy lên trước khi có dấu thời gian thì không có token để kiểm tra
+	resp.IsDauThoiGianHopLe = true
+	if giayPhepDB.DauThoiGianBlockchain != nil {
+		_, info, err := s.verifyToken(*giayPhepDB.DauThoiGianBlockchain, blockchainAnchorData(giayPhepIDStr, resp.H1HashDB, resp.H2HashDB))
+		resp.DauThoiGian = info
+		resp.IsDauThoiGianHopLe = err == nil
+	}
+
+	if resp.IsH1Matched && resp.IsH2Matched && !resp.IsDauThoiGianHopLe {
+		resp.Message = "XÁC THỰC THẤT BẠI! Dấu thời gian lúc đẩy lên Blockchain không hợp lệ."
+	} else if resp.IsH1Matched && resp.IsH2Matched {
 		resp.Message = "Xác thực thành công! Dữ liệu CSDL khớp với Blockchain."
 		giayPhepDTO, err := s.mapGiayPhepToResponse(ctx, giayPhepDB)
 		if err != nil {
@@ -470,69 +620,566 @@ func (s *giayPhepService) VerifyGiayPhep(ctx context.Context, giayPhepID uuid.UU
 	return resp, nil
 }
 
-func (s *giayPhepService) KySoGiayPhep(ctx context.Context, giayPhepID uuid.UUID, userID uuid.UUID) error {
-	// 1. Lấy thông tin Giấy phép
-	gp, err := s.gpRepo.GetGiayPhepByID(ctx, s.db, giayPhepID)
+func (s *giayPhepService) KySoGiayPhep(ctx context.Context, giayPhepID uuid.UUID, userID uuid.UUID, totpCode string, loaiChuKy string) (*dto.ChuKyResponse, error) {
+	if loaiChuKy == "" {
+		loaiChuKy = models.LoaiChuKyChinh
+	}
+
+	// Dòng giấy phép bị khóa đến hết transaction: người ký sau chờ người ký trước commit rồi mới đọc
+	// số chữ ký đã có, nên hai người không cùng thứ tự và không ghi đè file bản đã ký của nhau
+	kq := &ketQuaKySo{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.kySo(ctx, tx, kq, giayPhepID, userID, totpCode, loaiChuKy)
+	})
 	if err != nil {
-		return err
+		if kq.signedPhysical != "" {
+			os.Remove(kq.signedPhysical)
+		}
+		return nil, err
 	}
+	gp, user, chuKy := kq.gp, kq.user, kq.chuKy
 
-	// [LOG DEMO] Bắt đầu quy trình
-	fmt.Println("\n=======================================================")
-	fmt.Println(" BẮT ĐẦU QUY TRÌNH KÝ SỐ (DIGITAL SIGNATURE PROCESS)")
-	fmt.Println("=======================================================")
-	fmt.Printf(" Giấy phép ID: %s\n", gp.ID)
+	// Bản ký trung gian nằm trọn trong bản mới (phần đầu file), không cần giữ riêng
+	if kq.banKyTruoc != "" && kq.banKyTruoc != *gp.FileDuongDan {
+		if err := os.Remove(filepath.Join("..", filepath.FromSlash(kq.banKyTruoc))); err != nil && !os.IsNotExist(err) {
+			log.Printf("Cảnh báo: không xóa được bản ký trung gian %s: %v", kq.banKyTruoc, err)
+		}
+	}
 
-	// Validate trạng thái
-	if gp.TrangThaiGiayPhep == "DaKy" {
-		fmt.Println(" LỖI: Giấy phép này đã được ký trước đó.")
-		return errors.New("giấy phép này đã được ký rồi")
+	if loaiChuKy == models.LoaiChuKyChinh {
+		if err := s.approvals.GhiNhanKy(ctx, gp, kq.h2DaDuyet, user); err != nil {
+			log.Printf("Cảnh báo: không ghi được bước ký vào lịch sử phê duyệt của giấy phép %s: %v", gp.ID, err)
+		}
+	}
+
+	chuKy.NguoiKy = *user
+	resp := mapChuKy(chuKy)
+	return &resp, nil
+}
+
+// ketQuaKySo là những gì KySoGiayPhep cần sau transaction ký
+type ketQuaKySo struct {
+	gp         *models.GiayPhep
+	user       *models.User
+	chuKy      *models.ChuKy
+	h2DaDuyet  string
+	banKyTruoc string
+	// signedPhysical là file bản đã ký vừa ghi, bị xóa nếu transaction không commit
+	signedPhysical string
+}
+
+// kySo kiểm tra và ký giấy phép trong transaction tx, sau khi đã khóa dòng giấy phép
+func (s *giayPhepService) kySo(ctx context.Context, tx *gorm.DB, kq *ketQuaKySo, giayPhepID, userID uuid.UUID, totpCode, loaiChuKy string) error {
+	// 1. Khóa và đọc giấy phép cùng các chữ ký đã có
+	gp, err := s.gpRepo.GetGiayPhepForUpdate(ctx, tx, giayPhepID)
+	if err != nil {
+		return err
+	}
+
+	// Validate trạng thái: sau chữ ký chính không nhận thêm chữ ký nào. Xét theo các dòng chu_ky
+	// chứ không theo TrangThaiGiayPhep, để trạng thái có đổi thế nào cũng không ký chính được lần hai.
+	for _, ck := range gp.ChuKys {
+		if ck.LoaiChuKy == models.LoaiChuKyChinh {
+			return ErrGiayPhepDaKyChinh
+		}
+	}
+	if gp.TrangThaiGiayPhep == "ThuHoi" || gp.TrangThaiGiayPhep == "DaHetHan" {
+		return ErrGiayPhepDaBiThuHoi
 	}
 	if gp.H2Hash == nil || *gp.H2Hash == "" {
-		fmt.Println(" LỖI: Không tìm thấy H2Hash (Chưa upload file).")
 		return errors.New("giấy phép chưa có file đính kèm hoặc chưa tính H2Hash")
 	}
-
-	fmt.Printf(" Hash của tài liệu gốc (H2Hash): %s\n", *gp.H2Hash)
+	for _, ck := range gp.ChuKys {
+		if ck.NguoiKyID == userID {
+			return ErrDaKyGiayPhep
+		}
+	}
 
 	// 2. Lấy thông tin User & Key
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("yw Người ký: %s (Email: %s)\n", user.FullName, user.Email)
 
-	if user.PrivateKeyPEM == nil || *user.PrivateKeyPEM == "" {
-		fmt.Println(" LỖI: Cán bộ chưa có Private Key.")
-		return errors.New("tài khoản này chưa được cấu hình Chữ ký số")
+	// Chữ ký chính chỉ được ký khi đã qua đủ các bước phê duyệt của loại giấy phép, đúng chức vụ phụ trách bước ký.
+	// Các bước được duyệt trên nội dung trước khi có chữ ký nào (bản gốc nếu đã có ký nháy PAdES).
+	kq.h2DaDuyet = *gp.H2Hash
+	if gp.H2HashGoc != nil {
+		kq.h2DaDuyet = *gp.H2HashGoc
+	}
+	if loaiChuKy == models.LoaiChuKyChinh {
+		if err := s.approvals.KiemTraTruocKy(ctx, gp, user); err != nil {
+			return err
+		}
+	}
+
+	// Xác nhận lại bằng mã TOTP mới trước khi dùng private key. Kiểm tra sau khi giấy phép đã hợp lệ
+	// để yêu cầu bị từ chối vì trạng thái giấy phép không tiêu mã (mỗi mã chỉ dùng được một lần).
+	if err := s.stepUp.VerifyStepUp(userID, totpCode); err != nil {
+		return err
+	}
+
+	// Chọn signer theo cấu hình của cán bộ (khóa CSDL hoặc HSM)
+	sgn, err := s.signers.ForUser(user)
+	if err != nil {
+		return err
 	}
 
-	// [LOG DEMO] Giả lập hiển thị key (chỉ hiện 1 phần để bảo mật)
-	fmt.Println(" Đã tải Private Key của cán bộ (RSA 2048-bit).")
-	fmt.Println("   --> Key prefix: " + (*user.PrivateKeyPEM)[0:50] + "...")
+	publicKeyPEM, err := signer.PublicKeyPEM(sgn)
+	if err != nil {
+		return err
+	}
 
-	// 3. Thực hiện Ký số
-	fmt.Println("  Đang thực hiện thuật toán ký RSA...")
-	signature, err := utils.SignHash(*gp.H2Hash, *user.PrivateKeyPEM)
+	// Chữ ký chỉ có giá trị khi khóa ký được CA nội bộ chứng thực cho đúng người ký
+	chungThu, err := s.ca.FindForKey(userID, sgn.Public(), time.Now())
 	if err != nil {
 		return err
 	}
 
-	// [LOG DEMO] Kết quả
-	fmt.Println(" Ký thành công!")
-	fmt.Printf("hm Chữ ký số sinh ra (Base64): %s...\n", signature[0:60]) // In 60 ký tự đầu
+	// 3. Đọc file đang phát hành (đã mang các chữ ký trước, nếu có) và xác nhận chưa bị sửa kể từ khi tính H2Hash
+	if gp.FileDuongDan == nil || *gp.FileDuongDan == "" {
+		return errors.New("giấy phép chưa có file đính kèm")
+	}
+	hienTai, err := os.ReadFile(filepath.Join("..", filepath.FromSlash(*gp.FileDuongDan)))
+	if err != nil {
+		return fmt.Errorf("không đọc được file giấy phép: %w", err)
+	}
+	if hashHienTai := sha256.Sum256(hienTai); hex.EncodeToString(hashHienTai[:]) != *gp.H2Hash {
+		return ErrFileGiayPhepBiThayDoi
+	}
+
+	leafCert, err := ca.ParseCertPEM(chungThu.CertPEM)
+	if err != nil {
+		return err
+	}
+	caCert, err := ca.ParseCertPEM(chungThu.ChainPEM)
+	if err != nil {
+		return err
+	}
+	chain := []*x509.Certificate{caCert}
 
-	// 4. Cập nhật DB
 	now := time.Now()
-	gp.ChuKySo = &signature
-	gp.NguoiKyID = &userID
-	gp.NgayKy = &now
-	gp.PublicKeyNguoiKy = user.PublicKeyPEM
-	gp.TrangThaiGiayPhep = "DaKy"
+	thuTu := len(gp.ChuKys) + 1
+	banPhatHanh := hienTai
+	// Dấu thời gian RFC 3161 trên giá trị chữ ký, nhúng vào CMS (PAdES-B-T / CAdES-T)
+	timestamper := func(signature []byte) ([]byte, error) {
+		return s.tsa.Stamp(ctx, signature)
+	}
+	h2PhatHanh := *gp.H2Hash
+	var signedDBPath string
+
+	// 3a. File PDF: nhúng chữ ký PAdES bằng một bản cập nhật tăng dần (giữ nguyên các chữ ký trước),
+	// lưu thành phiên bản mới và tính lại H2Hash. Các định dạng khác giữ nguyên file, chỉ có chữ ký CMS rời (.p7s).
+	if pades.IsPDF(hienTai) {
+		lyDo := "Ký số giấy phép " + gp.SoGiayPhep
+		if loaiChuKy == models.LoaiChuKyNhay {
+			lyDo = "Ký nháy giấy phép " + gp.SoGiayPhep
+		}
+		daKy, err := pades.Sign(hienTai, pades.Options{
+			Name:        user.FullName,
+			Reason:      lyDo,
+			ContactInfo: user.Email,
+			SigningTime: now,
+		}, func(digest []byte) ([]byte, error) {
+			return cms.SignDigest(digest, sgn, leafCert, chain, cms.SignOptions{Timestamper: timestamper})
+		})
+		if err != nil {
+			return err
+		}
+
+		goc := *gp.FileDuongDan
+		if gp.FileGocDuongDan != nil {
+			goc = *gp.FileGocDuongDan
+		}
+		signedDBPath = signedVersionPath(goc, thuTu)
+		kq.signedPhysical = filepath.Join("..", filepath.FromSlash(signedDBPath))
+		if err := os.WriteFile(kq.signedPhysical, daKy, 0o644); err != nil {
+			return fmt.Errorf("lỗi lưu file đã ký: %w", err)
+		}
+		hashDaKy := sha256.Sum256(daKy)
+		banPhatHanh = daKy
+		h2PhatHanh = hex.EncodeToString(hashDaKy[:])
+	}
+	// 3b. Chữ ký CMS rời (.p7s) trên toàn bộ file phát hành, có signing-time, kiểm tra được bằng openssl
+	contentDigest := sha256.Sum256(banPhatHanh)
+	p7s, err := cms.SignDigest(contentDigest[:], sgn, leafCert, chain, cms.SignOptions{SigningTime: now, Timestamper: timestamper})
+	if err != nil {
+		return err
+	}
+	chuKyCMS := base64.StdEncoding.EncodeToString(p7s)
+
+	// 4. Chữ ký rời RSA PKCS#1 v1.5 trên SHA-256(H2Hash dạng hex) - giữ nguyên định dạng cũ
+	digest := sha256.Sum256([]byte(h2PhatHanh))
+	rawSig, err := sgn.SignDigest(digest[:], crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	signature := base64.StdEncoding.EncodeToString(rawSig)
 
-	err = s.gpRepo.UpdateGiayPhep(ctx, s.db, gp)
-	if err == nil {
-		fmt.Println(" Đã lưu chữ ký và cập nhật trạng thái vào Database.")
+	// 4a. Dấu thời gian trên chữ ký rời: NgayKy lấy theo genTime của TSA thay vì đồng hồ máy chủ
+	token, err := s.tsa.Stamp(ctx, rawSig)
+	if err != nil {
+		return err
+	}
+	tok, err := s.tsa.Verify(token, rawSig)
+	if err != nil {
+		return err
+	}
+	dauThoiGian := base64.StdEncoding.EncodeToString(token)
+
+	chuKy := &models.ChuKy{
+		GiayPhepID:       gp.ID,
+		ThuTu:            thuTu,
+		LoaiChuKy:        loaiChuKy,
+		NguoiKyID:        userID,
+		NgayKy:           tok.GenTime,
+		H2Hash:           h2PhatHanh,
+		ChuKySo:          signature,
+		PublicKeyNguoiKy: &publicKeyPEM,
+		ChungThuID:       &chungThu.ID,
+		ChuKyCMS:         &chuKyCMS,
+		DauThoiGian:      &dauThoiGian,
+		NhungPDF:         signedDBPath != "",
+	}
+
+	// 5. Cập nhật DB: với PDF, bản gốc được giữ lại (từ chữ ký đầu tiên) và file phát hành là bản mang chữ ký mới nhất
+	if signedDBPath != "" {
+		if gp.FileGocDuongDan == nil {
+			gp.FileGocDuongDan = gp.FileDuongDan
+			gp.H2HashGoc = gp.H2Hash
+		} else {
+			kq.banKyTruoc = *gp.FileDuongDan
+		}
+		gp.FileDuongDan = &signedDBPath
+		gp.H2Hash = &h2PhatHanh
+	}
+	if loaiChuKy == models.LoaiChuKyChinh {
+		gp.TrangThaiGiayPhep = "DaKy"
+	}
+
+	if err := s.gpRepo.CreateChuKy(ctx, tx, chuKy); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrChuKyDongThoi
+		}
+		return err
+	}
+	if err := s.gpRepo.UpdateGiayPhep(ctx, tx, gp); err != nil {
+		return err
+	}
+	kq.gp, kq.user, kq.chuKy = gp, user, chuKy
+	return nil
+}
+
+// signedVersionPath đặt bản đã ký cạnh bản gốc: <tên gốc>_da_ky.pdf cho chữ ký đầu tiên,
+// <tên gốc>_da_ky_<n>.pdf cho chữ ký thứ n
+func signedVersionPath(dbPath string, thuTu int) string {
+	base := strings.TrimSuffix(dbPath, path.Ext(dbPath)) + "_da_ky"
+	if thuTu > 1 {
+		base += fmt.Sprintf("_%d", thuTu)
+	}
+	return base + ".pdf"
+}
+
+func mapChuKy(ck *models.ChuKy) dto.ChuKyResponse {
+	resp := dto.ChuKyResponse{
+		ID:            ck.ID,
+		ThuTu:         ck.ThuTu,
+		LoaiChuKy:     ck.LoaiChuKy,
+		NguoiKy:       &dto.NguoiKyInfo{ID: ck.NguoiKyID.String(), HoTen: ck.NguoiKy.FullName},
+		NgayKy:        ck.NgayKy,
+		H2Hash:        ck.H2Hash,
+		ChungThuID:    ck.ChungThuID,
+		NhungPDF:      ck.NhungPDF,
+		CoDauThoiGian: ck.DauThoiGian != nil,
+	}
+	if ck.PublicKeyNguoiKy != nil {
+		if pub, err := signer.ParsePublicKeyPEM(*ck.PublicKeyNguoiKy); err == nil {
+			resp.NguoiKy.KeyID = signer.Fingerprint(pub)
+		}
+	}
+	return resp
+}
+
+// VerifyChuKy kiểm tra file đang phát hành (còn trên đĩa, hash khớp H2Hash, là bản mang chữ ký cuối cùng)
+// rồi kiểm tra độc lập từng chữ ký theo thứ tự ký. Xem verifyMotChuKy cho các bước với mỗi chữ ký.
+func (s *giayPhepService) VerifyChuKy(ctx context.Context, giayPhepID uuid.UUID) (*dto.VerifySignatureResponse, error) {
+	gp, err := s.gpRepo.GetGiayPhepByID(ctx, s.db, giayPhepID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGiayPhepKhongTimThay
+		}
+		return nil, err
+	}
+	// Endpoint công khai cho bên thứ ba (không có Actor) nên không áp phạm vi doanh nghiệp
+
+	resp := &dto.VerifySignatureResponse{GiayPhepID: gp.ID.String(), ChuKy: []dto.KetQuaChuKy{}}
+	fail := func(check, message string) (*dto.VerifySignatureResponse, error) {
+		resp.FailedCheck = check
+		resp.Message = message
+		return resp, nil
+	}
+
+	if gp.H2Hash != nil {
+		resp.H2HashDB = *gp.H2Hash
+	}
+	if n := len(gp.ChuKys); n > 0 {
+		last := gp.ChuKys[n-1]
+		resp.NgayKy = &last.NgayKy
+		resp.NguoiKy = &dto.NguoiKyInfo{ID: last.NguoiKyID.String(), HoTen: last.NguoiKy.FullName}
+	}
+
+	// 1. Đã ký chưa
+	if len(gp.ChuKys) == 0 || gp.H2Hash == nil || *gp.H2Hash == "" {
+		return fail(dto.KiemTraDaKy, "Giấy phép chưa được ký số")
+	}
+
+	// 2. File đang phát hành còn tồn tại
+	if gp.FileDuongDan == nil || *gp.FileDuongDan == "" {
+		return fail(dto.KiemTraFileTonTai, "Giấy phép không có file đính kèm")
+	}
+	data, err := os.ReadFile(filepath.Join("..", filepath.FromSlash(*gp.FileDuongDan)))
+	if err != nil {
+		return fail(dto.KiemTraFileTonTai, "Không đọc được file giấy phép trên máy chủ")
+	}
+	sum := sha256.Sum256(data)
+	computed := hex.EncodeToString(sum[:])
+	resp.H2HashComputed = computed
+
+	// 3. File chưa bị sửa kể từ lúc tính H2Hash và đúng là bản mang chữ ký cuối cùng
+	if computed != *gp.H2Hash {
+		return fail(dto.KiemTraHashFile, "File giấy phép đã bị thay đổi (hash không khớp H2Hash)")
+	}
+	if gp.ChuKys[len(gp.ChuKys)-1].H2Hash != computed {
+		return fail(dto.KiemTraHashFile, "File đang phát hành không phải bản mang chữ ký cuối cùng")
+	}
+
+	// Các chữ ký PAdES nhúng trong PDF, mỗi chữ ký phủ một bản sửa đổi (phần đầu file)
+	var pdfSigs []pades.Signature
+	for _, ck := range gp.ChuKys {
+		if ck.NhungPDF {
+			if pdfSigs, err = pades.ExtractAll(data); err != nil {
+				return fail(dto.KiemTraPAdES, "Chữ ký nhúng trong PDF không hợp lệ: "+err.Error())
+			}
+			break
+		}
+	}
+
+	// 4. Kiểm tra từng chữ ký; kết quả chung lấy theo chữ ký thất bại đầu tiên, hoặc chữ ký cuối cùng
+	var chon *dto.KetQuaChuKy
+	for i := range gp.ChuKys {
+		resp.ChuKy = append(resp.ChuKy, s.verifyMotChuKy(&gp.ChuKys[i], data, computed, pdfSigs))
+	}
+	for i := range resp.ChuKy {
+		if !resp.ChuKy[i].IsValid {
+			chon = &resp.ChuKy[i]
+			break
+		}
+	}
+	if chon == nil {
+		chon = &resp.ChuKy[len(resp.ChuKy)-1]
+	}
+	resp.NguoiKy = chon.NguoiKy
+	resp.NgayKy = chon.NgayKy
+	resp.DauThoiGian = chon.DauThoiGian
+	resp.ChungThu = chon.ChungThu
+	resp.ChuKyPDF = chon.ChuKyPDF
+
+	if !chon.IsValid {
+		if len(resp.ChuKy) > 1 {
+			return fail(chon.FailedCheck, fmt.Sprintf("Chữ ký thứ %d: %s", chon.ThuTu, chon.Message))
+		}
+		return fail(chon.FailedCheck, chon.Message)
+	}
+
+	resp.IsValid = true
+	resp.Message = "Chữ ký số hợp lệ, file giấy phép toàn vẹn, chứng thư số hợp lệ tại thời điểm ký"
+	if len(resp.ChuKy) > 1 {
+		resp.Message = fmt.Sprintf("Cả %d chữ ký số đều hợp lệ, file giấy phép toàn vẹn, chứng thư số hợp lệ tại thời điểm ký", len(resp.ChuKy))
+	}
+	return resp, nil
+}
+
+// verifyMotChuKy kiểm tra một chữ ký theo thứ tự: tìm đúng bản file mà chữ ký phủ
+// -> chữ ký RSA khớp public key đã chụp lại lúc ký -> dấu thời gian RFC 3161 xác nhận NgayKy
+// -> chứng thư số hợp lệ tại NgayKy
+// -> chữ ký PAdES nhúng trong PDF và chữ ký CMS rời do đúng chứng thư đó ký.
+// Dừng ở bước đầu tiên thất bại.
+func (s *giayPhepService) verifyMotChuKy(ck *models.ChuKy, data []byte, computed string, pdfSigs []pades.Signature) dto.KetQuaChuKy {
+	ngayKy := ck.NgayKy
+	kq := dto.KetQuaChuKy{
+		ThuTu:     ck.ThuTu,
+		LoaiChuKy: ck.LoaiChuKy,
+		H2Hash:    ck.H2Hash,
+		NgayKy:    &ngayKy,
+		NguoiKy:   &dto.NguoiKyInfo{ID: ck.NguoiKyID.String(), HoTen: ck.NguoiKy.FullName},
+	}
+	fail := func(check, message string) dto.KetQuaChuKy {
+		kq.FailedCheck = check
+		kq.Message = message
+		return kq
+	}
+
+	// 1. Bản file mà chữ ký phủ: với PDF là bản sửa đổi có hash bằng H2Hash của chữ ký,
+	// với file khác (không nhúng được chữ ký) là chính file đang phát hành
+	var pdfSig *pades.Signature
+	if ck.NhungPDF {
+		for i := range pdfSigs {
+			prefix := sha256.Sum256(data[:pdfSigs[i].End])
+			if hex.EncodeToString(prefix[:]) == ck.H2Hash {
+				pdfSig = &pdfSigs[i]
+				break
+			}
+		}
+		if pdfSig == nil {
+			return fail(dto.KiemTraHashFile, "Không tìm thấy trong file giấy phép bản sửa đổi mà chữ ký này phủ")
+		}
+	} else if ck.H2Hash != computed {
+		return fail(dto.KiemTraHashFile, "File giấy phép không còn là bản mà chữ ký này phủ")
+	}
+
+	// 2. Chữ ký hợp lệ với public key tại thời điểm ký
+	if ck.PublicKeyNguoiKy == nil || *ck.PublicKeyNguoiKy == "" {
+		return fail(dto.KiemTraPublicKey, "Không có public key của người ký")
+	}
+	pub, err := signer.ParsePublicKeyPEM(*ck.PublicKeyNguoiKy)
+	if err != nil {
+		return fail(dto.KiemTraPublicKey, "Public key của người ký không hợp lệ")
+	}
+	kq.NguoiKy.KeyID = signer.Fingerprint(pub)
+
+	sig, err := base64.StdEncoding.DecodeString(ck.ChuKySo)
+	if err != nil {
+		return fail(dto.KiemTraChuKy, "Chữ ký số không đúng định dạng")
+	}
+	digest := sha256.Sum256([]byte(ck.H2Hash))
+	if err := signer.VerifyDigest(pub, crypto.SHA256, digest[:], sig); err != nil {
+		return fail(dto.KiemTraChuKy, "Chữ ký số không hợp lệ")
+	}
+
+	// 2a. Dấu thời gian trên chữ ký: do TSA tin cậy cấp và khớp NgayKy.
+	// Chữ ký trước khi hỗ trợ dấu thời gian chỉ có NgayKy của máy chủ nên bỏ qua bước này.
+	if ck.DauThoiGian != nil {
+		tok, info, err := s.verifyToken(*ck.DauThoiGian, sig)
+		if err != nil {
+			return fail(dto.KiemTraDauThoiGian, "Dấu thời gian của chữ ký không hợp lệ: "+err.Error())
+		}
+		kq.DauThoiGian = info
+		if tok.GenTime.Sub(ck.NgayKy).Abs() > time.Second {
+			return fail(dto.KiemTraDauThoiGian, "Ngày ký không khớp dấu thời gian do TSA xác nhận")
+		}
+	}
+
+	// 3. Chứng thư số: đúng khóa đã ký, chuỗi tới CA nội bộ và chưa bị thu hồi tại NgayKy
+	if ck.ChungThuID == nil {
+		return fail(dto.KiemTraChungThu, "Chữ ký không gắn với chứng thư số nào")
+	}
+	chungThu, err := s.ca.GetByID(*ck.ChungThuID)
+	if err != nil {
+		return fail(dto.KiemTraChungThu, "Không tìm thấy chứng thư số đã dùng để ký")
+	}
+	kq.ChungThu = &dto.ChungThuInfo{
+		SerialNumber: chungThu.SerialNumber,
+		SubjectCN:    chungThu.SubjectCN,
+		Email:        chungThu.Email,
+		NotBefore:    chungThu.NotBefore,
+		NotAfter:     chungThu.NotAfter,
+		RevokedAt:    chungThu.RevokedAt,
+	}
+	if fingerprint, err := ca.PublicKeyFingerprint(pub); err != nil || fingerprint != chungThu.PublicKeyFingerprint {
+		return fail(dto.KiemTraChungThu, "Chứng thư số không khớp với khóa đã ký")
+	}
+	cert, err := s.ca.VerifyAt(chungThu, ck.NgayKy)
+	if cert != nil {
+		kq.ChungThu.Issuer = cert.Issuer.CommonName
+	}
+	if err != nil {
+		if errors.Is(err, ca.ErrChungThuBiThuHoi) {
+			return fail(dto.KiemTraChungThu, "Chứng thư số đã bị thu hồi trước thời điểm ký")
+		}
+		return fail(dto.KiemTraChungThu, "Chứng thư số không hợp lệ tại thời điểm ký")
+	}
+
+	// 4. Chữ ký PAdES nhúng trong PDF: phủ đúng bản sửa đổi và do đúng chứng thư trên ký
+	if pdfSig != nil {
+		signerInfo, err := cms.VerifyDigest(pdfSig.CMS, pdfSig.Digest)
+		if err != nil {
+			return fail(dto.KiemTraPAdES, "Chữ ký nhúng trong PDF không hợp lệ: "+err.Error())
+		}
+		kq.ChuKyPDF = &dto.ChuKyPDFInfo{
+			SubjectCN:    signerInfo.Certificate.Subject.CommonName,
+			SerialNumber: signerInfo.Certificate.SerialNumber.Text(16),
+		}
+		if cert == nil || !signerInfo.Certificate.Equal(cert) {
+			return fail(dto.KiemTraPAdES, "Chữ ký nhúng trong PDF không dùng chứng thư số đã ghi nhận")
+		}
+		if msg := s.checkEmbeddedToken(ck, signerInfo); msg != "" {
+			return fail(dto.KiemTraPAdES, "Chữ ký nhúng trong PDF: "+msg)
+		}
+	}
+
+	// 5. Chữ ký CMS rời (.p7s) trên bản file mà chữ ký phủ (không có với chữ ký trước khi hỗ trợ .p7s)
+	if ck.ChuKyCMS != nil {
+		p7s, err := base64.StdEncoding.DecodeString(*ck.ChuKyCMS)
+		if err != nil {
+			return fail(dto.KiemTraCMS, "Chữ ký CMS không đúng định dạng")
+		}
+		fileDigest, err := hex.DecodeString(ck.H2Hash)
+		if err != nil {
+			return fail(dto.KiemTraCMS, "H2Hash của chữ ký không hợp lệ")
+		}
+		signerInfo, err := cms.VerifyDigest(p7s, fileDigest)
+		if err != nil {
+			return fail(dto.KiemTraCMS, "Chữ ký CMS không hợp lệ: "+err.Error())
+		}
+		if cert == nil || !signerInfo.Certificate.Equal(cert) {
+			return fail(dto.KiemTraCMS, "Chữ ký CMS không dùng chứng thư số đã ghi nhận")
+		}
+		if msg := s.checkEmbeddedToken(ck, signerInfo); msg != "" {
+			return fail(dto.KiemTraCMS, "Chữ ký CMS: "+msg)
+		}
+	}
+
+	kq.IsValid = true
+	kq.Message = "Chữ ký hợp lệ"
+	return kq
+}
+
+// checkEmbeddedToken kiểm tra dấu thời gian nhúng trong CMS (unsigned attribute signature-time-stamp).
+// Chữ ký đã có dấu thời gian trên chữ ký rời thì CMS cũng bắt buộc phải có. Trả về "" nếu hợp lệ.
+func (s *giayPhepService) checkEmbeddedToken(ck *models.ChuKy, signerInfo *cms.SignerResult) string {
+	if signerInfo.TimestampToken == nil {
+		if ck.DauThoiGian != nil {
+			return "thiếu dấu thời gian RFC 3161"
+		}
+		return ""
+	}
+	tok, err := s.tsa.Verify(signerInfo.TimestampToken, signerInfo.Signature)
+	if err != nil {
+		return "dấu thời gian không hợp lệ: " + err.Error()
+	}
+	if tok.GenTime.Sub(ck.NgayKy).Abs() > time.Minute {
+		return "dấu thời gian lệch quá xa ngày ký"
+	}
+	return ""
+}
+
+// GetChuKyCMS trả về chữ ký CMS detached (DER) của chữ ký cuối cùng, tức chữ ký phủ file giấy phép đang phát hành
+func (s *giayPhepService) GetChuKyCMS(ctx context.Context, giayPhepID uuid.UUID) ([]byte, error) {
+	gp, err := s.gpRepo.GetGiayPhepByID(ctx, s.db, giayPhepID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGiayPhepKhongTimThay
+		}
+		return nil, err
+	}
+	// Endpoint công khai như VerifyChuKy, không áp phạm vi doanh nghiệp
+	if len(gp.ChuKys) == 0 {
+		return nil, ErrChuaCoChuKyCMS
+	}
+	last := gp.ChuKys[len(gp.ChuKys)-1]
+	if last.ChuKyCMS == nil || *last.ChuKyCMS == "" {
+		return nil, ErrChuaCoChuKyCMS
 	}
-	return err
+	return base64.StdEncoding.DecodeString(*last.ChuKyCMS)
 }
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/policy"
+	"github.com/vnkmasc/KmaERM/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// suKienHoSo là dữ liệu của một dòng nhật ký hồ sơ; truoc/sau được ghi dưới dạng JSON
+type suKienHoSo struct {
+	loai           string
+	trangThaiTruoc string
+	trangThaiSau   string
+	truoc          any
+	sau            any
+	ghiChu         string
+	// heThong: sự kiện do hệ thống tự thực hiện (ví dụ phân công tự động), không gắn người thực hiện
+	heThong bool
+}
+
+// ghiLichSuHoSo ghi một sự kiện vào nhật ký hồ sơ, người thực hiện lấy từ actor của request.
+// Nên gọi trong cùng transaction với thay đổi để nhật ký không lệch với dữ liệu.
+func ghiLichSuHoSo(ctx context.Context, db *gorm.DB, repo repository.HoSoLichSuRepository, hoSoID uuid.UUID, sk suKienHoSo) error {
+	row := &models.HoSoLichSu{
+		HoSoID:         hoSoID,
+		LoaiSuKien:     sk.loai,
+		TrangThaiTruoc: optionalString(sk.trangThaiTruoc),
+		TrangThaiSau:   optionalString(sk.trangThaiSau),
+		GhiChu:         optionalString(sk.ghiChu),
+	}
+	if actor, ok := policy.ActorFrom(ctx); ok && actor.UserID != uuid.Nil && !sk.heThong {
+		row.NguoiThucHienID = &actor.UserID
+	}
+	var err error
+	if row.DuLieuTruoc, err = toJSON(sk.truoc); err != nil {
+		return err
+	}
+	if row.DuLieuSau, err = toJSON(sk.sau); err != nil {
+		return err
+	}
+	return repo.Create(ctx, db, row)
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func toJSON(v any) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := string(b)
+	return &s, nil
+}
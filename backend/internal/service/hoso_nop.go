@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"gorm.io/gorm"
+)
+
+var ErrHoSoChuaDayDu = errors.New("hồ sơ chưa đủ tài liệu bắt buộc")
+
+// HoSoChuaDayDuError liệt kê các khe tài liệu bắt buộc còn trống khi doanh nghiệp nộp hồ sơ
+type HoSoChuaDayDuError struct {
+	TaiLieus []dto.TaiLieuConThieuResponse
+}
+
+func (e *HoSoChuaDayDuError) Error() string {
+	ten := make([]string, len(e.TaiLieus))
+	for i, tl := range e.TaiLieus {
+		ten[i] = tl.TenLoaiTaiLieu
+	}
+	return fmt.Sprintf("%s: %s", ErrHoSoChuaDayDu.Error(), strings.Join(ten, "; "))
+}
+
+func (e *HoSoChuaDayDuError) Unwrap() error {
+	return ErrHoSoChuaDayDu
+}
+
+// kiemTraDayDu: mọi khe tài liệu bắt buộc phải có ít nhất một tài liệu (gọi trong transaction đã khóa hồ sơ)
+func (s *hoSoService) kiemTraDayDu(ctx context.Context, tx *gorm.DB, hoSoID uuid.UUID) error {
+	conTrong, err := s.tailieuRepo.ListKheBatBuocConTrong(ctx, tx, hoSoID)
+	if err != nil {
+		return fmt.Errorf("lỗi khi kiểm tra thành phần hồ sơ: %w", err)
+	}
+	if len(conTrong) == 0 {
+		return nil
+	}
+	thieu := make([]dto.TaiLieuConThieuResponse, len(conTrong))
+	for i, khe := range conTrong {
+		thieu[i] = dto.TaiLieuConThieuResponse{
+			HoSoTaiLieuID:  khe.ID,
+			LoaiTaiLieuID:  khe.LoaiTaiLieuID,
+			TenLoaiTaiLieu: khe.LoaiTaiLieu.Ten,
+		}
+	}
+	return &HoSoChuaDayDuError{TaiLieus: thieu}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/policy"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCanBoKhongHopLe        = errors.New("người được phân công phải là cán bộ đang hoạt động")
+	ErrDaPhanCongCanBoNay     = errors.New("hồ sơ đã được phân công cho cán bộ này")
+	ErrKhongThePhanCong       = errors.New("hồ sơ đã duyệt, không thể phân công lại")
+	ErrTrangThaiLocKhongHopLe = errors.New("trạng thái lọc không hợp lệ")
+)
+
+// Chiến lược phân công tự động khi hồ sơ được tiếp nhận, cấu hình qua HO_SO_PHAN_CONG
+const (
+	PhanCongItViecNhat = "least-loaded"
+	PhanCongXoayVong   = "round-robin"
+	// Chỉ phân công thủ công
+	PhanCongThuCong = "manual"
+)
+
+// trangThaiChuaXong: hồ sơ còn nằm trong hàng đợi của cán bộ xử lý
+var trangThaiChuaXong = []string{TrangThaiHoSoDaTiepNhan, TrangThaiHoSoDangXuLy, TrangThaiHoSoBiTraLai}
+
+// chienLuocPhanCong đọc HO_SO_PHAN_CONG; mặc định giao cho cán bộ ít việc nhất
+func chienLuocPhanCong() string {
+	switch v := os.Getenv("HO_SO_PHAN_CONG"); v {
+	case PhanCongXoayVong, PhanCongThuCong:
+		return v
+	default:
+		return PhanCongItViecNhat
+	}
+}
+
+// tuDongPhanCong giao hồ sơ vừa tiếp nhận cho một cán bộ theo chiến lược cấu hình (gọi trong transaction).
+// Hồ sơ đã có người xử lý, hoặc không còn cán bộ nào hoạt động, thì giữ nguyên.
+func (s *hoSoService) tuDongPhanCong(ctx context.Context, tx *gorm.DB, hoSo *models.HoSo) error {
+	chienLuoc := chienLuocPhanCong()
+	if hoSo.CanBoXuLyID != nil || chienLuoc == PhanCongThuCong {
+		return nil
+	}
+	// Hai hồ sơ tiếp nhận đồng thời không được cùng chọn một cán bộ theo số liệu cũ
+	if err := s.phanCongRepo.KhoaPhanCong(ctx, tx); err != nil {
+		return err
+	}
+
+	var canBoID uuid.UUID
+	var err error
+	if chienLuoc == PhanCongXoayVong {
+		canBoID, err = s.phanCongRepo.ChonCanBoXoayVong(ctx, tx)
+	} else {
+		canBoID, err = s.phanCongRepo.ChonCanBoItViecNhat(ctx, tx, trangThaiChuaXong)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("lỗi khi chọn cán bộ xử lý: %w", err)
+	}
+
+	now := time.Now()
+	hoSo.CanBoXuLyID = &canBoID
+	hoSo.NgayPhanCong = &now
+	return ghiLichSuHoSo(ctx, tx, s.lichSuRepo, hoSo.ID, suKienHoSo{
+		loai:    models.SuKienPhanCong,
+		sau:     map[string]any{"can_bo_xu_ly_id": canBoID, "chien_luoc": chienLuoc},
+		ghiChu:  "Phân công tự động khi tiếp nhận",
+		heThong: true,
+	})
+}
+
+// PhanCong giao (hoặc giao lại) hồ sơ cho một cán bộ. Giao lại cần lý do và được ghi vào nhật ký.
+func (s *hoSoService) PhanCong(ctx context.Context, hoSoID uuid.UUID, req *dto.PhanCongHoSoRequest) (*models.HoSo, error) {
+	lyDo := strings.TrimSpace(req.LyDo)
+
+	var hoSo *models.HoSo
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		hoSo, err = s.hosoRepo.GetHoSoForUpdate(ctx, tx, hoSoID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrHoSoKhongTimThay
+			}
+			return err
+		}
+		if hoSo.TrangThaiHoSo == TrangThaiHoSoDaDuyet || hoSo.TrangThaiHoSo == TrangThaiHoSoDaCapPhep {
+			return ErrKhongThePhanCong
+		}
+
+		canBo, err := s.phanCongRepo.GetCanBo(ctx, tx, req.CanBoID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCanBoKhongHopLe
+			}
+			return err
+		}
+		if !canBo.IsActive || !slices.Contains(canBoRoles, canBo.Role.Name) {
+			return ErrCanBoKhongHopLe
+		}
+
+		truoc := hoSo.CanBoXuLyID
+		if truoc != nil {
+			if *truoc == canBo.ID {
+				return ErrDaPhanCongCanBoNay
+			}
+			if lyDo == "" {
+				return ErrThieuLyDo
+			}
+		}
+
+		now := time.Now()
+		hoSo.CanBoXuLyID = &canBo.ID
+		hoSo.NgayPhanCong = &now
+		if err := s.hosoRepo.UpdateHoSo(ctx, tx, hoSo); err != nil {
+			return err
+		}
+
+		sk := suKienHoSo{
+			loai:   models.SuKienPhanCong,
+			sau:    map[string]any{"can_bo_xu_ly_id": canBo.ID},
+			ghiChu: lyDo,
+		}
+		if truoc != nil {
+			sk.truoc = map[string]any{"can_bo_xu_ly_id": *truoc}
+		}
+		return ghiLichSuHoSo(ctx, tx, s.lichSuRepo, hoSo.ID, sk)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hoSo, nil
+}
+
+// ListHoSoCuaToi là hàng đợi của cán bộ đang đăng nhập, hạn trả sớm nhất trước.
+// trangThai rỗng: mọi hồ sơ chưa giải quyết xong.
+func (s *hoSoService) ListHoSoCuaToi(ctx context.Context, trangThai string, page int, pageSize int) (*dto.HoSoListResponse, error) {
+	actor, _ := policy.ActorFrom(ctx)
+	if actor.RoleName == middleware.RoleDoanhNghiep {
+		return nil, ErrKhongCoQuyenThaoTac
+	}
+
+	locTrangThai := trangThaiChuaXong
+	if trangThai != "" {
+		if !slices.Contains(trangThaiChuaXong, trangThai) && trangThai != TrangThaiHoSoDaDuyet && trangThai != TrangThaiHoSoDaCapPhep {
+			return nil, ErrTrangThaiLocKhongHopLe
+		}
+		locTrangThai = []string{trangThai}
+	}
+
+	hoSos, total, err := s.hosoRepo.ListHoSoCuaCanBo(ctx, s.db, actor.UserID, locTrangThai, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi lấy hồ sơ được phân công: %w", err)
+	}
+
+	data := make([]dto.HoSoDetailsResponse, len(hoSos))
+	for i := range hoSos {
+		data[i] = dto.ToHoSoDetailsResponse(&hoSos[i])
+	}
+	return &dto.HoSoListResponse{
+		Data:     data,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	}, nil
+}
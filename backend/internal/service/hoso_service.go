@@ -12,6 +12,7 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/vnkmasc/KmaERM/backend/internal/dto"
 	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/policy"
 	"github.com/vnkmasc/KmaERM/backend/internal/repository"
 	"gorm.io/gorm"
 )
@@ -36,48 +37,82 @@ type HoSoService interface {
 	UpdateHoSo(ctx context.Context, hoSoID uuid.UUID, req *dto.UpdateHoSoRequest) (*models.HoSo, error)
 	GetLoaiTaiLieu(ctx context.Context, tenThuTuc string) (any, error)
 	DeleteHoSo(ctx context.Context, hoSoID uuid.UUID) error
+	ChuyenTrangThai(ctx context.Context, hoSoID uuid.UUID, thaoTac string, req *dto.ChuyenTrangThaiHoSoRequest) (*models.HoSo, error)
+	GetLichSu(ctx context.Context, hoSoID uuid.UUID) ([]dto.HoSoLichSuResponse, error)
+	GetYeuCauBoSung(ctx context.Context, hoSoID uuid.UUID) ([]dto.YeuCauBoSungResponse, error)
+	PhanCong(ctx context.Context, hoSoID uuid.UUID, req *dto.PhanCongHoSoRequest) (*models.HoSo, error)
+	ListHoSoCuaToi(ctx context.Context, trangThai string, page int, pageSize int) (*dto.HoSoListResponse, error)
 }
 
 type hoSoService struct {
-	db          *gorm.DB
-	hosoRepo    repository.HoSoRepository
-	tailieuRepo repository.TaiLieuRepository
+	db           *gorm.DB
+	hosoRepo     repository.HoSoRepository
+	tailieuRepo  repository.TaiLieuRepository
+	lichSuRepo   repository.HoSoLichSuRepository
+	yeuCauRepo   repository.YeuCauBoSungRepository
+	hanXuLy      HanXuLy
+	phanCongRepo repository.PhanCongRepository
+	thuTucRepo   repository.ThuTucRepository
+	danhSo       DanhSo
 }
 
 func NewHoSoService(
 	db *gorm.DB,
 	hosoRepo repository.HoSoRepository,
 	tailieuRepo repository.TaiLieuRepository,
+	lichSuRepo repository.HoSoLichSuRepository,
+	yeuCauRepo repository.YeuCauBoSungRepository,
+	hanXuLy HanXuLy,
+	phanCongRepo repository.PhanCongRepository,
+	thuTucRepo repository.ThuTucRepository,
+	danhSo DanhSo,
 ) HoSoService {
 	return &hoSoService{
-		db:          db,
-		hosoRepo:    hosoRepo,
-		tailieuRepo: tailieuRepo,
+		db:           db,
+		hosoRepo:     hosoRepo,
+		tailieuRepo:  tailieuRepo,
+		lichSuRepo:   lichSuRepo,
+		yeuCauRepo:   yeuCauRepo,
+		hanXuLy:      hanXuLy,
+		phanCongRepo: phanCongRepo,
+		thuTucRepo:   thuTucRepo,
+		danhSo:       danhSo,
 	}
 }
 
 const (
-	TrangThaiHoSoMoiTao     = "MoiTao"
-	TrangThaiHoSoBiTraLai   = "BiTraLai"
-	TrangThaiHoSoDaTiepNhan = "DaTiepNhan"
-	TrangThaiHoSoDangXuLy   = "DangXuLy"
-	TrangThaiHoSoDaDuyet    = "DaDuyet"
+	TrangThaiHoSoMoiTao      = "MoiTao"
+	TrangThaiHoSoChoTiepNhan = "ChoTiepNhan"
+	TrangThaiHoSoBiTraLai    = "BiTraLai"
+	TrangThaiHoSoDaTiepNhan  = "DaTiepNhan"
+	TrangThaiHoSoDangXuLy    = "DangXuLy"
+	TrangThaiHoSoDaDuyet     = "DaDuyet"
+	TrangThaiHoSoDaCapPhep   = "DaCapPhep"
 )
 
 func (s *hoSoService) CreateHoSo(ctx context.Context, req *dto.CreateHoSoRequest) (*models.HoSo, error) {
-	generatedMaHoSo := fmt.Sprintf("HS-%s", time.Now().Format("20060102-150405"))
+	// Tài khoản doanh nghiệp chỉ được tạo hồ sơ cho chính doanh nghiệp của mình
+	doanhNghiepID, err := policy.ScopeDoanhNghiepID(ctx, req.DoanhNghiepID)
+	if err != nil {
+		return nil, err
+	}
 
 	hoSo := models.HoSo{
-		DoanhNghiepID: req.DoanhNghiepID,
-		MaHoSo:        generatedMaHoSo,
+		DoanhNghiepID: doanhNghiepID,
 		LoaiThuTuc:    req.LoaiThuTuc,
 		NgayDangKy:    req.NgayDangKy,
-		TrangThaiHoSo: "MoiTao",
+		TrangThaiHoSo: TrangThaiHoSoMoiTao,
 	}
 
-	requiredTaiLieuTen, err := s.getRequiredTaiLieu(req.LoaiThuTuc)
+	thuTuc, err := s.thuTucRepo.GetThuTucByTen(ctx, s.db, req.LoaiThuTuc)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLoaiThuTucKhongHopLe
+		}
+		return nil, fmt.Errorf("lỗi khi tải danh mục thủ tục: %w", err)
+	}
+	if !thuTuc.HoatDong {
+		return nil, ErrLoaiThuTucKhongHopLe
 	}
 
 	tx := s.db.Begin()
@@ -91,6 +126,16 @@ func (s *hoSoService) CreateHoSo(ctx context.Context, req *dto.CreateHoSoRequest
 		}
 	}()
 
+	// Mã hồ sơ được cấp trong transaction: tạo hồ sơ lỗi thì số được hoàn lại
+	maHoSo, err := s.danhSo.CapSo(ctx, tx, SoHieuMaHoSo, time.Now(), func(ma string) (bool, error) {
+		return s.hosoRepo.ExistsMaHoSo(ctx, tx, ma)
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	hoSo.MaHoSo = maHoSo
+
 	if err := s.hosoRepo.CreateHoSo(ctx, tx, &hoSo); err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
@@ -103,23 +148,32 @@ func (s *hoSoService) CreateHoSo(ctx context.Context, req *dto.CreateHoSoRequest
 		return nil, fmt.Errorf("lỗi tạo hồ sơ: %w", err)
 	}
 
-	for _, tenTaiLieu := range requiredTaiLieuTen {
-		loaiTL, err := s.tailieuRepo.GetLoaiTaiLieuByTen(ctx, tx, tenTaiLieu)
-		if err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("lỗi cấu hình: không tìm thấy loại tài liệu '%s': %w", tenTaiLieu, err)
-		}
-
+	for _, tl := range thuTuc.TaiLieus {
 		hstl := models.HoSoTaiLieu{
 			HoSoID:        hoSo.ID,
-			LoaiTaiLieuID: loaiTL.ID,
+			LoaiTaiLieuID: tl.LoaiTaiLieuID,
+			BatBuoc:       tl.BatBuoc,
 		}
 		if err := s.tailieuRepo.CreateHoSoTaiLieu(ctx, tx, &hstl); err != nil {
 			tx.Rollback()
-			return nil, fmt.Errorf("lỗi tạo khe tài liệu '%s': %w", tenTaiLieu, err)
+			return nil, fmt.Errorf("lỗi tạo khe tài liệu '%s': %w", tl.LoaiTaiLieu.Ten, err)
 		}
 	}
 
+	if err := ghiLichSuHoSo(ctx, tx, s.lichSuRepo, hoSo.ID, suKienHoSo{
+		loai:         models.SuKienTaoHoSo,
+		trangThaiSau: hoSo.TrangThaiHoSo,
+		sau: map[string]any{
+			"ma_ho_so":        hoSo.MaHoSo,
+			"loai_thu_tuc":    hoSo.LoaiThuTuc,
+			"ngay_dang_ky":    hoSo.NgayDangKy,
+			"doanh_nghiep_id": hoSo.DoanhNghiepID,
+		},
+	}); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("lỗi ghi nhật ký hồ sơ: %w", err)
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("không thể commit transaction: %w", err)
 	}
@@ -127,100 +181,31 @@ func (s *hoSoService) CreateHoSo(ctx context.Context, req *dto.CreateHoSoRequest
 	return &hoSo, nil
 }
 
-func (s *hoSoService) getRequiredTaiLieu(loaiThuTuc string) ([]string, error) {
-	switch loaiThuTuc {
-	case "Cấp mới Giấy phép kinh doanh":
-		return []string{
-			"Đơn đề nghị cấp Giấy phép kinh doanh",
-			"Giấy chứng nhận đăng ký doanh nghiệp",
-			"Danh sách đội ngũ kĩ thuật và văn bằng",
-			"Phương án kinh doanh",
-			"Phương án bảo mật và an toàn thông tin mạng",
-			"Phương án kỹ thuật và Phương án bảo hành bảo trì",
-			"Tài liệu kĩ thuật",
-			"Giấy chứng nhận hợp quy",
-		}, nil
-
-	case "Sửa đổi, bổ sung Giấy phép kinh doanh":
-		return []string{
-			"Đơn đề nghị cấp sửa đổi, bổ sung Giấy phép kinh doanh",
-			"Giấy phép kinh doanh sản phẩm, dịch vụ mật mã dân sự",
-		}, nil
-
-	case "Gia hạn Giấy phép kinh doanh":
-		return []string{
-			"Đơn đề nghị gia hạn Giấy phép kinh doanh",
-			"Giấy phép kinh doanh sản phẩm, dịch vụ mật mã dân sự",
-			"Báo cáo hoạt động của doanh nghiệp",
-		}, nil
-
-	case "Cấp lại Giấy phép kinh doanh":
-		return []string{
-			"Đơn đề nghị cấp lại Giấy phép kinh doanh",
-		}, nil
-
-	case "Cấp Giấy phép xuất khẩu, nhập khẩu":
-		return []string{
-			"Đơn đề nghị cấp Giấy phép xuất khẩu, nhập khẩu",
-			"Giấy phép kinh doanh sản phẩm, dịch vụ mật mã dân sự",
-			"Tài liệu kĩ thuật",
-			"Giấy chứng nhận hợp quy",
-		}, nil
-
-	case "Báo cáo hoạt động định kỳ":
-		return []string{
-			"Báo cáo hoạt động của doanh nghiệp",
-		}, nil
-
-	default:
-		return nil, ErrLoaiThuTucKhongHopLe
-	}
-}
-
-var allThuTucNames = []string{
-	"Cấp mới Giấy phép kinh doanh",
-	"Sửa đổi, bổ sung Giấy phép kinh doanh",
-	"Gia hạn Giấy phép kinh doanh",
-	"Cấp lại Giấy phép kinh doanh",
-	"Cấp Giấy phép xuất khẩu, nhập khẩu",
-	"Báo cáo hoạt động định kỳ",
-}
-
+// GetLoaiTaiLieu trả về thành phần hồ sơ của một thủ tục, hoặc của mọi thủ tục đang hoạt động theo nhóm khi tenThuTuc rỗng
 func (s *hoSoService) GetLoaiTaiLieu(ctx context.Context, tenThuTuc string) (any, error) {
 
 	if tenThuTuc != "" {
-		requiredNames, err := s.getRequiredTaiLieu(tenThuTuc)
-		if err != nil {
-			return nil, err
-		}
-
-		loaiTaiLieus, err := s.tailieuRepo.ListLoaiTaiLieu(ctx, s.db, requiredNames)
+		thuTuc, err := s.thuTucRepo.GetThuTucByTen(ctx, s.db, tenThuTuc)
 		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrLoaiThuTucKhongHopLe
+			}
 			return nil, fmt.Errorf("lỗi khi lấy danh sách loại tài liệu: %w", err)
 		}
-		return loaiTaiLieus, nil
+		return dto.ToLoaiTaiLieuThuTucResponses(thuTuc), nil
 	}
 
-	var groupedResult []dto.GroupedLoaiTaiLieuResponse
-
-	for _, thuTucName := range allThuTucNames {
-
-		requiredNames, _ := s.getRequiredTaiLieu(thuTucName)
-		if requiredNames == nil {
-			continue
-		}
-
-		taiLieuModels, err := s.tailieuRepo.ListLoaiTaiLieu(ctx, s.db, requiredNames)
-		if err != nil {
-			return nil, fmt.Errorf("lỗi khi lấy tài liệu cho nhóm '%s': %w", thuTucName, err)
-		}
+	thuTucs, err := s.thuTucRepo.ListThuTuc(ctx, s.db, true)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi lấy danh mục thủ tục: %w", err)
+	}
 
-		group := dto.GroupedLoaiTaiLieuResponse{
-			TenThuTuc: thuTucName,
-			TaiLieus:  taiLieuModels,
+	groupedResult := make([]dto.GroupedLoaiTaiLieuResponse, len(thuTucs))
+	for i := range thuTucs {
+		groupedResult[i] = dto.GroupedLoaiTaiLieuResponse{
+			TenThuTuc: thuTucs[i].Ten,
+			TaiLieus:  dto.ToLoaiTaiLieuThuTucResponses(&thuTucs[i]),
 		}
-
-		groupedResult = append(groupedResult, group)
 	}
 
 	return groupedResult, nil
@@ -234,6 +219,11 @@ func (s *hoSoService) GetHoSoDetails(ctx context.Context, hoSoID uuid.UUID) (*mo
 		return nil, err
 	}
 
+	// Hồ sơ của doanh nghiệp khác: trả về "không tìm thấy" thay vì 403
+	if !policy.CanAccessDoanhNghiep(ctx, hoSo.DoanhNghiepID) {
+		return nil, ErrHoSoKhongTimThay
+	}
+
 	return hoSo, nil
 }
 
@@ -245,7 +235,12 @@ func (s *hoSoService) ListHoSo(
 	pageSize int,
 ) (*dto.HoSoListResponse, error) {
 
-	// 1. Gọi Repository
+	// 1. Tài khoản doanh nghiệp luôn bị ép lọc theo doanh nghiệp của mình
+	doanhNghiepID, err := policy.ScopeDoanhNghiepID(ctx, doanhNghiepID)
+	if err != nil {
+		return nil, err
+	}
+
 	hoSos, total, err := s.hosoRepo.ListHoSo(ctx, s.db, doanhNghiepID, params, page, pageSize)
 	if err != nil {
 		return nil, fmt.Errorf("lỗi khi lấy danh sách hồ sơ: %w", err)
@@ -278,6 +273,7 @@ func (s *hoSoService) UploadTaiLieu(
 	// 1. Kiểm tra khe tài liệu
 	var kheTaiLieu models.HoSoTaiLieu
 	if err := s.db.WithContext(ctx).
+		Preload("HoSo").
 		First(&kheTaiLieu, "id = ?", req.HoSoTaiLieuID).Error; err != nil {
 
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -285,6 +281,9 @@ func (s *hoSoService) UploadTaiLieu(
 		}
 		return nil, fmt.Errorf("lỗi khi kiểm tra khe cắm tài liệu: %w", err)
 	}
+	if !policy.CanAccessDoanhNghiep(ctx, kheTaiLieu.HoSo.DoanhNghiepID) {
+		return nil, ErrKheTaiLieuKhongTonTai
+	}
 
 	// 2. Chuẩn bị đường dẫn
 	hoSoID := kheTaiLieu.HoSoID.String()
@@ -331,23 +330,73 @@ func (s *hoSoService) UploadTaiLieu(
 		CreatedAt:     time.Now(),
 	}
 
-	if err := s.tailieuRepo.CreateTaiLieu(ctx, s.db, &taiLieu); err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		hoSo, err := s.hosoRepo.GetHoSoForUpdate(ctx, tx, kheTaiLieu.HoSoID)
+		if err != nil {
+			return err
+		}
+		if err := s.kiemTraSuaTaiLieu(ctx, tx, hoSo, kheTaiLieu.ID); err != nil {
+			return err
+		}
+		if err := s.tailieuRepo.CreateTaiLieu(ctx, tx, &taiLieu); err != nil {
+			return err
+		}
+		if err := s.yeuCauRepo.DanhDauDaBoSung(ctx, tx, taiLieu.HoSoTaiLieuID, taiLieu.CreatedAt); err != nil {
+			return err
+		}
+		return ghiLichSuHoSo(ctx, tx, s.lichSuRepo, kheTaiLieu.HoSoID, suKienHoSo{
+			loai: models.SuKienNopTaiLieu,
+			sau: map[string]any{
+				"tai_lieu_id":       taiLieu.ID,
+				"ho_so_tai_lieu_id": taiLieu.HoSoTaiLieuID,
+				"tieu_de":           taiLieu.TieuDe,
+			},
+		})
+	})
+	if err != nil {
 		_ = os.Remove(finalDst)
+		if errors.Is(err, ErrHoSoKhongSuaTaiLieu) || errors.Is(err, ErrKheKhongCanBoSung) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("lỗi lưu thông tin file vào CSDL: %w", err)
 	}
 
 	return &taiLieu, nil
 }
 func (s *hoSoService) DeleteTaiLieu(ctx context.Context, taiLieuID uuid.UUID) error {
-	taiLieu, err := s.tailieuRepo.GetTaiLieuByID(ctx, s.db, taiLieuID)
+	taiLieu, err := s.GetTaiLieuByID(ctx, taiLieuID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrTaiLieuKhongTimThay
-		}
-		return fmt.Errorf("lỗi khi tìm tài liệu: %w", err)
+		return err
 	}
 
-	if err := s.tailieuRepo.DeleteTaiLieu(ctx, s.db, taiLieuID); err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		hoSo, err := s.hosoRepo.GetHoSoForUpdate(ctx, tx, taiLieu.HoSoTaiLieu.HoSoID)
+		if err != nil {
+			return err
+		}
+		if err := s.kiemTraSuaTaiLieu(ctx, tx, hoSo, taiLieu.HoSoTaiLieuID); err != nil {
+			return err
+		}
+		if err := s.tailieuRepo.DeleteTaiLieu(ctx, tx, taiLieuID); err != nil {
+			return err
+		}
+		// Xóa tài liệu vừa bổ sung thì khe lại là chưa bổ sung, nộp lại hồ sơ sẽ bị chặn
+		if err := s.yeuCauRepo.BoDanhDauBoSung(ctx, tx, taiLieu.HoSoTaiLieuID); err != nil {
+			return err
+		}
+		return ghiLichSuHoSo(ctx, tx, s.lichSuRepo, taiLieu.HoSoTaiLieu.HoSoID, suKienHoSo{
+			loai: models.SuKienXoaTaiLieu,
+			truoc: map[string]any{
+				"tai_lieu_id":       taiLieu.ID,
+				"ho_so_tai_lieu_id": taiLieu.HoSoTaiLieuID,
+				"tieu_de":           taiLieu.TieuDe,
+			},
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrHoSoKhongSuaTaiLieu) || errors.Is(err, ErrKheKhongCanBoSung) {
+			return err
+		}
 		return fmt.Errorf("lỗi khi xóa tài liệu khỏi CSDL: %w", err)
 	}
 
@@ -378,24 +427,41 @@ func (s *hoSoService) GetTaiLieuByID(ctx context.Context, taiLieuID uuid.UUID) (
 		}
 		return nil, fmt.Errorf("lỗi khi tìm tài liệu: %w", err)
 	}
+	if !policy.CanAccessDoanhNghiep(ctx, taiLieu.HoSoTaiLieu.HoSo.DoanhNghiepID) {
+		return nil, ErrTaiLieuKhongTimThay
+	}
 	return taiLieu, nil
 }
 
+// UpdateHoSo sửa thông tin nhập tay của hồ sơ. Dòng hồ sơ được khóa trong transaction để không ghi đè
+// thay đổi của một thao tác chuyển trạng thái (tiếp nhận, trả lại, cấp giấy phép...) chạy đồng thời.
 func (s *hoSoService) UpdateHoSo(ctx context.Context, hoSoID uuid.UUID, req *dto.UpdateHoSoRequest) (*models.HoSo, error) {
-	hoSo, err := s.hosoRepo.GetHoSoByID(ctx, s.db, hoSoID)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrHoSoKhongTimThay
+	var hoSo *models.HoSo
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		hoSo, err = s.hosoRepo.GetHoSoForUpdate(ctx, tx, hoSoID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrHoSoKhongTimThay
+			}
+			return err
 		}
-		return nil, fmt.Errorf("lỗi khi tìm hồ sơ: %w", err)
-	}
-	hoSo.NgayDangKy = req.NgayDangKy
-	hoSo.NgayTiepNhan = req.NgayTiepNhan
-	hoSo.NgayHenTra = req.NgayHenTra
-	hoSo.SoGiayPhepTheoHoSo = req.SoGiayPhepTheoHoSo
-	hoSo.TrangThaiHoSo = req.TrangThaiHoSo
-
-	if err := s.hosoRepo.UpdateHoSo(ctx, s.db, hoSo); err != nil {
+		// Ngày tiếp nhận và hạn trả chỉ đổi qua thao tác tiếp nhận; trạng thái chỉ đổi qua máy trạng thái
+		if hoSo.NgayDangKy.Equal(req.NgayDangKy) {
+			return nil
+		}
+		truoc := map[string]any{"ngay_dang_ky": hoSo.NgayDangKy}
+		hoSo.NgayDangKy = req.NgayDangKy
+		if err := s.hosoRepo.UpdateHoSo(ctx, tx, hoSo); err != nil {
+			return err
+		}
+		return ghiLichSuHoSo(ctx, tx, s.lichSuRepo, hoSo.ID, suKienHoSo{
+			loai:  models.SuKienCapNhatHoSo,
+			truoc: truoc,
+			sau:   map[string]any{"ngay_dang_ky": hoSo.NgayDangKy},
+		})
+	})
+	if err != nil {
 		return nil, fmt.Errorf("lỗi khi cập nhật hồ sơ: %w", err)
 	}
 
@@ -411,6 +477,9 @@ func (s *hoSoService) DeleteHoSo(ctx context.Context, hoSoID uuid.UUID) error {
 		}
 		return fmt.Errorf("lỗi khi tìm hồ sơ: %w", err)
 	}
+	if !policy.CanAccessDoanhNghiep(ctx, hoSo.DoanhNghiepID) {
+		return ErrHoSoKhongTimThay
+	}
 
 	// 2. Logic nghiệp vụ: Chỉ cho phép xóa hồ sơ "MoiTao" hoặc "BiTraLai"
 	if hoSo.TrangThaiHoSo != TrangThaiHoSoMoiTao && hoSo.TrangThaiHoSo != TrangThaiHoSoBiTraLai {
@@ -454,3 +523,27 @@ func (s *hoSoService) DeleteHoSo(ctx context.Context, hoSoID uuid.UUID) error {
 
 	return nil
 }
+
+// GetLichSu trả về nhật ký xử lý hồ sơ theo thứ tự thời gian
+func (s *hoSoService) GetLichSu(ctx context.Context, hoSoID uuid.UUID) ([]dto.HoSoLichSuResponse, error) {
+	hoSo, err := s.hosoRepo.GetHoSoByID(ctx, s.db, hoSoID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHoSoKhongTimThay
+		}
+		return nil, err
+	}
+	if !policy.CanAccessDoanhNghiep(ctx, hoSo.DoanhNghiepID) {
+		return nil, ErrHoSoKhongTimThay
+	}
+
+	list, err := s.lichSuRepo.ListByHoSoID(ctx, s.db, hoSoID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.HoSoLichSuResponse, len(list))
+	for i := range list {
+		result[i] = dto.ToHoSoLichSuResponse(&list[i])
+	}
+	return result, nil
+}
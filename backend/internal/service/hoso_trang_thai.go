@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/policy"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrThaoTacKhongHopLe         = errors.New("thao tác không hợp lệ")
+	ErrChuyenTrangThaiKhongHopLe = errors.New("không thể thực hiện thao tác ở trạng thái hiện tại của hồ sơ")
+	ErrKhongCoQuyenThaoTac       = errors.New("bạn không có quyền thực hiện thao tác này trên hồ sơ")
+	ErrThieuLyDo                 = errors.New("cần nhập lý do")
+)
+
+// Các thao tác chuyển trạng thái hồ sơ (cũng là đường dẫn /ho-so/:id/<thao tác>)
+const (
+	ThaoTacNop      = "nop"
+	ThaoTacTiepNhan = "tiep-nhan"
+	ThaoTacXuLy     = "xu-ly"
+	ThaoTacTraLai   = "tra-lai"
+	ThaoTacNopLai   = "nop-lai"
+	ThaoTacDuyet    = "duyet"
+	// Cấp giấy phép có API riêng (GiayPhepService.CapGiayPhepTheoHoSo), không đi qua ChuyenTrangThai
+	ThaoTacCapGiayPhep = "cap-giay-phep"
+)
+
+// chuyenTrangThai mô tả một cạnh của máy trạng thái hồ sơ
+type chuyenTrangThai struct {
+	tu       []string // Các trạng thái được phép bắt đầu
+	den      string
+	roles    []string // Role được thực hiện; tài khoản doanh nghiệp chỉ thao tác trên hồ sơ của mình
+	canLyDo  bool
+	apiRieng bool // Thao tác tạo thêm dữ liệu ngoài hồ sơ, thực hiện qua API riêng
+}
+
+var (
+	canBoRoles = []string{middleware.RoleAdmin, middleware.RoleCanBo}
+	moiRoles   = []string{middleware.RoleAdmin, middleware.RoleCanBo, middleware.RoleDoanhNghiep}
+)
+
+// mayTrangThaiHoSo: MoiTao -> ChoTiepNhan -> DaTiepNhan -> DangXuLy -> DaDuyet (-> DaCapPhep với thủ tục cấp giấy phép),
+// hồ sơ chỉ được nộp (chờ tiếp nhận) khi đủ tài liệu bắt buộc;
+// cán bộ trả lại (BiTraLai) khi đã tiếp nhận hoặc đang xử lý, doanh nghiệp bổ sung rồi nộp lại để tiếp nhận lại.
+var mayTrangThaiHoSo = map[string]chuyenTrangThai{
+	ThaoTacNop:         {tu: []string{TrangThaiHoSoMoiTao}, den: TrangThaiHoSoChoTiepNhan, roles: moiRoles},
+	ThaoTacTiepNhan:    {tu: []string{TrangThaiHoSoChoTiepNhan}, den: TrangThaiHoSoDaTiepNhan, roles: canBoRoles},
+	ThaoTacXuLy:        {tu: []string{TrangThaiHoSoDaTiepNhan}, den: TrangThaiHoSoDangXuLy, roles: canBoRoles},
+	ThaoTacTraLai:      {tu: []string{TrangThaiHoSoDaTiepNhan, TrangThaiHoSoDangXuLy}, den: TrangThaiHoSoBiTraLai, roles: canBoRoles, canLyDo: true},
+	ThaoTacNopLai:      {tu: []string{TrangThaiHoSoBiTraLai}, den: TrangThaiHoSoDaTiepNhan, roles: moiRoles},
+	ThaoTacDuyet:       {tu: []string{TrangThaiHoSoDangXuLy}, den: TrangThaiHoSoDaDuyet, roles: canBoRoles},
+	ThaoTacCapGiayPhep: {tu: []string{TrangThaiHoSoDaDuyet}, den: TrangThaiHoSoDaCapPhep, roles: canBoRoles, apiRieng: true},
+}
+
+// thuTuThaoTac giữ thứ tự hiển thị ổn định cho ThaoTacHopLe
+var thuTuThaoTac = []string{ThaoTacNop, ThaoTacTiepNhan, ThaoTacXuLy, ThaoTacTraLai, ThaoTacNopLai, ThaoTacDuyet, ThaoTacCapGiayPhep}
+
+// ThaoTacHopLe trả về các thao tác role được thực hiện từ trạng thái hiện tại của hồ sơ
+func ThaoTacHopLe(trangThai string, role string) []string {
+	var result []string
+	for _, ten := range thuTuThaoTac {
+		ct := mayTrangThaiHoSo[ten]
+		if slices.Contains(ct.tu, trangThai) && slices.Contains(ct.roles, role) {
+			result = append(result, ten)
+		}
+	}
+	return result
+}
+
+// ChuyenTrangThai thực hiện một thao tác trên hồ sơ: kiểm tra trạng thái nguồn và quyền theo máy trạng thái,
+// khóa dòng hồ sơ trong transaction để hai thao tác đồng thời không cùng chuyển từ một trạng thái.
+func (s *hoSoService) ChuyenTrangThai(ctx context.Context, hoSoID uuid.UUID, thaoTac string, req *dto.ChuyenTrangThaiHoSoRequest) (*models.HoSo, error) {
+	ct, ok := mayTrangThaiHoSo[thaoTac]
+	if !ok || ct.apiRieng {
+		return nil, ErrThaoTacKhongHopLe
+	}
+	actor, _ := policy.ActorFrom(ctx)
+	if !slices.Contains(ct.roles, actor.RoleName) {
+		return nil, ErrKhongCoQuyenThaoTac
+	}
+	lyDo := strings.TrimSpace(req.LyDo)
+	if ct.canLyDo && lyDo == "" {
+		return nil, ErrThieuLyDo
+	}
+
+	var hoSo *models.HoSo
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		hoSo, err = s.hosoRepo.GetHoSoForUpdate(ctx, tx, hoSoID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrHoSoKhongTimThay
+			}
+			return err
+		}
+		if !policy.CanAccessDoanhNghiep(ctx, hoSo.DoanhNghiepID) {
+			return ErrHoSoKhongTimThay
+		}
+		if !slices.Contains(ct.tu, hoSo.TrangThaiHoSo) {
+			return fmt.Errorf("%w (%s -> %s, hiện tại: %s)", ErrChuyenTrangThaiKhongHopLe, thaoTac, ct.den, hoSo.TrangThaiHoSo)
+		}
+
+		trangThaiTruoc := hoSo.TrangThaiHoSo
+		chiTiet := map[string]any{"thao_tac": thaoTac}
+		now := time.Now()
+		switch thaoTac {
+		case ThaoTacNop:
+			if err := s.kiemTraDayDu(ctx, tx, hoSo.ID); err != nil {
+				return err
+			}
+		case ThaoTacTiepNhan:
+			hoSo.NgayTiepNhan = now
+			if hoSo.NgayHenTra, err = s.hanXuLy.TinhNgayHenTra(ctx, tx, hoSo.LoaiThuTuc, now, 0); err != nil {
+				return err
+			}
+			chiTiet["ngay_hen_tra"] = hoSo.NgayHenTra
+		case ThaoTacTraLai:
+			hoSo.LyDoTraLai = &lyDo
+			// Dừng đồng hồ thời hạn trong lúc chờ doanh nghiệp bổ sung
+			hoSo.NgayTamDung = &now
+			yeuCau, err := s.taoYeuCauBoSung(ctx, tx, hoSo, lyDo, req.TaiLieu)
+			if err != nil {
+				return err
+			}
+			chiTiet["lan_bo_sung"] = yeuCau.Lan
+			chiTiet["tai_lieu_can_bo_sung"] = req.TaiLieu
+		case ThaoTacNopLai:
+			yeuCau, err := s.dongYeuCauBoSung(ctx, tx, hoSo)
+			if err != nil {
+				return err
+			}
+			if yeuCau != nil {
+				chiTiet["lan_bo_sung"] = yeuCau.Lan
+			}
+			if err := s.tiepTucThoiHan(ctx, tx, hoSo, now); err != nil {
+				return err
+			}
+			chiTiet["so_ngay_tam_dung"] = hoSo.SoNgayTamDung
+			chiTiet["ngay_hen_tra"] = hoSo.NgayHenTra
+		}
+		hoSo.TrangThaiHoSo = ct.den
+		if hoSo.TrangThaiHoSo == TrangThaiHoSoDaTiepNhan && hoSo.CanBoXuLyID == nil {
+			if err := s.tuDongPhanCong(ctx, tx, hoSo); err != nil {
+				return err
+			}
+		}
+		if err := s.hosoRepo.UpdateHoSo(ctx, tx, hoSo); err != nil {
+			return err
+		}
+		return ghiLichSuHoSo(ctx, tx, s.lichSuRepo, hoSo.ID, suKienHoSo{
+			loai:           models.SuKienChuyenTrangThai,
+			trangThaiTruoc: trangThaiTruoc,
+			trangThaiSau:   hoSo.TrangThaiHoSo,
+			sau:            chiTiet,
+			ghiChu:         lyDo,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hoSo, nil
+}
+
+// tiepTucThoiHan chạy lại đồng hồ khi hồ sơ được nộp lại: cộng số ngày làm việc đã dừng
+// vào tổng thời gian tạm dừng rồi tính lại hạn trả từ ngày tiếp nhận ban đầu
+func (s *hoSoService) tiepTucThoiHan(ctx context.Context, tx *gorm.DB, hoSo *models.HoSo, now time.Time) error {
+	if hoSo.NgayTamDung == nil {
+		return nil
+	}
+	soNgay, err := s.hanXuLy.DemNgayLamViec(ctx, tx, *hoSo.NgayTamDung, now)
+	if err != nil {
+		return err
+	}
+	hoSo.SoNgayTamDung += soNgay
+	hoSo.NgayTamDung = nil
+	if hoSo.NgayTiepNhan.IsZero() {
+		return nil
+	}
+	hoSo.NgayHenTra, err = s.hanXuLy.TinhNgayHenTra(ctx, tx, hoSo.LoaiThuTuc, hoSo.NgayTiepNhan, hoSo.SoNgayTamDung)
+	return err
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/calendar"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrChuaCauHinhThoiHan   = errors.New("loại thủ tục này chưa cấu hình thời hạn giải quyết")
+	ErrNgayNghiKhongTimThay = errors.New("không tìm thấy ngày nghỉ trong lịch")
+)
+
+// HanXuLy tính hạn trả hồ sơ theo ngày làm việc; các hàm nhận db để chạy trong transaction của người gọi
+type HanXuLy interface {
+	// TinhNgayHenTra: hạn trả = ngày tiếp nhận + thời hạn của thủ tục + số ngày làm việc đã tạm dừng
+	TinhNgayHenTra(ctx context.Context, db *gorm.DB, loaiThuTuc string, ngayTiepNhan time.Time, soNgayTamDung int) (time.Time, error)
+	DemNgayLamViec(ctx context.Context, db *gorm.DB, tu time.Time, den time.Time) (int, error)
+	// CongNgayLamViec trả về hết giờ hành chính của ngày làm việc thứ soNgay sau ngày chứa tu
+	CongNgayLamViec(ctx context.Context, db *gorm.DB, tu time.Time, soNgay int) (time.Time, error)
+}
+
+type LichLamViecService interface {
+	HanXuLy
+	ListNgayNghi(ctx context.Context, nam int) ([]models.NgayNghiLe, error)
+	LuuNgayNghi(ctx context.Context, ngay time.Time, req *dto.LuuNgayNghiRequest) (*models.NgayNghiLe, error)
+	XoaNgayNghi(ctx context.Context, ngay time.Time) error
+}
+
+type lichLamViecService struct {
+	db         *gorm.DB
+	repo       repository.LichLamViecRepository
+	thuTucRepo repository.ThuTucRepository
+}
+
+func NewLichLamViecService(db *gorm.DB, repo repository.LichLamViecRepository, thuTucRepo repository.ThuTucRepository) LichLamViecService {
+	return &lichLamViecService{db: db, repo: repo, thuTucRepo: thuTucRepo}
+}
+
+// napLich nạp ngày nghỉ lễ và ngày làm bù từ ngày chứa tu đến hết ngày chứa den
+func (s *lichLamViecService) napLich(ctx context.Context, db *gorm.DB, tu time.Time, den time.Time) (*calendar.Lich, error) {
+	list, err := s.repo.ListNgayNghi(ctx, db, calendar.NgayCua(tu), calendar.NgayCua(den))
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi tải lịch nghỉ lễ: %w", err)
+	}
+	var ngayNghi, ngayLamBu []time.Time
+	for _, d := range list {
+		if d.LaNgayLamBu {
+			ngayLamBu = append(ngayLamBu, d.Ngay)
+		} else {
+			ngayNghi = append(ngayNghi, d.Ngay)
+		}
+	}
+	return calendar.New(ngayNghi, ngayLamBu), nil
+}
+
+func (s *lichLamViecService) TinhNgayHenTra(ctx context.Context, db *gorm.DB, loaiThuTuc string, ngayTiepNhan time.Time, soNgayTamDung int) (time.Time, error) {
+	thuTuc, err := s.thuTucRepo.GetThuTucByTen(ctx, db, loaiThuTuc)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return time.Time{}, fmt.Errorf("%w: %s", ErrChuaCauHinhThoiHan, loaiThuTuc)
+		}
+		return time.Time{}, err
+	}
+	return s.CongNgayLamViec(ctx, db, ngayTiepNhan, thuTuc.SoNgayLamViec+soNgayTamDung)
+}
+
+func (s *lichLamViecService) CongNgayLamViec(ctx context.Context, db *gorm.DB, tu time.Time, soNgay int) (time.Time, error) {
+	// Mỗi tuần có 5 ngày làm việc, cộng thêm khoảng dự phòng cho kỳ nghỉ Tết dài nhất
+	ketThuc := calendar.NgayCua(tu).AddDate(0, 0, soNgay*2+60)
+	for {
+		lich, err := s.napLich(ctx, db, tu, ketThuc)
+		if err != nil {
+			return time.Time{}, err
+		}
+		han := lich.CongNgayLamViec(tu, soNgay)
+		if !calendar.NgayCua(han).After(ketThuc) {
+			return han, nil
+		}
+		// Đi quá khoảng đã nạp thì các ngày sau ketThuc bị coi là ngày thường dù có thể là ngày nghỉ:
+		// nạp rộng thêm rồi tính lại
+		ketThuc = calendar.NgayCua(han).AddDate(0, 0, 60)
+	}
+}
+
+func (s *lichLamViecService) DemNgayLamViec(ctx context.Context, db *gorm.DB, tu time.Time, den time.Time) (int, error) {
+	if !den.After(tu) {
+		return 0, nil
+	}
+	lich, err := s.napLich(ctx, db, tu, den)
+	if err != nil {
+		return 0, err
+	}
+	return lich.DemNgayLamViec(tu, den), nil
+}
+
+// ListNgayNghi trả về lịch nghỉ lễ của một năm; nam <= 0 lấy năm hiện tại
+func (s *lichLamViecService) ListNgayNghi(ctx context.Context, nam int) ([]models.NgayNghiLe, error) {
+	if nam <= 0 {
+		nam = time.Now().In(calendar.ViTri).Year()
+	}
+	tu := time.Date(nam, time.January, 1, 0, 0, 0, 0, calendar.ViTri)
+	den := time.Date(nam, time.December, 31, 0, 0, 0, 0, calendar.ViTri)
+	return s.repo.ListNgayNghi(ctx, s.db, tu, den)
+}
+
+// LuuNgayNghi thêm mới hoặc sửa ngày nghỉ đã có. Hạn trả của các hồ sơ đã tiếp nhận
+// không bị tính lại; thay đổi chỉ áp dụng cho các lần tính hạn sau đó.
+func (s *lichLamViecService) LuuNgayNghi(ctx context.Context, ngay time.Time, req *dto.LuuNgayNghiRequest) (*models.NgayNghiLe, error) {
+	ngayNghi := &models.NgayNghiLe{
+		Ngay:        ngay,
+		Ten:         strings.TrimSpace(req.Ten),
+		LaNgayLamBu: req.LaNgayLamBu,
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.repo.UpsertNgayNghi(ctx, s.db, ngayNghi); err != nil {
+		return nil, fmt.Errorf("lỗi khi lưu ngày nghỉ: %w", err)
+	}
+	return ngayNghi, nil
+}
+
+func (s *lichLamViecService) XoaNgayNghi(ctx context.Context, ngay time.Time) error {
+	affected, err := s.repo.DeleteNgayNghi(ctx, s.db, ngay)
+	if err != nil {
+		return fmt.Errorf("lỗi khi xóa ngày nghỉ: %w", err)
+	}
+	if affected == 0 {
+		return ErrNgayNghiKhongTimThay
+	}
+	return nil
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrChuaCoQuyTrinhDuyet = errors.New("loại giấy phép này chưa cấu hình quy trình phê duyệt")
+	ErrChuaDuPheDuyet      = errors.New("giấy phép chưa được phê duyệt đủ các bước trước khi ký")
+	ErrBuocCuoiLaKySo      = errors.New("các bước phê duyệt đã xong, bước cuối cùng thực hiện bằng ký số")
+	ErrKhongDungChucVu     = errors.New("chức vụ của bạn không phải chức vụ phụ trách bước này")
+	ErrDaDuyetBuocKhac     = errors.New("bạn đã phê duyệt một bước khác của giấy phép này, mỗi bước phải do người khác thực hiện")
+	ErrGiayPhepChuaCoFile  = errors.New("giấy phép chưa có file đính kèm, chưa thể phê duyệt")
+	ErrPheDuyetSauKhiKy    = errors.New("giấy phép đã ký số, quy trình phê duyệt đã kết thúc")
+	ErrChucVuChiChoCanBo   = errors.New("chỉ tài khoản cán bộ hoặc quản trị mới có chức vụ phê duyệt")
+	ErrQuyTrinhTrungChucVu = errors.New("hai bước liên tiếp không được cùng chức vụ")
+)
+
+// ApprovalGate chặn ký số khi giấy phép chưa qua đủ các bước phê duyệt của loại giấy phép đó
+type ApprovalGate interface {
+	KiemTraTruocKy(ctx context.Context, gp *models.GiayPhep, user *models.User) error
+	// GhiNhanKy lưu bước cuối (ký số) vào lịch sử phê duyệt; h2Hash là file đã được duyệt và ký
+	GhiNhanKy(ctx context.Context, gp *models.GiayPhep, h2Hash string, user *models.User) error
+}
+
+type PheDuyetService interface {
+	ApprovalGate
+	GetQuyTrinh(ctx context.Context, loaiGiayPhep string) ([]models.BuocDuyet, error)
+	CauHinhQuyTrinh(ctx context.Context, req *dto.CauHinhQuyTrinhRequest) ([]models.BuocDuyet, error)
+	UpdateChucVu(ctx context.Context, userID uuid.UUID, chucVu string) error
+	GetTrangThai(ctx context.Context, giayPhepID uuid.UUID) (*dto.PheDuyetStatusResponse, error)
+	PheDuyet(ctx context.Context, giayPhepID uuid.UUID, userID uuid.UUID, req *dto.PheDuyetRequest) (*dto.PheDuyetStatusResponse, error)
+}
+
+type pheDuyetService struct {
+	db       *gorm.DB
+	repo     repository.PheDuyetRepository
+	gpRepo   repository.GiayPhepRepository
+	userRepo repository.UserRepository
+}
+
+func NewPheDuyetService(db *gorm.DB, repo repository.PheDuyetRepository, gpRepo repository.GiayPhepRepository, userRepo repository.UserRepository) PheDuyetService {
+	return &pheDuyetService{db: db, repo: repo, gpRepo: gpRepo, userRepo: userRepo}
+}
+
+// tienDo là trạng thái lượt duyệt hiện tại của một giấy phép
+type tienDo struct {
+	cacBuoc []models.BuocDuyet
+	lichSu  []models.PheDuyetGiayPhep
+	// Lượt đồng ý còn hiệu lực theo thứ tự bước
+	daDuyet map[int]*models.PheDuyetGiayPhep
+	// Chỉ số (trong cacBuoc) của bước đang chờ; bằng len(cacBuoc) khi đã xong
+	hienTai int
+}
+
+// tinhTienDo đi qua lịch sử theo thời gian: lượt từ chối hoặc lượt duyệt trên file khác
+// (đã thay file) làm lại từ đầu. Lượt duyệt chỉ được tính khi khớp bước hiện có của quy trình.
+func tinhTienDo(cacBuoc []models.BuocDuyet, lichSu []models.PheDuyetGiayPhep, h2Hash string) *tienDo {
+	t := &tienDo{cacBuoc: cacBuoc, lichSu: lichSu, daDuyet: map[int]*models.PheDuyetGiayPhep{}}
+	for i := range lichSu {
+		row := &lichSu[i]
+		if row.QuyetDinh == models.QuyetDinhTuChoi || row.H2Hash != h2Hash {
+			t.daDuyet = map[int]*models.PheDuyetGiayPhep{}
+			continue
+		}
+		for _, b := range cacBuoc {
+			if b.ThuTu == row.ThuTu && b.ChucVu == row.ChucVu {
+				t.daDuyet[row.ThuTu] = row
+			}
+		}
+	}
+	for t.hienTai < len(cacBuoc) && t.daDuyet[cacBuoc[t.hienTai].ThuTu] != nil {
+		t.hienTai++
+	}
+	return t
+}
+
+// sanSangKy: mọi bước trước bước ký (bước cuối) đã được duyệt
+func (t *tienDo) sanSangKy() bool {
+	return t.hienTai >= len(t.cacBuoc)-1
+}
+
+func (t *tienDo) daDuyetBoi(userID uuid.UUID) bool {
+	for _, row := range t.daDuyet {
+		if row.NguoiDuyetID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *pheDuyetService) loadTienDo(ctx context.Context, db *gorm.DB, gp *models.GiayPhep) (*tienDo, error) {
+	cacBuoc, err := s.repo.ListBuocDuyet(ctx, db, gp.LoaiGiayPhep)
+	if err != nil {
+		return nil, err
+	}
+	lichSu, err := s.repo.ListPheDuyet(ctx, db, gp.ID)
+	if err != nil {
+		return nil, err
+	}
+	return tinhTienDo(cacBuoc, lichSu, noiDungHash(gp)), nil
+}
+
+// noiDungHash là hash nội dung được phê duyệt: PDF đã có chữ ký (kể cả ký nháy) mang H2Hash mới
+// của bản nhúng chữ ký, còn các bước được duyệt trên bản gốc
+func noiDungHash(gp *models.GiayPhep) string {
+	if gp.H2HashGoc != nil {
+		return *gp.H2HashGoc
+	}
+	if gp.H2Hash != nil {
+		return *gp.H2Hash
+	}
+	return ""
+}
+
+func (s *pheDuyetService) GetQuyTrinh(ctx context.Context, loaiGiayPhep string) ([]models.BuocDuyet, error) {
+	return s.repo.ListBuocDuyet(ctx, s.db, loaiGiayPhep)
+}
+
+// CauHinhQuyTrinh thay toàn bộ quy trình của một loại giấy phép. Giấy phép đang duyệt dở
+// tiếp tục theo quy trình mới: lượt duyệt cũ chỉ còn tính nếu khớp thứ tự và chức vụ của bước mới.
+func (s *pheDuyetService) CauHinhQuyTrinh(ctx context.Context, req *dto.CauHinhQuyTrinhRequest) ([]models.BuocDuyet, error) {
+	loai := strings.TrimSpace(req.LoaiGiayPhep)
+	cacBuoc := make([]models.BuocDuyet, 0, len(req.CacBuoc))
+	for i, b := range req.CacBuoc {
+		chucVu := strings.ToUpper(strings.TrimSpace(b.ChucVu))
+		if i > 0 && cacBuoc[i-1].ChucVu == chucVu {
+			return nil, ErrQuyTrinhTrungChucVu
+		}
+		cacBuoc = append(cacBuoc, models.BuocDuyet{
+			LoaiGiayPhep: loai,
+			ThuTu:        i + 1,
+			TenBuoc:      strings.TrimSpace(b.TenBuoc),
+			ChucVu:       chucVu,
+		})
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.repo.ReplaceQuyTrinh(ctx, tx, loai, cacBuoc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cacBuoc, nil
+}
+
+func (s *pheDuyetService) UpdateChucVu(ctx context.Context, userID uuid.UUID, chucVu string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNguoiDungKhongTonTai
+		}
+		return err
+	}
+
+	chucVu = strings.ToUpper(strings.TrimSpace(chucVu))
+	if chucVu == "" {
+		return s.userRepo.SetChucVu(userID, nil)
+	}
+	if user.Role.Name != middleware.RoleCanBo && user.Role.Name != middleware.RoleAdmin {
+		return ErrChucVuChiChoCanBo
+	}
+	return s.userRepo.SetChucVu(userID, &chucVu)
+}
+
+func (s *pheDuyetService) getGiayPhep(ctx context.Context, db *gorm.DB, giayPhepID uuid.UUID) (*models.GiayPhep, error) {
+	gp, err := s.gpRepo.GetGiayPhepByID(ctx, db, giayPhepID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGiayPhepKhongTimThay
+		}
+		return nil, err
+	}
+	return gp, nil
+}
+
+func (s *pheDuyetService) GetTrangThai(ctx context.Context, giayPhepID uuid.UUID) (*dto.PheDuyetStatusResponse, error) {
+	gp, err := s.getGiayPhep(ctx, s.db, giayPhepID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.loadTienDo(ctx, s.db, gp)
+	if err != nil {
+		return nil, err
+	}
+	return mapTienDo(gp, t), nil
+}
+
+// PheDuyet ghi quyết định của cán bộ cho bước đang chờ. Dòng giấy phép bị khóa trong lúc ghi
+// để hai người không cùng duyệt một bước.
+func (s *pheDuyetService) PheDuyet(ctx context.Context, giayPhepID uuid.UUID, userID uuid.UUID, req *dto.PheDuyetRequest) (*dto.PheDuyetStatusResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *dto.PheDuyetStatusResponse
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var locked models.GiayPhep
+		if err := tx.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "id = ?", giayPhepID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGiayPhepKhongTimThay
+			}
+			return err
+		}
+		gp := &locked
+
+		switch gp.TrangThaiGiayPhep {
+		case "DaKy":
+			return ErrPheDuyetSauKhiKy
+		case "ThuHoi", "DaHetHan":
+			return ErrGiayPhepDaBiThuHoi
+		}
+		if gp.H2Hash == nil || *gp.H2Hash == "" {
+			return ErrGiayPhepChuaCoFile
+		}
+
+		t, err := s.loadTienDo(ctx, tx, gp)
+		if err != nil {
+			return err
+		}
+		if len(t.cacBuoc) == 0 {
+			return ErrChuaCoQuyTrinhDuyet
+		}
+		if t.sanSangKy() {
+			return ErrBuocCuoiLaKySo
+		}
+		buoc := t.cacBuoc[t.hienTai]
+		if user.ChucVu == nil || *user.ChucVu != buoc.ChucVu {
+			return fmt.Errorf("%w (bước %q cần chức vụ %s)", ErrKhongDungChucVu, buoc.TenBuoc, buoc.ChucVu)
+		}
+		if t.daDuyetBoi(userID) {
+			return ErrDaDuyetBuocKhac
+		}
+
+		row := &models.PheDuyetGiayPhep{
+			GiayPhepID:   gp.ID,
+			ThuTu:        buoc.ThuTu,
+			TenBuoc:      buoc.TenBuoc,
+			ChucVu:       buoc.ChucVu,
+			NguoiDuyetID: userID,
+			QuyetDinh:    req.QuyetDinh,
+			H2Hash:       noiDungHash(gp),
+		}
+		if yKien := strings.TrimSpace(req.YKien); yKien != "" {
+			row.YKien = &yKien
+		}
+		if err := s.repo.CreatePheDuyet(ctx, tx, row); err != nil {
+			return err
+		}
+
+		t, err = s.loadTienDo(ctx, tx, gp)
+		if err != nil {
+			return err
+		}
+		resp = mapTienDo(gp, t)
+		return nil
+	})
+	return resp, err
+}
+
+func (s *pheDuyetService) KiemTraTruocKy(ctx context.Context, gp *models.GiayPhep, user *models.User) error {
+	t, err := s.loadTienDo(ctx, s.db, gp)
+	if err != nil {
+		return err
+	}
+	// Loại giấy phép chưa cấu hình quy trình thì không yêu cầu phê duyệt
+	if len(t.cacBuoc) == 0 {
+		return nil
+	}
+	if !t.sanSangKy() {
+		buoc := t.cacBuoc[t.hienTai]
+		return fmt.Errorf("%w (đang chờ bước %q - %s)", ErrChuaDuPheDuyet, buoc.TenBuoc, buoc.ChucVu)
+	}
+	buocKy := t.cacBuoc[len(t.cacBuoc)-1]
+	if user.ChucVu == nil || *user.ChucVu != buocKy.ChucVu {
+		return fmt.Errorf("%w (bước ký cần chức vụ %s)", ErrKhongDungChucVu, buocKy.ChucVu)
+	}
+	if t.daDuyetBoi(user.ID) {
+		return ErrDaDuyetBuocKhac
+	}
+	return nil
+}
+
+func (s *pheDuyetService) GhiNhanKy(ctx context.Context, gp *models.GiayPhep, h2Hash string, user *models.User) error {
+	cacBuoc, err := s.repo.ListBuocDuyet(ctx, s.db, gp.LoaiGiayPhep)
+	if err != nil || len(cacBuoc) == 0 {
+		return err
+	}
+	buocKy := cacBuoc[len(cacBuoc)-1]
+	yKien := "Ký số giấy phép"
+	return s.repo.CreatePheDuyet(ctx, s.db, &models.PheDuyetGiayPhep{
+		GiayPhepID:   gp.ID,
+		ThuTu:        buocKy.ThuTu,
+		TenBuoc:      buocKy.TenBuoc,
+		ChucVu:       buocKy.ChucVu,
+		NguoiDuyetID: user.ID,
+		QuyetDinh:    models.QuyetDinhDongY,
+		YKien:        &yKien,
+		H2Hash:       h2Hash,
+	})
+}
+
+func mapTienDo(gp *models.GiayPhep, t *tienDo) *dto.PheDuyetStatusResponse {
+	resp := &dto.PheDuyetStatusResponse{
+		GiayPhepID:   gp.ID,
+		LoaiGiayPhep: gp.LoaiGiayPhep,
+		CacBuoc:      make([]dto.BuocDuyetStatus, 0, len(t.cacBuoc)),
+		SanSangKy:    t.sanSangKy(),
+		LichSu:       make([]dto.PheDuyetLichSu, 0, len(t.lichSu)),
+	}
+
+	for i, b := range t.cacBuoc {
+		item := dto.BuocDuyetStatus{
+			ThuTu:     b.ThuTu,
+			TenBuoc:   b.TenBuoc,
+			ChucVu:    b.ChucVu,
+			LaBuocKy:  i == len(t.cacBuoc)-1,
+			TrangThai: dto.TrangThaiBuocChuaToi,
+		}
+		switch {
+		case i < t.hienTai:
+			row := t.daDuyet[b.ThuTu]
+			item.TrangThai = dto.TrangThaiBuocDaDuyet
+			item.NguoiDuyet = &dto.NguoiDuyetInfo{ID: row.NguoiDuyetID, HoTen: row.NguoiDuyet.FullName}
+			item.ThoiDiem = &row.CreatedAt
+		case i == t.hienTai:
+			item.TrangThai = dto.TrangThaiBuocChoDuyet
+		}
+		resp.CacBuoc = append(resp.CacBuoc, item)
+	}
+
+	for _, row := range t.lichSu {
+		resp.LichSu = append(resp.LichSu, dto.PheDuyetLichSu{
+			ID:         row.ID,
+			ThuTu:      row.ThuTu,
+			TenBuoc:    row.TenBuoc,
+			ChucVu:     row.ChucVu,
+			NguoiDuyet: dto.NguoiDuyetInfo{ID: row.NguoiDuyetID, HoTen: row.NguoiDuyet.FullName},
+			QuyetDinh:  row.QuyetDinh,
+			YKien:      row.YKien,
+			H2Hash:     row.H2Hash,
+			CreatedAt:  row.CreatedAt,
+		})
+	}
+	return resp
+}
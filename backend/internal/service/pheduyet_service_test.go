@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+)
+
+func TestTinhTienDo(t *testing.T) {
+	cacBuoc := []models.BuocDuyet{
+		{ThuTu: 1, TenBuoc: "Thẩm định", ChucVu: "CHUYEN_VIEN"},
+		{ThuTu: 2, TenBuoc: "Duyệt", ChucVu: "TRUONG_PHONG"},
+		{ThuTu: 3, TenBuoc: "Ký số", ChucVu: "LANH_DAO"},
+	}
+	chuyenVien, truongPhong := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	const fileMoi, fileCu = "h2-moi", "h2-cu"
+
+	dongY := func(thuTu int, chucVu string, nguoi uuid.UUID, h2 string) models.PheDuyetGiayPhep {
+		return models.PheDuyetGiayPhep{ThuTu: thuTu, ChucVu: chucVu, NguoiDuyetID: nguoi, QuyetDinh: models.QuyetDinhDongY, H2Hash: h2}
+	}
+	tuChoi := func(thuTu int, chucVu string, nguoi uuid.UUID) models.PheDuyetGiayPhep {
+		return models.PheDuyetGiayPhep{ThuTu: thuTu, ChucVu: chucVu, NguoiDuyetID: nguoi, QuyetDinh: models.QuyetDinhTuChoi, H2Hash: fileMoi}
+	}
+	buoc1 := dongY(1, "CHUYEN_VIEN", chuyenVien, fileMoi)
+	buoc2 := dongY(2, "TRUONG_PHONG", truongPhong, fileMoi)
+
+	tests := []struct {
+		ten          string
+		lichSu       []models.PheDuyetGiayPhep
+		hienTai      int
+		sanSangKy    bool
+		daDuyetBoiTP bool
+	}{
+		{ten: "chưa duyệt", hienTai: 0},
+		{ten: "xong bước 1", lichSu: []models.PheDuyetGiayPhep{buoc1}, hienTai: 1},
+		{ten: "xong các bước trước ký", lichSu: []models.PheDuyetGiayPhep{buoc1, buoc2}, hienTai: 2, sanSangKy: true, daDuyetBoiTP: true},
+		{ten: "từ chối làm lại từ đầu", lichSu: []models.PheDuyetGiayPhep{buoc1, buoc2, tuChoi(3, "LANH_DAO", uuid.Nil)}, hienTai: 0},
+		{ten: "duyệt lại sau từ chối", lichSu: []models.PheDuyetGiayPhep{buoc1, tuChoi(2, "TRUONG_PHONG", truongPhong), buoc1, buoc2}, hienTai: 2, sanSangKy: true, daDuyetBoiTP: true},
+		{ten: "đã thay file", lichSu: []models.PheDuyetGiayPhep{
+			dongY(1, "CHUYEN_VIEN", chuyenVien, fileCu), dongY(2, "TRUONG_PHONG", truongPhong, fileCu),
+		}, hienTai: 0},
+		{ten: "duyệt lại trên file mới", lichSu: []models.PheDuyetGiayPhep{
+			dongY(1, "CHUYEN_VIEN", chuyenVien, fileCu), dongY(2, "TRUONG_PHONG", truongPhong, fileCu), buoc1,
+		}, hienTai: 1},
+		{ten: "lượt duyệt file cũ sau file mới", lichSu: []models.PheDuyetGiayPhep{buoc1, dongY(2, "TRUONG_PHONG", truongPhong, fileCu)}, hienTai: 0},
+		{ten: "chức vụ không khớp quy trình", lichSu: []models.PheDuyetGiayPhep{dongY(1, "TRUONG_PHONG", truongPhong, fileMoi)}, hienTai: 0},
+		{ten: "bỏ qua bước 1", lichSu: []models.PheDuyetGiayPhep{buoc2}, hienTai: 0, daDuyetBoiTP: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ten, func(t *testing.T) {
+			td := tinhTienDo(cacBuoc, tc.lichSu, fileMoi)
+			if td.hienTai != tc.hienTai {
+				t.Errorf("hienTai = %d, muốn %d", td.hienTai, tc.hienTai)
+			}
+			if td.sanSangKy() != tc.sanSangKy {
+				t.Errorf("sanSangKy = %v, muốn %v", td.sanSangKy(), tc.sanSangKy)
+			}
+			if td.daDuyetBoi(truongPhong) != tc.daDuyetBoiTP {
+				t.Errorf("daDuyetBoi(trưởng phòng) = %v, muốn %v", td.daDuyetBoi(truongPhong), tc.daDuyetBoiTP)
+			}
+		})
+	}
+}
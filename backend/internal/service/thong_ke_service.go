@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// SoNgaySapDenHanMacDinh: hồ sơ đến hạn trong số ngày làm việc này được coi là sắp đến hạn
+const SoNgaySapDenHanMacDinh = 3
+
+type ThongKeService interface {
+	ThongKeSLA(ctx context.Context, soNgaySapDenHan int) (*dto.ThongKeSLAResponse, error)
+	ListQuaHan(ctx context.Context, params *dto.HoSoQuaHanSearchParams, canBoID uuid.UUID, page int, pageSize int) (*dto.HoSoQuaHanListResponse, error)
+}
+
+type thongKeService struct {
+	db      *gorm.DB
+	repo    repository.ThongKeRepository
+	hanXuLy HanXuLy
+}
+
+func NewThongKeService(db *gorm.DB, repo repository.ThongKeRepository, hanXuLy HanXuLy) ThongKeService {
+	return &thongKeService{db: db, repo: repo, hanXuLy: hanXuLy}
+}
+
+// ThongKeSLA phân loại hồ sơ đang giải quyết: quá hạn (đã qua ngày hẹn trả), sắp đến hạn
+// (hạn trả trong soNgaySapDenHan ngày làm việc tới), đúng hạn, và tạm dừng (đang bị trả lại).
+// Việc đếm thực hiện bằng SQL, không tải từng hồ sơ.
+func (s *thongKeService) ThongKeSLA(ctx context.Context, soNgaySapDenHan int) (*dto.ThongKeSLAResponse, error) {
+	now := time.Now()
+	moc, err := s.hanXuLy.CongNgayLamViec(ctx, s.db, now, soNgaySapDenHan)
+	if err != nil {
+		return nil, err
+	}
+
+	tong, err := s.repo.DemSLA(ctx, s.db, now, moc)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi thống kê SLA: %w", err)
+	}
+	theoCanBo, err := s.repo.DemSLATheoCanBo(ctx, s.db, now, moc)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi thống kê SLA theo cán bộ: %w", err)
+	}
+	theoThuTuc, err := s.repo.DemSLATheoThuTuc(ctx, s.db, now, moc)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi thống kê SLA theo thủ tục: %w", err)
+	}
+
+	return &dto.ThongKeSLAResponse{
+		ThoiDiem:        now,
+		MocSapDenHan:    moc,
+		SoNgaySapDenHan: soNgaySapDenHan,
+		Tong:            *tong,
+		TheoCanBo:       theoCanBo,
+		TheoThuTuc:      theoThuTuc,
+	}, nil
+}
+
+func (s *thongKeService) ListQuaHan(ctx context.Context, params *dto.HoSoQuaHanSearchParams, canBoID uuid.UUID, page int, pageSize int) (*dto.HoSoQuaHanListResponse, error) {
+	list, total, err := s.repo.ListQuaHan(ctx, s.db, time.Now(), params.LoaiThuTuc, canBoID, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi lấy danh sách hồ sơ quá hạn: %w", err)
+	}
+	if list == nil {
+		list = []dto.HoSoQuaHanResponse{}
+	}
+	return &dto.HoSoQuaHanListResponse{
+		Data:     list,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	}, nil
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrThuTucKhongTimThay     = errors.New("không tìm thấy thủ tục")
+	ErrThuTucTrungTen         = errors.New("tên thủ tục đã tồn tại")
+	ErrThuTucDaCoHoSo         = errors.New("thủ tục đã có hồ sơ, không thể đổi tên hoặc xóa; hãy chuyển sang ngừng hoạt động")
+	ErrLoaiTaiLieuKhongTonTai = errors.New("loại tài liệu không tồn tại")
+	ErrLoaiTaiLieuLapLai      = errors.New("một loại tài liệu xuất hiện nhiều lần trong thành phần hồ sơ")
+	ErrLoaiTaiLieuTrungTen    = errors.New("tên loại tài liệu đã tồn tại")
+	ErrThieuThoiHanGiayPhep   = errors.New("thủ tục cấp giấy phép phải có thời hạn hiệu lực của giấy phép")
+)
+
+// ThuTucService quản lý danh mục thủ tục: thời hạn giải quyết, thành phần hồ sơ và loại giấy phép được cấp
+type ThuTucService interface {
+	ListThuTuc(ctx context.Context, chiHoatDong bool) ([]models.ThuTuc, error)
+	GetThuTuc(ctx context.Context, id uuid.UUID) (*models.ThuTuc, error)
+	CreateThuTuc(ctx context.Context, req *dto.ThuTucRequest) (*models.ThuTuc, error)
+	UpdateThuTuc(ctx context.Context, id uuid.UUID, req *dto.ThuTucRequest) (*models.ThuTuc, error)
+	DeleteThuTuc(ctx context.Context, id uuid.UUID) error
+	ListLoaiTaiLieu(ctx context.Context) ([]models.LoaiTaiLieu, error)
+	CreateLoaiTaiLieu(ctx context.Context, req *dto.CreateLoaiTaiLieuRequest) (*models.LoaiTaiLieu, error)
+}
+
+type thuTucService struct {
+	db          *gorm.DB
+	repo        repository.ThuTucRepository
+	tailieuRepo repository.TaiLieuRepository
+}
+
+func NewThuTucService(db *gorm.DB, repo repository.ThuTucRepository, tailieuRepo repository.TaiLieuRepository) ThuTucService {
+	return &thuTucService{db: db, repo: repo, tailieuRepo: tailieuRepo}
+}
+
+func laLoiTrungLap(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (s *thuTucService) ListThuTuc(ctx context.Context, chiHoatDong bool) ([]models.ThuTuc, error) {
+	return s.repo.ListThuTuc(ctx, s.db, chiHoatDong)
+}
+
+func (s *thuTucService) GetThuTuc(ctx context.Context, id uuid.UUID) (*models.ThuTuc, error) {
+	thuTuc, err := s.repo.GetThuTucByID(ctx, s.db, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrThuTucKhongTimThay
+		}
+		return nil, err
+	}
+	return thuTuc, nil
+}
+
+// thanhPhanHoSo kiểm tra và dựng thành phần hồ sơ từ request, thứ tự theo thứ tự gửi lên
+func (s *thuTucService) thanhPhanHoSo(ctx context.Context, db *gorm.DB, thuTucID uuid.UUID, inputs []dto.ThuTucTaiLieuInput) ([]models.ThuTucTaiLieu, error) {
+	ids := make([]uuid.UUID, 0, len(inputs))
+	daCo := map[uuid.UUID]bool{}
+	taiLieus := make([]models.ThuTucTaiLieu, len(inputs))
+	for i, in := range inputs {
+		if daCo[in.LoaiTaiLieuID] {
+			return nil, ErrLoaiTaiLieuLapLai
+		}
+		daCo[in.LoaiTaiLieuID] = true
+		ids = append(ids, in.LoaiTaiLieuID)
+
+		taiLieus[i] = models.ThuTucTaiLieu{
+			ThuTucID:      thuTucID,
+			LoaiTaiLieuID: in.LoaiTaiLieuID,
+			BatBuoc:       in.BatBuoc == nil || *in.BatBuoc,
+			ThuTu:         i + 1,
+		}
+	}
+
+	count, err := s.repo.CountLoaiTaiLieu(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+	if count != int64(len(ids)) {
+		return nil, ErrLoaiTaiLieuKhongTonTai
+	}
+	return taiLieus, nil
+}
+
+// ganThongTin chép các trường chung từ request vào thủ tục
+func ganThongTin(thuTuc *models.ThuTuc, req *dto.ThuTucRequest) {
+	thuTuc.Ten = strings.TrimSpace(req.Ten)
+	thuTuc.MoTa = req.MoTa
+	thuTuc.SoNgayLamViec = req.SoNgayLamViec
+	thuTuc.LoaiGiayPhep = nil
+	thuTuc.SoThangHieuLuc = nil
+	if req.LoaiGiayPhep != nil {
+		if loai := strings.TrimSpace(*req.LoaiGiayPhep); loai != "" {
+			thuTuc.LoaiGiayPhep = &loai
+			thuTuc.SoThangHieuLuc = req.SoThangHieuLuc
+		}
+	}
+	thuTuc.HoatDong = req.HoatDong == nil || *req.HoatDong
+	thuTuc.ThuTuHienThi = req.ThuTuHienThi
+}
+
+func (s *thuTucService) CreateThuTuc(ctx context.Context, req *dto.ThuTucRequest) (*models.ThuTuc, error) {
+	var thuTuc models.ThuTuc
+	ganThongTin(&thuTuc, req)
+	if thuTuc.LoaiGiayPhep != nil && thuTuc.SoThangHieuLuc == nil {
+		return nil, ErrThieuThoiHanGiayPhep
+	}
+
+	var id uuid.UUID
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		taiLieus, err := s.thanhPhanHoSo(ctx, tx, uuid.Nil, req.TaiLieus)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.CreateThuTuc(ctx, tx, &thuTuc); err != nil {
+			if laLoiTrungLap(err) {
+				return ErrThuTucTrungTen
+			}
+			return fmt.Errorf("lỗi khi tạo thủ tục: %w", err)
+		}
+		for i := range taiLieus {
+			taiLieus[i].ThuTucID = thuTuc.ID
+		}
+		id = thuTuc.ID
+		return s.repo.ReplaceTaiLieu(ctx, tx, thuTuc.ID, taiLieus)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetThuTuc(ctx, id)
+}
+
+// UpdateThuTuc sửa thủ tục và thay thành phần hồ sơ. Hồ sơ đã tạo giữ nguyên các khe tài liệu cũ;
+// thủ tục đã có hồ sơ không được đổi tên vì hồ sơ tham chiếu thủ tục theo tên.
+func (s *thuTucService) UpdateThuTuc(ctx context.Context, id uuid.UUID, req *dto.ThuTucRequest) (*models.ThuTuc, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		thuTuc, err := s.repo.GetThuTucByID(ctx, tx, id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrThuTucKhongTimThay
+			}
+			return err
+		}
+
+		tenCu := thuTuc.Ten
+		ganThongTin(thuTuc, req)
+		if thuTuc.LoaiGiayPhep != nil && thuTuc.SoThangHieuLuc == nil {
+			return ErrThieuThoiHanGiayPhep
+		}
+		if thuTuc.Ten != tenCu {
+			soHoSo, err := s.repo.CountHoSo(ctx, tx, tenCu)
+			if err != nil {
+				return err
+			}
+			if soHoSo > 0 {
+				return ErrThuTucDaCoHoSo
+			}
+		}
+
+		taiLieus, err := s.thanhPhanHoSo(ctx, tx, thuTuc.ID, req.TaiLieus)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.UpdateThuTuc(ctx, tx, thuTuc); err != nil {
+			if laLoiTrungLap(err) {
+				return ErrThuTucTrungTen
+			}
+			return fmt.Errorf("lỗi khi cập nhật thủ tục: %w", err)
+		}
+		return s.repo.ReplaceTaiLieu(ctx, tx, thuTuc.ID, taiLieus)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetThuTuc(ctx, id)
+}
+
+func (s *thuTucService) DeleteThuTuc(ctx context.Context, id uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		thuTuc, err := s.repo.GetThuTucByID(ctx, tx, id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrThuTucKhongTimThay
+			}
+			return err
+		}
+		soHoSo, err := s.repo.CountHoSo(ctx, tx, thuTuc.Ten)
+		if err != nil {
+			return err
+		}
+		if soHoSo > 0 {
+			return ErrThuTucDaCoHoSo
+		}
+		return s.repo.DeleteThuTuc(ctx, tx, id)
+	})
+}
+
+func (s *thuTucService) ListLoaiTaiLieu(ctx context.Context) ([]models.LoaiTaiLieu, error) {
+	return s.tailieuRepo.ListLoaiTaiLieu(ctx, s.db, nil)
+}
+
+func (s *thuTucService) CreateLoaiTaiLieu(ctx context.Context, req *dto.CreateLoaiTaiLieuRequest) (*models.LoaiTaiLieu, error) {
+	loaiTaiLieu := &models.LoaiTaiLieu{
+		Ten:  strings.TrimSpace(req.Ten),
+		MoTa: strings.TrimSpace(req.MoTa),
+	}
+	if err := s.tailieuRepo.CreateLoaiTaiLieu(ctx, s.db, loaiTaiLieu); err != nil {
+		if laLoiTrungLap(err) {
+			return nil, ErrLoaiTaiLieuTrungTen
+		}
+		return nil, fmt.Errorf("lỗi khi tạo loại tài liệu: %w", err)
+	}
+	return loaiTaiLieu, nil
+}
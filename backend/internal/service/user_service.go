@@ -1,46 +1,129 @@
 package service
 
 import (
+	"crypto/subtle"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/ca"
 	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/keystore"
 	"github.com/vnkmasc/KmaERM/backend/internal/middleware"
 	"github.com/vnkmasc/KmaERM/backend/internal/models"
 	"github.com/vnkmasc/KmaERM/backend/internal/repository"
+	"github.com/vnkmasc/KmaERM/backend/internal/signer"
 	"github.com/vnkmasc/KmaERM/backend/utils"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+var (
+	ErrRefreshTokenKhongHopLe = errors.New("refresh token không hợp lệ hoặc đã hết hạn")
+	ErrNguoiDungKhongTonTai   = errors.New("không tìm thấy người dùng")
+
+	ErrCanMaHaiLop       = errors.New("yêu cầu mã xác thực 2 lớp (TOTP)")
+	ErrMaHaiLopKhongDung = errors.New("mã xác thực 2 lớp không chính xác hoặc đã được sử dụng")
+	ErrHaiLopChuaBat     = errors.New("tài khoản chưa bật xác thực 2 lớp")
+	ErrHaiLopDaBat       = errors.New("xác thực 2 lớp đã được bật")
+	ErrHaiLopChuaKhoiTao = errors.New("chưa khởi tạo xác thực 2 lớp, hãy gọi bước thiết lập trước")
+	ErrKhongTheTatHaiLop = errors.New("tài khoản cán bộ/quản trị bắt buộc dùng xác thực 2 lớp")
+	ErrMatKhauKhongDung  = errors.New("mật khẩu không chính xác")
+
+	ErrOTPKhongDung   = errors.New("mã OTP không chính xác")
+	ErrOTPHetHan      = errors.New("mã OTP đã hết hạn")
+	ErrOTPBiVoHieuHoa = errors.New("nhập sai OTP quá nhiều lần, mã đã bị hủy. Vui lòng yêu cầu mã mới")
+)
+
+// Số lần nhập sai tối đa cho một mã OTP quên mật khẩu
+const maxOTPAttempts = 5
+
+const recoveryCodeCount = 10
+
+// StepUpVerifier xác nhận lại danh tính (mã TOTP mới) trước thao tác nhạy cảm như ký số
+type StepUpVerifier interface {
+	VerifyStepUp(userID uuid.UUID, code string) error
+}
+
+const defaultRefreshTokenTTL = 7 * 24 * time.Hour
+
+// refreshTokenTTL có thể cấu hình qua REFRESH_TOKEN_TTL (ví dụ "168h")
+func refreshTokenTTL() time.Duration {
+	if v := os.Getenv("REFRESH_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultRefreshTokenTTL
+}
+
 type UserService interface {
-	Login(req *dto.LoginRequest) (*dto.LoginResponse, error)
+	Login(req *dto.LoginRequest, meta dto.ClientMeta) (*dto.LoginResponse, error)
+	GetLockState(userID uuid.UUID) (*dto.UserLockStateResponse, error)
+	Unlock(userID uuid.UUID) error
+	Refresh(refreshToken string, meta dto.ClientMeta) (*dto.TokenResponse, error)
+	Logout(sessionID uuid.UUID) error
+	LogoutAll(userID uuid.UUID) error
+	SetActive(userID uuid.UUID, active bool) error
+	ConfigureSigner(userID uuid.UUID, req *dto.UpdateSignerRequest) (*dto.SignerInfoResponse, error)
 	CreateCanBo(req *dto.CreateCanBoRequest) error
 	ChangePassword(userID uuid.UUID, req *dto.ChangePasswordRequest) error
-	ResetPassword(req *dto.ResetPasswordRequest) error
-	ForgotPassword(email string) error
-	VerifyOTP(email, otpInput string) error
+	ResetPassword(req *dto.ResetPasswordRequest, meta dto.ClientMeta) error
+	ForgotPassword(email string, meta dto.ClientMeta) error
+	VerifyOTP(email, otpInput string, meta dto.ClientMeta) error
+
+	SetupTOTP(userID uuid.UUID) (*dto.TOTPSetupResponse, error)
+	EnableTOTP(userID uuid.UUID, code string) (*dto.RecoveryCodesResponse, error)
+	DisableTOTP(userID uuid.UUID, req *dto.DisableTOTPRequest) error
+	RegenerateRecoveryCodes(userID uuid.UUID, code string) (*dto.RecoveryCodesResponse, error)
+	StepUpVerifier
 }
 
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	throttle    *authThrottle
+	keys        *keystore.Keystore
+	signers     *signer.Provider
+	ca          *ca.Authority
+	db          *gorm.DB
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
-	return &userService{userRepo: userRepo}
+func NewUserService(db *gorm.DB, userRepo repository.UserRepository, sessionRepo repository.SessionRepository, throttleRepo repository.ThrottleRepository, keys *keystore.Keystore, signers *signer.Provider, authority *ca.Authority) UserService {
+	return &userService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		throttle:    &authThrottle{repo: throttleRepo},
+		keys:        keys,
+		signers:     signers,
+		ca:          authority,
+		db:          db,
+	}
 }
 
-func (s *userService) Login(req *dto.LoginRequest) (*dto.LoginResponse, error) {
+func (s *userService) Login(req *dto.LoginRequest, meta dto.ClientMeta) (*dto.LoginResponse, error) {
+	// 0. Chống dò mật khẩu: tài khoản/IP đang bị khóa tạm thì từ chối ngay (không kiểm tra mật khẩu)
+	if err := s.throttle.Check(models.ThrottleScopeLogin, req.Email, meta.IPAddress); err != nil {
+		return nil, err
+	}
+
 	// 1. Tìm user trong DB
 	user, err := s.userRepo.GetByEmail(req.Email)
 	if err != nil {
+		// Email không tồn tại vẫn tính là một lần sai để không lộ email nào có thật
+		s.throttle.Fail(models.ThrottleScopeLogin, req.Email, meta.IPAddress)
 		return nil, errors.New("thông tin đăng nhập không chính xác") // Không nên báo cụ thể lỗi email để bảo mật
 	}
 
 	// 2. Kiểm tra mật khẩu (So sánh Hash)
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
 	if err != nil {
+		s.throttle.Fail(models.ThrottleScopeLogin, req.Email, meta.IPAddress)
 		return nil, errors.New("thông tin đăng nhập không chính xác") // Sai pass
 	}
 
@@ -49,9 +132,31 @@ func (s *userService) Login(req *dto.LoginRequest) (*dto.LoginResponse, error) {
 		return nil, errors.New("tài khoản đã bị khóa")
 	}
 
-	// 4. Tạo JWT Token
-	token, err := middleware.GenerateToken(user.ID, user.RoleID, user.DoanhNghiepID)
-	if err != nil {
+	// 3b. Xác thực 2 lớp (nếu đã bật)
+	if user.TOTPEnabled {
+		if req.TOTPCode == "" && req.RecoveryCode == "" {
+			return nil, ErrCanMaHaiLop
+		}
+		if err := s.verifySecondFactor(user, req.TOTPCode, req.RecoveryCode); err != nil {
+			if errors.Is(err, ErrMaHaiLopKhongDung) {
+				s.throttle.Fail(models.ThrottleScopeLogin, req.Email, meta.IPAddress)
+			}
+			return nil, err
+		}
+	}
+	s.throttle.Success(models.ThrottleScopeLogin, req.Email)
+
+	// 4. Tạo phiên đăng nhập + cặp access/refresh token.
+	// Cán bộ/quản trị chưa bật 2FA chỉ nhận token đăng ký 2FA, bật xong phải đăng nhập lại kèm mã TOTP.
+	dangKyHaiLop := requiresTOTP(user) && !user.TOTPEnabled
+	var tokens *dto.TokenResponse
+	if dangKyHaiLop {
+		accessToken, ttl, err := middleware.GenerateEnrollmentToken(user.ID, user.RoleID)
+		if err != nil {
+			return nil, errors.New("lỗi tạo token")
+		}
+		tokens = &dto.TokenResponse{AccessToken: accessToken, ExpiresIn: int64(ttl.Seconds())}
+	} else if _, tokens, err = s.issueSession(nil, user, meta); err != nil {
 		return nil, errors.New("lỗi tạo token")
 	}
 
@@ -63,7 +168,9 @@ func (s *userService) Login(req *dto.LoginRequest) (*dto.LoginResponse, error) {
 
 	// Lưu ý: Chuyển UUID sang string cẩn thận
 	res := &dto.LoginResponse{
-		AccessToken: token,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
 		User: dto.UserSummary{
 			ID:            user.ID.String(),
 			Email:         user.Email,
@@ -76,10 +183,130 @@ func (s *userService) Login(req *dto.LoginRequest) (*dto.LoginResponse, error) {
 	if user.DoanhNghiepID == nil {
 		res.User.DoanhNghiepID = nil
 	}
+	res.TOTPEnrollmentRequired = dangKyHaiLop
+
+	return res, nil
+}
+
+// issueSession tạo một dòng user_sessions mới và phát hành access token gắn với phiên đó
+func (s *userService) issueSession(tx *gorm.DB, user *models.User, meta dto.ClientMeta) (*models.UserSession, *dto.TokenResponse, error) {
+	refreshToken, refreshHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session := &models.UserSession{
+		UserID:           user.ID,
+		RefreshTokenHash: refreshHash,
+		UserAgent:        meta.UserAgent,
+		IPAddress:        meta.IPAddress,
+		ExpiresAt:        time.Now().Add(refreshTokenTTL()),
+	}
+	if err := s.sessionRepo.Create(tx, session); err != nil {
+		return nil, nil, err
+	}
+
+	accessToken, err := middleware.GenerateToken(user.ID, user.RoleID, user.DoanhNghiepID, session.ID)
+	if err != nil {
+		return nil, nil, err
+	}
 
+	return session, &dto.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(middleware.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// Refresh xoay vòng refresh token: token cũ bị thu hồi và trỏ tới phiên mới.
+// Nếu một token đã bị xoay vòng được dùng lại (dấu hiệu bị đánh cắp) thì thu hồi toàn bộ phiên của user.
+func (s *userService) Refresh(refreshToken string, meta dto.ClientMeta) (*dto.TokenResponse, error) {
+	var res *dto.TokenResponse
+	var reusedBy uuid.UUID
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		session, err := s.sessionRepo.GetByTokenHashForUpdate(tx, utils.HashToken(refreshToken))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefreshTokenKhongHopLe
+			}
+			return err
+		}
+
+		if session.RevokedAt != nil {
+			if session.ReplacedBy != nil {
+				reusedBy = session.UserID
+			}
+			return ErrRefreshTokenKhongHopLe
+		}
+		if time.Now().After(session.ExpiresAt) {
+			return ErrRefreshTokenKhongHopLe
+		}
+
+		user, err := s.userRepo.GetByID(session.UserID)
+		if err != nil || !user.IsActive {
+			return ErrRefreshTokenKhongHopLe
+		}
+
+		newSession, tokens, err := s.issueSession(tx, user, meta)
+		if err != nil {
+			return err
+		}
+		res = tokens
+		return s.sessionRepo.MarkRotated(tx, session.ID, newSession.ID)
+	})
+
+	// Thu hồi ngoài transaction trên (transaction đó đã rollback vì trả lỗi)
+	if reusedBy != uuid.Nil {
+		if revokeErr := s.revokeAll(reusedBy); revokeErr != nil {
+			log.Printf("Lỗi thu hồi phiên khi phát hiện dùng lại refresh token (user %s): %v", reusedBy, revokeErr)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
 	return res, nil
 }
 
+func (s *userService) Logout(sessionID uuid.UUID) error {
+	return s.sessionRepo.Revoke(nil, sessionID)
+}
+
+func (s *userService) LogoutAll(userID uuid.UUID) error {
+	return s.revokeAll(userID)
+}
+
+// revokeAll thu hồi mọi phiên và mọi access token đã phát hành của user
+func (s *userService) revokeAll(userID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.revokeAllTx(tx, userID)
+	})
+}
+
+func (s *userService) revokeAllTx(tx *gorm.DB, userID uuid.UUID) error {
+	if err := s.userRepo.RevokeTokens(tx, userID, time.Now()); err != nil {
+		return err
+	}
+	return s.sessionRepo.RevokeAllByUser(tx, userID)
+}
+
+// SetActive khóa/mở khóa tài khoản. Khóa tài khoản có hiệu lực ngay với các token đang dùng.
+func (s *userService) SetActive(userID uuid.UUID, active bool) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userRepo.SetActive(tx, userID, active); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNguoiDungKhongTonTai
+			}
+			return err
+		}
+		if active {
+			return nil
+		}
+		return s.revokeAllTx(tx, userID)
+	})
+}
+
 func (s *userService) CreateCanBo(req *dto.CreateCanBoRequest) error {
 	// A. Kiểm tra Email (Giữ nguyên)
 	if existing, _ := s.userRepo.GetByEmail(req.Email); existing != nil && existing.Email != "" {
@@ -105,6 +332,12 @@ func (s *userService) CreateCanBo(req *dto.CreateCanBoRequest) error {
 		return errors.New("lỗi khởi tạo chữ ký số cho cán bộ")
 	}
 
+	// Private key không bao giờ được lưu ở dạng bản rõ
+	sealed, err := s.keys.Seal(privKey)
+	if err != nil {
+		return fmt.Errorf("lỗi mã hóa khóa ký cho cán bộ: %w", err)
+	}
+
 	// D. Tạo User Model (Cập nhật thêm Key)
 	newCanBo := models.User{
 		Email:         req.Email,
@@ -114,13 +347,28 @@ func (s *userService) CreateCanBo(req *dto.CreateCanBoRequest) error {
 		RoleID:        role.ID,
 		DoanhNghiepID: nil,
 
-		// Lưu Key vào DB
-		PrivateKeyPEM: &privKey,
+		// Lưu Key (đã mã hóa) vào DB
+		PrivateKeyEnc: &sealed.PrivateKeyEnc,
+		DEKEnc:        &sealed.DEKEnc,
+		KEKVersion:    &sealed.KEKVersion,
 		PublicKeyPEM:  &pubKey,
 	}
 
-	// E. Lưu vào DB (Giữ nguyên)
-	return s.userRepo.Create(nil, &newCanBo)
+	pub, err := signer.ParsePublicKeyPEM(pubKey)
+	if err != nil {
+		return err
+	}
+
+	// E. Lưu vào DB và cấp chứng thư số (CN = họ tên, SAN = email) trong cùng transaction
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userRepo.Create(tx, &newCanBo); err != nil {
+			return err
+		}
+		if _, err := s.ca.Issue(tx, &newCanBo, pub); err != nil {
+			return fmt.Errorf("lỗi cấp chứng thư số cho cán bộ: %w", err)
+		}
+		return nil
+	})
 }
 
 func (s *userService) ChangePassword(userID uuid.UUID, req *dto.ChangePasswordRequest) error {
@@ -140,11 +388,22 @@ func (s *userService) ChangePassword(userID uuid.UUID, req *dto.ChangePasswordRe
 		return err
 	}
 
-	// Cập nhật mật khẩu mới (Không quan tâm OTP)
-	return s.userRepo.UpdatePassword(userID, string(newHashedPass))
+	// Cập nhật mật khẩu mới (Không quan tâm OTP) và đăng xuất mọi thiết bị trong cùng transaction,
+	// tránh đổi được mật khẩu mà các phiên cũ vẫn còn hiệu lực
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userRepo.UpdatePassword(tx, userID, string(newHashedPass)); err != nil {
+			return err
+		}
+		return s.revokeAllTx(tx, userID)
+	})
 }
 
-func (s *userService) ForgotPassword(email string) error {
+func (s *userService) ForgotPassword(email string, meta dto.ClientMeta) error {
+	// Đang bị khóa do nhập sai OTP thì không cấp mã mới (tránh vòng lặp xin mã - dò mã)
+	if err := s.throttle.Check(models.ThrottleScopeOTP, email, meta.IPAddress); err != nil {
+		return err
+	}
+
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
 		return errors.New("email không tồn tại trong hệ thống")
@@ -158,6 +417,7 @@ func (s *userService) ForgotPassword(email string) error {
 	expiry := time.Now().Add(5 * time.Minute)
 	user.OtpCode = &otp
 	user.OtpExpiry = &expiry
+	user.OtpFailedCount = 0
 
 	if err := s.userRepo.Update(user); err != nil {
 		return errors.New("lỗi hệ thống khi lưu OTP")
@@ -175,32 +435,60 @@ func (s *userService) ForgotPassword(email string) error {
 	return nil
 }
 
-func (s *userService) VerifyOTP(email, otpInput string) error {
-	user, err := s.userRepo.GetByEmail(email)
-	if err != nil {
-		return errors.New("email không tồn tại")
-	}
+// checkOTP so khớp OTP quên mật khẩu, đếm số lần sai theo tài khoản/IP
+// và hủy mã sau maxOTPAttempts lần sai (6 chữ số không đủ chống dò trong 5 phút).
+func (s *userService) checkOTP(user *models.User, otpInput string, meta dto.ClientMeta) error {
+	if user.OtpCode == nil || subtle.ConstantTimeCompare([]byte(*user.OtpCode), []byte(otpInput)) != 1 {
+		s.throttle.Fail(models.ThrottleScopeOTP, user.Email, meta.IPAddress)
+		if user.OtpCode == nil {
+			return ErrOTPKhongDung
+		}
 
-	if user.OtpCode == nil || *user.OtpCode != otpInput {
-		return errors.New("mã OTP không chính xác")
+		count, err := s.userRepo.IncrementOTPFailure(user.ID)
+		if err != nil {
+			return err
+		}
+		if count >= maxOTPAttempts {
+			if err := s.userRepo.InvalidateOTP(user.ID); err != nil {
+				return err
+			}
+			return ErrOTPBiVoHieuHoa
+		}
+		return ErrOTPKhongDung
 	}
 	if user.OtpExpiry == nil || time.Now().After(*user.OtpExpiry) {
-		return errors.New("mã OTP đã hết hạn")
+		return ErrOTPHetHan
 	}
 	return nil
 }
 
-func (s *userService) ResetPassword(req *dto.ResetPasswordRequest) error {
+func (s *userService) VerifyOTP(email, otpInput string, meta dto.ClientMeta) error {
+	if err := s.throttle.Check(models.ThrottleScopeOTP, email, meta.IPAddress); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		s.throttle.Fail(models.ThrottleScopeOTP, email, meta.IPAddress)
+		return errors.New("email không tồn tại")
+	}
+
+	return s.checkOTP(user, otpInput, meta)
+}
+
+func (s *userService) ResetPassword(req *dto.ResetPasswordRequest, meta dto.ClientMeta) error {
+	if err := s.throttle.Check(models.ThrottleScopeOTP, req.Email, meta.IPAddress); err != nil {
+		return err
+	}
+
 	user, err := s.userRepo.GetByEmail(req.Email)
 	if err != nil {
+		s.throttle.Fail(models.ThrottleScopeOTP, req.Email, meta.IPAddress)
 		return errors.New("người dùng không tồn tại")
 	}
 
-	if user.OtpCode == nil || *user.OtpCode != req.Otp {
-		return errors.New("mã OTP không chính xác")
-	}
-	if user.OtpExpiry == nil || time.Now().After(*user.OtpExpiry) {
-		return errors.New("mã OTP đã hết hạn")
+	if err := s.checkOTP(user, req.Otp, meta); err != nil {
+		return err
 	}
 
 	newHashedPass, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
@@ -211,6 +499,340 @@ func (s *userService) ResetPassword(req *dto.ResetPasswordRequest) error {
 	user.PasswordHash = string(newHashedPass)
 	user.OtpCode = nil
 	user.OtpExpiry = nil
+	user.OtpFailedCount = 0
+
+	// Đặt lại mật khẩu => đăng xuất mọi thiết bị, trong cùng transaction với việc lưu mật khẩu mới
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		return s.revokeAllTx(tx, user.ID)
+	}); err != nil {
+		return err
+	}
+	s.throttle.Success(models.ThrottleScopeOTP, user.Email)
+	s.throttle.Success(models.ThrottleScopeLogin, user.Email)
+	return nil
+}
+
+// requiresTOTP: tài khoản có quyền ký số (cán bộ, quản trị) bắt buộc dùng 2FA
+func requiresTOTP(user *models.User) bool {
+	return user.Role.Name == middleware.RoleCanBo || user.Role.Name == middleware.RoleAdmin
+}
+
+func totpIssuer() string {
+	if v := os.Getenv("TOTP_ISSUER"); v != "" {
+		return v
+	}
+	return "KmaERM"
+}
+
+func encryptTOTPSecret(secret string) (string, error) {
+	key, err := utils.GetSystemMasterKey()
+	if err != nil {
+		return "", err
+	}
+	enc, err := utils.EncryptAES([]byte(secret), key)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(enc), nil
+}
+
+func decryptTOTPSecret(secretEnc string) (string, error) {
+	key, err := utils.GetSystemMasterKey()
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(secretEnc)
+	if err != nil {
+		return "", err
+	}
+	secret, err := utils.DecryptAES(raw, key)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// verifyTOTP kiểm tra mã và chặn dùng lại mã đã dùng (kể cả trong cửa sổ ±30s)
+func (s *userService) verifyTOTP(user *models.User, code string) error {
+	if user.TOTPSecretEnc == nil {
+		return ErrHaiLopChuaKhoiTao
+	}
+	secret, err := decryptTOTPSecret(*user.TOTPSecretEnc)
+	if err != nil {
+		return err
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return ErrMaHaiLopKhongDung
+	}
+	fresh, err := s.userRepo.MarkTOTPStepUsed(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrMaHaiLopKhongDung
+	}
+	return nil
+}
+
+func (s *userService) verifySecondFactor(user *models.User, totpCode, recoveryCode string) error {
+	if totpCode != "" {
+		return s.verifyTOTP(user, totpCode)
+	}
+
+	used, err := s.userRepo.UseRecoveryCode(user.ID, utils.HashToken(strings.ToLower(strings.TrimSpace(recoveryCode))))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrMaHaiLopKhongDung
+	}
+	return nil
+}
+
+// SetupTOTP sinh secret mới (chưa có hiệu lực cho tới khi EnableTOTP xác nhận được mã đầu tiên)
+func (s *userService) SetupTOTP(userID uuid.UUID) (*dto.TOTPSetupResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrNguoiDungKhongTonTai
+	}
+	if user.TOTPEnabled {
+		return nil, ErrHaiLopDaBat
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	secretEnc, err := encryptTOTPSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetTOTPSecret(nil, userID, &secretEnc, false); err != nil {
+		return nil, err
+	}
+
+	return &dto.TOTPSetupResponse{
+		Secret:     secret,
+		OtpauthURI: utils.TOTPProvisioningURI(totpIssuer(), user.Email, secret),
+	}, nil
+}
+
+func (s *userService) EnableTOTP(userID uuid.UUID, code string) (*dto.RecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrNguoiDungKhongTonTai
+	}
+	if user.TOTPEnabled {
+		return nil, ErrHaiLopDaBat
+	}
+	if err := s.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	// Mã khôi phục và cờ bật 2FA phải cùng được ghi, tránh bật 2FA mà không có mã khôi phục (hoặc ngược lại)
+	var res *dto.RecoveryCodesResponse
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if res, err = s.newRecoveryCodes(tx, userID); err != nil {
+			return err
+		}
+		return s.userRepo.SetTOTPSecret(tx, userID, user.TOTPSecretEnc, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *userService) DisableTOTP(userID uuid.UUID, req *dto.DisableTOTPRequest) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return ErrNguoiDungKhongTonTai
+	}
+	if requiresTOTP(user) {
+		return ErrKhongTheTatHaiLop
+	}
+	if !user.TOTPEnabled {
+		return ErrHaiLopChuaBat
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return ErrMatKhauKhongDung
+	}
+	if err := s.verifyTOTP(user, req.Code); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userRepo.ReplaceRecoveryCodes(tx, userID, nil); err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret_enc": nil,
+			"totp_enabled":    false,
+			"totp_last_step":  nil,
+		}).Error
+	})
+}
+
+func (s *userService) RegenerateRecoveryCodes(userID uuid.UUID, code string) (*dto.RecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrNguoiDungKhongTonTai
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrHaiLopChuaBat
+	}
+	if err := s.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+	var res *dto.RecoveryCodesResponse
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = s.newRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// newRecoveryCodes thay toàn bộ mã khôi phục cũ; DB chỉ giữ hash
+func (s *userService) newRecoveryCodes(tx *gorm.DB, userID uuid.UUID) (*dto.RecoveryCodesResponse, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, utils.HashToken(c))
+	}
+	if err := s.userRepo.ReplaceRecoveryCodes(tx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// VerifyStepUp yêu cầu mã TOTP mới (không chấp nhận mã khôi phục) cho thao tác ký số.
+// Số lần sai được đếm theo tài khoản để không dò được mã 6 chữ số qua endpoint ký.
+func (s *userService) VerifyStepUp(userID uuid.UUID, code string) error {
+	if err := s.throttle.Check(models.ThrottleScopeTOTP, userID.String(), ""); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return ErrNguoiDungKhongTonTai
+	}
+	if !user.TOTPEnabled {
+		return ErrHaiLopChuaBat
+	}
+	if err := s.verifyTOTP(user, code); err != nil {
+		if errors.Is(err, ErrMaHaiLopKhongDung) {
+			s.throttle.Fail(models.ThrottleScopeTOTP, userID.String(), "")
+		}
+		return err
+	}
+	s.throttle.Success(models.ThrottleScopeTOTP, userID.String())
+	return nil
+}
+
+func toThrottleState(t *models.AuthThrottle) dto.ThrottleState {
+	return dto.ThrottleState{
+		FailCount:   t.FailCount,
+		LockedUntil: t.LockedUntil,
+		Locked:      t.LockedUntil != nil && time.Now().Before(*t.LockedUntil),
+	}
+}
+
+// GetLockState tổng hợp trạng thái khóa (thủ công + tạm thời do nhập sai) cho quản trị viên
+func (s *userService) GetLockState(userID uuid.UUID) (*dto.UserLockStateResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrNguoiDungKhongTonTai
+	}
+
+	login, err := s.throttle.repo.Get(models.ThrottleScopeLogin, throttleSubject(user.Email))
+	if err != nil {
+		return nil, err
+	}
+	otp, err := s.throttle.repo.Get(models.ThrottleScopeOTP, throttleSubject(user.Email))
+	if err != nil {
+		return nil, err
+	}
+	totp, err := s.throttle.repo.Get(models.ThrottleScopeTOTP, throttleSubject(user.ID.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.UserLockStateResponse{
+		UserID:         user.ID.String(),
+		Email:          user.Email,
+		IsActive:       user.IsActive,
+		Login:          toThrottleState(login),
+		OTP:            toThrottleState(otp),
+		TOTP:           toThrottleState(totp),
+		OtpFailedCount: user.OtpFailedCount,
+	}, nil
+}
+
+// Unlock gỡ khóa tạm thời do nhập sai (không thay đổi is_active)
+func (s *userService) Unlock(userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return ErrNguoiDungKhongTonTai
+	}
+	if err := s.throttle.repo.Reset(models.ThrottleScopeLogin, throttleSubject(user.Email)); err != nil {
+		return err
+	}
+	if err := s.throttle.repo.Reset(models.ThrottleScopeTOTP, throttleSubject(user.ID.String())); err != nil {
+		return err
+	}
+	return s.throttle.repo.Reset(models.ThrottleScopeOTP, throttleSubject(user.Email))
+}
 
-	return s.userRepo.Update(user)
+// ConfigureSigner chuyển cán bộ sang dùng khóa trong CSDL hoặc trong HSM.
+// Signer mới được thử mở trước khi lưu; public_key_pem được cập nhật theo khóa mới.
+func (s *userService) ConfigureSigner(userID uuid.UUID, req *dto.UpdateSignerRequest) (*dto.SignerInfoResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrNguoiDungKhongTonTai
+	}
+
+	user.SignerType = req.SignerType
+	user.HSMKeyLabel = nil
+	if req.SignerType == signer.TypePKCS11 {
+		label := strings.TrimSpace(req.HSMKeyLabel)
+		user.HSMKeyLabel = &label
+	}
+
+	sgn, err := s.signers.ForUser(user)
+	if err != nil {
+		return nil, err
+	}
+	pubPEM, err := signer.PublicKeyPEM(sgn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Đổi khóa ký => chứng thư cũ không còn đúng khóa: thu hồi và cấp chứng thư mới
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userRepo.UpdateSigner(tx, userID, user.SignerType, user.HSMKeyLabel, pubPEM); err != nil {
+			return err
+		}
+		if err := s.ca.RevokeAllByUser(tx, userID, "Thay đổi khóa ký"); err != nil {
+			return err
+		}
+		_, err := s.ca.Issue(tx, user, sgn.Public())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dto.SignerInfoResponse{SignerType: user.SignerType, KeyID: sgn.KeyID(), PublicKeyPEM: pubPEM}, nil
 }
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vnkmasc/KmaERM/backend/internal/dto"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+	"github.com/vnkmasc/KmaERM/backend/internal/policy"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrKheTaiLieuKhongThuocHoSo = errors.New("khe tài liệu cần bổ sung không thuộc hồ sơ này")
+	ErrKheTaiLieuTrungLap       = errors.New("một khe tài liệu chỉ được yêu cầu bổ sung một lần trong mỗi lần trả lại")
+	ErrKheKhongCanBoSung        = errors.New("hồ sơ đang chờ bổ sung, chỉ được thay đổi tài liệu của các khe được yêu cầu bổ sung")
+	ErrHoSoKhongSuaTaiLieu      = errors.New("chỉ được thay đổi tài liệu khi hồ sơ mới tạo hoặc đang chờ bổ sung")
+	ErrChuaBoSungDu             = errors.New("chưa bổ sung đủ các tài liệu được yêu cầu")
+)
+
+// ChuaBoSungDuError liệt kê các khe tài liệu còn thiếu khi doanh nghiệp nộp lại hồ sơ
+type ChuaBoSungDuError struct {
+	TaiLieus []dto.KheTaiLieuBoSungResponse
+}
+
+func (e *ChuaBoSungDuError) Error() string {
+	ten := make([]string, len(e.TaiLieus))
+	for i, tl := range e.TaiLieus {
+		ten[i] = tl.TenLoaiTaiLieu
+	}
+	return fmt.Sprintf("%s: %s", ErrChuaBoSungDu.Error(), strings.Join(ten, "; "))
+}
+
+func (e *ChuaBoSungDuError) Unwrap() error {
+	return ErrChuaBoSungDu
+}
+
+// taoYeuCauBoSung mở một vòng bổ sung mới khi trả lại hồ sơ (gọi trong transaction đã khóa hồ sơ)
+func (s *hoSoService) taoYeuCauBoSung(ctx context.Context, tx *gorm.DB, hoSo *models.HoSo, lyDo string, cacKhe []dto.KheTaiLieuCanBoSung) (*models.YeuCauBoSung, error) {
+	ids := make([]uuid.UUID, 0, len(cacKhe))
+	seen := make(map[uuid.UUID]bool, len(cacKhe))
+	for _, khe := range cacKhe {
+		if seen[khe.HoSoTaiLieuID] {
+			return nil, ErrKheTaiLieuTrungLap
+		}
+		seen[khe.HoSoTaiLieuID] = true
+		ids = append(ids, khe.HoSoTaiLieuID)
+	}
+	if len(ids) > 0 {
+		count, err := s.yeuCauRepo.CountKheTaiLieu(ctx, tx, hoSo.ID, ids)
+		if err != nil {
+			return nil, err
+		}
+		if count != int64(len(ids)) {
+			return nil, ErrKheTaiLieuKhongThuocHoSo
+		}
+	}
+
+	hoSo.SoLanBoSung++
+	yeuCau := &models.YeuCauBoSung{
+		HoSoID:    hoSo.ID,
+		Lan:       hoSo.SoLanBoSung,
+		NoiDung:   lyDo,
+		TrangThai: models.YeuCauBoSungChoBoSung,
+	}
+	if actor, ok := policy.ActorFrom(ctx); ok && actor.UserID != uuid.Nil {
+		yeuCau.NguoiYeuCauID = &actor.UserID
+	}
+	for _, khe := range cacKhe {
+		yeuCau.TaiLieus = append(yeuCau.TaiLieus, models.YeuCauBoSungTaiLieu{
+			HoSoTaiLieuID: khe.HoSoTaiLieuID,
+			LyDo:          strings.TrimSpace(khe.LyDo),
+		})
+	}
+	if err := s.yeuCauRepo.Create(ctx, tx, yeuCau); err != nil {
+		return nil, err
+	}
+	return yeuCau, nil
+}
+
+// dongYeuCauBoSung kết thúc vòng bổ sung đang mở khi doanh nghiệp nộp lại hồ sơ.
+// Mọi khe được yêu cầu phải đã có tài liệu nộp mới sau khi trả lại.
+func (s *hoSoService) dongYeuCauBoSung(ctx context.Context, tx *gorm.DB, hoSo *models.HoSo) (*models.YeuCauBoSung, error) {
+	yeuCau, err := s.yeuCauRepo.GetDangMo(ctx, tx, hoSo.ID)
+	if err != nil {
+		// Hồ sơ trả lại trước khi có tính năng yêu cầu bổ sung
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var thieu []dto.KheTaiLieuBoSungResponse
+	for i := range yeuCau.TaiLieus {
+		if !yeuCau.TaiLieus[i].DaBoSung {
+			thieu = append(thieu, dto.ToKheTaiLieuBoSungResponse(&yeuCau.TaiLieus[i]))
+		}
+	}
+	if len(thieu) > 0 {
+		return nil, &ChuaBoSungDuError{TaiLieus: thieu}
+	}
+	if err := s.yeuCauRepo.Dong(ctx, tx, yeuCau.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	return yeuCau, nil
+}
+
+// kiemTraSuaTaiLieu: tài liệu chỉ được nộp hoặc xóa khi hồ sơ mới tạo, hoặc khi hồ sơ bị trả lại và khe nằm trong
+// vòng bổ sung đang mở (vòng không chỉ định khe thì mọi khe). Gọi trong transaction đã khóa hồ sơ.
+func (s *hoSoService) kiemTraSuaTaiLieu(ctx context.Context, tx *gorm.DB, hoSo *models.HoSo, kheID uuid.UUID) error {
+	switch hoSo.TrangThaiHoSo {
+	case TrangThaiHoSoMoiTao:
+		return nil
+	case TrangThaiHoSoBiTraLai:
+	default:
+		return ErrHoSoKhongSuaTaiLieu
+	}
+	yeuCau, err := s.yeuCauRepo.GetDangMo(ctx, tx, hoSo.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if len(yeuCau.TaiLieus) == 0 {
+		return nil
+	}
+	for _, tl := range yeuCau.TaiLieus {
+		if tl.HoSoTaiLieuID == kheID {
+			return nil
+		}
+	}
+	return ErrKheKhongCanBoSung
+}
+
+// GetYeuCauBoSung trả về các vòng yêu cầu bổ sung của hồ sơ, vòng mới nhất trước
+func (s *hoSoService) GetYeuCauBoSung(ctx context.Context, hoSoID uuid.UUID) ([]dto.YeuCauBoSungResponse, error) {
+	hoSo, err := s.hosoRepo.GetHoSoByID(ctx, s.db, hoSoID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHoSoKhongTimThay
+		}
+		return nil, err
+	}
+	if !policy.CanAccessDoanhNghiep(ctx, hoSo.DoanhNghiepID) {
+		return nil, ErrHoSoKhongTimThay
+	}
+
+	list, err := s.yeuCauRepo.ListByHoSoID(ctx, s.db, hoSoID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.YeuCauBoSungResponse, len(list))
+	for i := range list {
+		result[i] = dto.ToYeuCauBoSungResponse(&list[i])
+	}
+	return result, nil
+}
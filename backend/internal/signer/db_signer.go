@@ -0,0 +1,51 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+)
+
+// dbSigner dùng private key lưu trong CSDL (đã được keystore giải mã ngay trước khi ký)
+type dbSigner struct {
+	key *rsa.PrivateKey
+	id  string
+}
+
+func newDBSigner(privateKeyPEM string) (*dbSigner, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, errors.New("private key không hợp lệ")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		// Khóa nạp từ file (ví dụ TSA_KEY_FILE do openssl sinh) thường ở dạng PKCS#8
+		parsed, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if err8 != nil || !ok {
+			return nil, err
+		}
+		key = rsaKey
+	}
+	return &dbSigner{key: key, id: TypeDB + ":" + Fingerprint(&key.PublicKey)}, nil
+}
+
+// NewKeySigner tạo signer từ private key RSA dạng PEM của hệ thống (ví dụ khóa TSA), không gắn với user nào
+func NewKeySigner(privateKeyPEM string) (Signer, error) {
+	return newDBSigner(privateKeyPEM)
+}
+
+func (s *dbSigner) KeyID() string {
+	return s.id
+}
+
+func (s *dbSigner) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s *dbSigner) SignDigest(digest []byte, hash crypto.Hash) ([]byte, error) {
+	return rsa.SignPKCS1v15(rand.Reader, s.key, hash, digest)
+}
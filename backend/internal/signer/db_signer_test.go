@@ -0,0 +1,105 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+)
+
+func taoKhoaRSA(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return key
+}
+
+func pemPKCS1(key *rsa.PrivateKey) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func pemPKCS8(t *testing.T, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestDBSignerDocPKCS1VaPKCS8(t *testing.T) {
+	key := taoKhoaRSA(t)
+	for ten, keyPEM := range map[string]string{
+		"PKCS#1": pemPKCS1(key),
+		"PKCS#8": pemPKCS8(t, key),
+	} {
+		s, err := newDBSigner(keyPEM)
+		if err != nil {
+			t.Fatalf("%s: newDBSigner: %v", ten, err)
+		}
+		if !key.PublicKey.Equal(s.Public()) {
+			t.Errorf("%s: public key không khớp khóa gốc", ten)
+		}
+		if want := TypeDB + ":" + Fingerprint(&key.PublicKey); s.KeyID() != want {
+			t.Errorf("%s: KeyID = %q, muốn %q", ten, s.KeyID(), want)
+		}
+	}
+}
+
+func TestDBSignerKhoaKhongHopLe(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ten, keyPEM := range map[string]string{
+		"không phải PEM":   "khong-phai-pem",
+		"PEM hỏng":         string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte{1, 2, 3}})),
+		"khóa không RSA":   pemPKCS8(t, ecKey),
+		"chỉ có khóa công": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte{0x30, 0x00}})),
+	} {
+		if _, err := NewKeySigner(keyPEM); err == nil {
+			t.Errorf("%s: muốn lỗi", ten)
+		}
+	}
+}
+
+func TestDBSignerKyVaKiemTra(t *testing.T) {
+	s, err := NewKeySigner(pemPKCS1(taoKhoaRSA(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("giấy phép số 15/2025/GP-BCY"))
+	sig, err := s.SignDigest(digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("SignDigest: %v", err)
+	}
+
+	// Đi qua đúng đường lưu/đọc public key của hệ thống
+	pubPEM, err := PublicKeyPEM(s)
+	if err != nil {
+		t.Fatalf("PublicKeyPEM: %v", err)
+	}
+	if !strings.Contains(pubPEM, "BEGIN PUBLIC KEY") {
+		t.Errorf("PublicKeyPEM không ở dạng PKIX: %q", pubPEM)
+	}
+	pub, err := ParsePublicKeyPEM(pubPEM)
+	if err != nil {
+		t.Fatalf("ParsePublicKeyPEM: %v", err)
+	}
+	if err := VerifyDigest(pub, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("VerifyDigest: %v", err)
+	}
+
+	khac := sha256.Sum256([]byte("nội dung khác"))
+	if err := VerifyDigest(pub, crypto.SHA256, khac[:], sig); err == nil {
+		t.Error("chữ ký không được khớp digest khác")
+	}
+}
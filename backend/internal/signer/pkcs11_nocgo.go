@@ -0,0 +1,20 @@
+//go:build !cgo
+
+package signer
+
+import "errors"
+
+// Thư viện PKCS#11 cần cgo (dlopen module .so); bản build CGO_ENABLED=0 chỉ hỗ trợ signer CSDL
+var errPKCS11CanCgo = errors.New("bản build này không hỗ trợ PKCS#11 (cần build với CGO_ENABLED=1)")
+
+type pkcs11Token struct{}
+
+func openPKCS11Token(PKCS11Config) (*pkcs11Token, error) {
+	return nil, errPKCS11CanCgo
+}
+
+func (t *pkcs11Token) close() {}
+
+func (t *pkcs11Token) signer(string) (Signer, error) {
+	return nil, errPKCS11CanCgo
+}
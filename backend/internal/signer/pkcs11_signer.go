@@ -0,0 +1,171 @@
+//go:build cgo
+
+package signer
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/miekg/pkcs11"
+)
+
+// Tiền tố DigestInfo (DER) theo RFC 8017 mục 9.2: CKM_RSA_PKCS chỉ đệm PKCS#1 v1.5,
+// phần DigestInfo phải tự ghép trước digest.
+var digestInfoPrefix = map[crypto.Hash][]byte{
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// pkcs11Token giữ module PKCS#11 đã Initialize; mỗi lần ký mở một session riêng
+type pkcs11Token struct {
+	ctx  *pkcs11.Ctx
+	slot uint
+	cfg  PKCS11Config
+}
+
+func openPKCS11Token(cfg PKCS11Config) (*pkcs11Token, error) {
+	ctx := pkcs11.New(cfg.ModulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("không nạp được module %s", cfg.ModulePath)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, err
+	}
+
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		ctx.Finalize()
+		ctx.Destroy()
+		return nil, err
+	}
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err == nil && info.Label == cfg.TokenLabel {
+			return &pkcs11Token{ctx: ctx, slot: slot, cfg: cfg}, nil
+		}
+	}
+
+	ctx.Finalize()
+	ctx.Destroy()
+	return nil, fmt.Errorf("không tìm thấy token có nhãn %q", cfg.TokenLabel)
+}
+
+func (t *pkcs11Token) close() {
+	t.ctx.Finalize()
+	t.ctx.Destroy()
+}
+
+// withSession mở session + đăng nhập CKU_USER, đóng lại sau khi fn chạy xong
+func (t *pkcs11Token) withSession(fn func(sh pkcs11.SessionHandle) error) error {
+	sh, err := t.ctx.OpenSession(t.slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return err
+	}
+	defer t.ctx.CloseSession(sh)
+
+	// Trạng thái đăng nhập được chia sẻ giữa các session của cùng ứng dụng
+	if err := t.ctx.Login(sh, pkcs11.CKU_USER, t.cfg.PIN); err != nil {
+		var pErr pkcs11.Error
+		if !errors.As(err, &pErr) || pErr != pkcs11.CKR_USER_ALREADY_LOGGED_IN {
+			return fmt.Errorf("đăng nhập token thất bại: %w", err)
+		}
+	}
+	return fn(sh)
+}
+
+func (t *pkcs11Token) findObject(sh pkcs11.SessionHandle, class uint, label string) (pkcs11.ObjectHandle, error) {
+	tmpl := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := t.ctx.FindObjectsInit(sh, tmpl); err != nil {
+		return 0, err
+	}
+	objs, _, err := t.ctx.FindObjects(sh, 2)
+	t.ctx.FindObjectsFinal(sh)
+	if err != nil {
+		return 0, err
+	}
+	if len(objs) == 0 {
+		return 0, fmt.Errorf("không tìm thấy khóa có nhãn %q trên token", label)
+	}
+	if len(objs) > 1 {
+		return 0, fmt.Errorf("có nhiều khóa cùng nhãn %q trên token", label)
+	}
+	return objs[0], nil
+}
+
+// signer đọc public key (CKA_MODULUS, CKA_PUBLIC_EXPONENT) của khóa keyLabel; private key ở lại trong token
+func (t *pkcs11Token) signer(keyLabel string) (Signer, error) {
+	var pub *rsa.PublicKey
+	err := t.withSession(func(sh pkcs11.SessionHandle) error {
+		obj, err := t.findObject(sh, pkcs11.CKO_PUBLIC_KEY, keyLabel)
+		if err != nil {
+			return err
+		}
+		attrs, err := t.ctx.GetAttributeValue(sh, obj, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil {
+			return err
+		}
+		pub = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(attrs[0].Value),
+			E: int(new(big.Int).SetBytes(attrs[1].Value).Int64()),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pkcs11Signer{token: t, label: keyLabel, pub: pub}, nil
+}
+
+type pkcs11Signer struct {
+	token *pkcs11Token
+	label string
+	pub   *rsa.PublicKey
+}
+
+func (s *pkcs11Signer) KeyID() string {
+	return TypePKCS11 + ":" + s.token.cfg.TokenLabel + "/" + s.label
+}
+
+func (s *pkcs11Signer) Public() crypto.PublicKey {
+	return s.pub
+}
+
+func (s *pkcs11Signer) SignDigest(digest []byte, hash crypto.Hash) ([]byte, error) {
+	prefix, ok := digestInfoPrefix[hash]
+	if !ok {
+		return nil, fmt.Errorf("thuật toán băm %v chưa được hỗ trợ cho PKCS#11", hash)
+	}
+	if len(digest) != hash.Size() {
+		return nil, errors.New("độ dài digest không khớp thuật toán băm")
+	}
+
+	var sig []byte
+	err := s.token.withSession(func(sh pkcs11.SessionHandle) error {
+		key, err := s.token.findObject(sh, pkcs11.CKO_PRIVATE_KEY, s.label)
+		if err != nil {
+			return err
+		}
+		mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS, nil)}
+		if err := s.token.ctx.SignInit(sh, mech, key); err != nil {
+			return err
+		}
+		sig, err = s.token.ctx.Sign(sh, append(append([]byte{}, prefix...), digest...))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("HSM ký thất bại: %w", err)
+	}
+	return sig, nil
+}
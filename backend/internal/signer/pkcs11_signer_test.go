@@ -0,0 +1,57 @@
+//go:build cgo
+
+package signer
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Chạy với token thật (ví dụ SoftHSM) đã có cặp khóa RSA nhãn PKCS11_TEST_KEY_LABEL:
+//
+//	PKCS11_MODULE=/usr/lib/softhsm/libsofthsm2.so PKCS11_TOKEN_LABEL=kmaerm PKCS11_PIN=1234 \
+//	PKCS11_TEST_KEY_LABEL=can-bo-test go test ./internal/signer -run PKCS11
+func TestPKCS11SignerKyVaKiemTra(t *testing.T) {
+	if os.Getenv("PKCS11_MODULE") == "" {
+		t.Skip("PKCS11_MODULE chưa đặt, bỏ qua test HSM")
+	}
+	label := os.Getenv("PKCS11_TEST_KEY_LABEL")
+	if label == "" {
+		t.Skip("PKCS11_TEST_KEY_LABEL chưa đặt, bỏ qua test HSM")
+	}
+
+	p := NewProvider(nil, PKCS11ConfigFromEnv())
+	defer p.Close()
+
+	s, err := p.PKCS11Signer(label)
+	if err != nil {
+		t.Fatalf("PKCS11Signer: %v", err)
+	}
+	if !strings.HasPrefix(s.KeyID(), TypePKCS11+":") {
+		t.Errorf("KeyID = %q", s.KeyID())
+	}
+	pub, ok := s.Public().(*rsa.PublicKey)
+	if !ok {
+		t.Fatalf("public key kiểu %T, muốn RSA", s.Public())
+	}
+
+	digest := sha256.Sum256([]byte("giấy phép ký bằng HSM"))
+	sig, err := s.SignDigest(digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("SignDigest: %v", err)
+	}
+	if err := VerifyDigest(pub, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("VerifyDigest: %v", err)
+	}
+
+	if _, err := s.SignDigest(digest[:16], crypto.SHA256); err == nil {
+		t.Error("digest sai độ dài phải bị từ chối")
+	}
+	if _, err := p.PKCS11Signer(label + "-khong-ton-tai"); err == nil {
+		t.Error("nhãn khóa không tồn tại phải trả lỗi")
+	}
+}
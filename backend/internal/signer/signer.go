@@ -0,0 +1,138 @@
+// Package signer tách thao tác ký số khỏi nơi lưu khóa: khóa trong CSDL (mã hóa bằng keystore)
+// hoặc khóa nằm trong HSM/token PKCS#11 (SoftHSM khi chạy local) và không bao giờ rời thiết bị.
+package signer
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/keystore"
+	"github.com/vnkmasc/KmaERM/backend/internal/models"
+)
+
+// Loại signer cấu hình cho từng user (cột users.signer_type)
+const (
+	TypeDB     = "db"
+	TypePKCS11 = "pkcs11"
+)
+
+var (
+	ErrLoaiSignerKhongHopLe = errors.New("loại signer không hợp lệ (chỉ hỗ trợ 'db' hoặc 'pkcs11')")
+	ErrChuaCauHinhPKCS11    = errors.New("hệ thống chưa cấu hình PKCS#11 (PKCS11_MODULE, PKCS11_TOKEN_LABEL, PKCS11_PIN)")
+	ErrThieuNhanKhoaHSM     = errors.New("tài khoản dùng HSM nhưng chưa khai báo nhãn khóa (hsm_key_label)")
+)
+
+// Signer ký một digest đã băm sẵn bằng RSA PKCS#1 v1.5
+type Signer interface {
+	// KeyID định danh khóa, ví dụ "db:<fingerprint>" hoặc "pkcs11:<token>/<label>"
+	KeyID() string
+	Public() crypto.PublicKey
+	SignDigest(digest []byte, hash crypto.Hash) ([]byte, error)
+}
+
+// PublicKeyPEM mã hóa public key của signer sang PEM (PKIX) để lưu kèm chữ ký
+func PublicKeyPEM(s Signer) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(s.Public())
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// Fingerprint là SHA-256 (16 ký tự hex đầu) của public key dạng PKIX
+func Fingerprint(pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8])
+}
+
+// PKCS11Config lấy từ biến môi trường, dùng chung cho mọi user có signer_type = pkcs11
+type PKCS11Config struct {
+	ModulePath string // Ví dụ /usr/lib/softhsm/libsofthsm2.so
+	TokenLabel string
+	PIN        string
+}
+
+func PKCS11ConfigFromEnv() PKCS11Config {
+	return PKCS11Config{
+		ModulePath: os.Getenv("PKCS11_MODULE"),
+		TokenLabel: os.Getenv("PKCS11_TOKEN_LABEL"),
+		PIN:        os.Getenv("PKCS11_PIN"),
+	}
+}
+
+func (c PKCS11Config) Enabled() bool {
+	return c.ModulePath != "" && c.TokenLabel != ""
+}
+
+// Provider chọn signer theo cấu hình của từng user
+type Provider struct {
+	keys      *keystore.Keystore
+	hsmConfig PKCS11Config
+
+	mu  sync.Mutex
+	hsm *pkcs11Token // Khởi tạo module PKCS#11 một lần, khi có user đầu tiên cần
+}
+
+func NewProvider(keys *keystore.Keystore, hsmConfig PKCS11Config) *Provider {
+	return &Provider{keys: keys, hsmConfig: hsmConfig}
+}
+
+func (p *Provider) ForUser(user *models.User) (Signer, error) {
+	switch user.SignerType {
+	case "", TypeDB:
+		privateKeyPEM, err := p.keys.OpenUser(user)
+		if err != nil {
+			return nil, err
+		}
+		return newDBSigner(privateKeyPEM)
+
+	case TypePKCS11:
+		if user.HSMKeyLabel == nil || *user.HSMKeyLabel == "" {
+			return nil, ErrThieuNhanKhoaHSM
+		}
+		return p.PKCS11Signer(*user.HSMKeyLabel)
+	}
+	return nil, ErrLoaiSignerKhongHopLe
+}
+
+// PKCS11Signer trả về signer cho khóa có nhãn keyLabel trên token đã cấu hình
+func (p *Provider) PKCS11Signer(keyLabel string) (Signer, error) {
+	if !p.hsmConfig.Enabled() {
+		return nil, ErrChuaCauHinhPKCS11
+	}
+
+	p.mu.Lock()
+	if p.hsm == nil {
+		tok, err := openPKCS11Token(p.hsmConfig)
+		if err != nil {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("không kết nối được HSM: %w", err)
+		}
+		p.hsm = tok
+	}
+	tok := p.hsm
+	p.mu.Unlock()
+
+	return tok.signer(keyLabel)
+}
+
+// Close giải phóng module PKCS#11 (nếu đã khởi tạo)
+func (p *Provider) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.hsm != nil {
+		p.hsm.close()
+		p.hsm = nil
+	}
+}
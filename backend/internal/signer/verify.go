@@ -0,0 +1,31 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+)
+
+// ParsePublicKeyPEM đọc public key RSA dạng PKIX ("PUBLIC KEY") như lưu trong public_key_pem / public_key_nguoi_ky
+func ParsePublicKeyPEM(publicKeyPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, errors.New("public key không hợp lệ")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("chỉ hỗ trợ public key RSA")
+	}
+	return rsaPub, nil
+}
+
+// VerifyDigest kiểm tra chữ ký RSA PKCS#1 v1.5 do Signer.SignDigest tạo ra
+func VerifyDigest(pub *rsa.PublicKey, hash crypto.Hash, digest, sig []byte) error {
+	return rsa.VerifyPKCS1v15(pub, hash, digest, sig)
+}
@@ -0,0 +1,130 @@
+package tsa
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/ca"
+	"github.com/vnkmasc/KmaERM/backend/internal/signer"
+)
+
+// NewFromEnv chọn nguồn dấu thời gian theo cấu hình:
+//   - TSA_URL: TSA bên ngoài qua HTTP, chứng thư gốc của TSA khai báo trong TSA_TRUST_FILE
+//   - TSA_CERT_FILE + TSA_KEY_FILE: TSA nội bộ với khóa/chứng thư tự cấu hình
+//     (file chứng thư có thể chứa cả chuỗi, chứng thư cuối cùng được coi là trust anchor)
+//   - mặc định: TSA nội bộ với khóa sinh sẵn, chứng thư do CA nội bộ cấp
+//
+// TSA nội bộ bắt buộc có TSA_POLICY_OID: OID chính sách dấu thời gian ghi vào mọi TSTInfo, phải nằm dưới
+// nhánh OID mà đơn vị vận hành được cấp (ví dụ nhánh doanh nghiệp 1.3.6.1.4.1.<PEN> đăng ký với IANA).
+// Hệ thống không tự đặt OID mặc định vì không sở hữu nhánh OID nào.
+//
+// CA nội bộ luôn nằm trong trust anchor để kiểm tra được dấu thời gian đã cấp trước đó.
+func NewFromEnv(authority *ca.Authority) (*Stamper, error) {
+	roots, err := authority.RootCertificates()
+	if err != nil {
+		return nil, err
+	}
+	if path := os.Getenv("TSA_TRUST_FILE"); path != "" {
+		extra, err := readCertsFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("TSA_TRUST_FILE: %w", err)
+		}
+		roots = append(roots, extra...)
+	}
+
+	if url := os.Getenv("TSA_URL"); url != "" {
+		return NewStamper(NewHTTPClient(url), roots), nil
+	}
+
+	policy, err := policyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	certFile, keyFile := os.Getenv("TSA_CERT_FILE"), os.Getenv("TSA_KEY_FILE")
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("cần khai báo đồng thời TSA_CERT_FILE và TSA_KEY_FILE")
+		}
+		certs, err := readCertsFile(certFile)
+		if err != nil {
+			return nil, fmt.Errorf("TSA_CERT_FILE: %w", err)
+		}
+		keyPEM, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("TSA_KEY_FILE: %w", err)
+		}
+		sgn, err := signer.NewKeySigner(string(keyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("TSA_KEY_FILE: %w", err)
+		}
+		roots = append(roots, certs[len(certs)-1])
+		return NewStamper(NewLocalTSA(sgn, certs[0], certs[1:], policy), roots), nil
+	}
+
+	keyPEM, chain, err := authority.EnsureTimestamping()
+	if err != nil {
+		return nil, err
+	}
+	sgn, err := signer.NewKeySigner(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return NewStamper(NewLocalTSA(sgn, chain[0], chain[1:], policy), roots), nil
+}
+
+func policyFromEnv() (asn1.ObjectIdentifier, error) {
+	v := os.Getenv("TSA_POLICY_OID")
+	if v == "" {
+		return nil, errors.New("cần khai báo TSA_POLICY_OID (OID chính sách dấu thời gian) cho TSA nội bộ")
+	}
+	return parsePolicyOID(v)
+}
+
+func parsePolicyOID(v string) (asn1.ObjectIdentifier, error) {
+	var oid asn1.ObjectIdentifier
+	for _, part := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("TSA_POLICY_OID không hợp lệ: %s", v)
+		}
+		oid = append(oid, n)
+	}
+	if len(oid) < 2 {
+		return nil, fmt.Errorf("TSA_POLICY_OID không hợp lệ: %s", v)
+	}
+	return oid, nil
+}
+
+func readCertsFile(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("không có chứng thư PEM nào trong file")
+	}
+	return certs, nil
+}
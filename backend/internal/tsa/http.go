@@ -0,0 +1,99 @@
+package tsa
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString asn1.RawValue  `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+// HTTPClient xin dấu thời gian từ TSA bên ngoài qua giao thức HTTP của RFC 3161
+type HTTPClient struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPClient(url string) *HTTPClient {
+	return &HTTPClient{url: url, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (c *HTTPClient) Timestamp(ctx context.Context, digest []byte) ([]byte, error) {
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 63))
+	if err != nil {
+		return nil, err
+	}
+	body, err := asn1.Marshal(timeStampReq{
+		Version:        1,
+		MessageImprint: sha256Imprint(digest),
+		Nonce:          nonce,
+		CertReq:        true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/timestamp-query")
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TSA trả về HTTP %d", res.StatusCode)
+	}
+	raw, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp timeStampResp
+	if _, err := asn1.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("phản hồi TSA không hợp lệ: %w", err)
+	}
+	// 0 = granted, 1 = grantedWithMods
+	if resp.Status.Status > 1 || len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, fmt.Errorf("TSA từ chối cấp dấu thời gian (status %d)", resp.Status.Status)
+	}
+
+	// Token phải trả lời đúng yêu cầu này: cùng digest và cùng nonce
+	info, _, err := parseToken(resp.TimeStampToken.FullBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, ErrDauThoiGianKhongKhop
+	}
+	if info.Nonce == nil || info.Nonce.Cmp(nonce) != 0 {
+		return nil, errors.New("nonce trong dấu thời gian không khớp yêu cầu")
+	}
+	return resp.TimeStampToken.FullBytes, nil
+}
@@ -0,0 +1,55 @@
+package tsa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/cms"
+	"github.com/vnkmasc/KmaERM/backend/internal/signer"
+)
+
+// LocalTSA tự cấp dấu thời gian ngay trong tiến trình bằng khóa và chứng thư TSA đã cấu hình
+type LocalTSA struct {
+	sgn    signer.Signer
+	cert   *x509.Certificate
+	chain  []*x509.Certificate
+	policy asn1.ObjectIdentifier
+}
+
+// NewLocalTSA: chain là các chứng thư phát hành cert (không gồm cert), nhúng kèm token để bên kiểm tra dựng chuỗi
+func NewLocalTSA(sgn signer.Signer, cert *x509.Certificate, chain []*x509.Certificate, policy asn1.ObjectIdentifier) *LocalTSA {
+	return &LocalTSA{sgn: sgn, cert: cert, chain: chain, policy: policy}
+}
+
+func (t *LocalTSA) Timestamp(_ context.Context, digest []byte) ([]byte, error) {
+	if len(digest) != sha256.Size {
+		return nil, errors.New("digest cần đóng dấu thời gian phải là SHA-256")
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if now.After(t.cert.NotAfter) {
+		return nil, errors.New("chứng thư TSA đã hết hạn")
+	}
+	info, err := asn1.Marshal(tstInfo{
+		Version:        1,
+		Policy:         t.policy,
+		MessageImprint: sha256Imprint(digest),
+		SerialNumber:   serial,
+		GenTime:        now,
+		Accuracy:       accuracy{Seconds: 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cms.SignEncapsulated(oidTSTInfo, info, t.sgn, t.cert, t.chain)
+}
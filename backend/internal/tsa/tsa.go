@@ -0,0 +1,152 @@
+// Package tsa cấp và kiểm tra dấu thời gian tin cậy theo RFC 3161.
+// Dấu thời gian (TimeStampToken) là một CMS SignedData chứa TSTInfo: TSA xác nhận
+// digest của dữ liệu đã tồn tại tại thời điểm genTime, thay cho time.Now() của máy chủ ứng dụng.
+package tsa
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/cms"
+)
+
+var (
+	ErrDauThoiGianKhongHopLe = errors.New("dấu thời gian RFC 3161 không hợp lệ")
+	ErrDauThoiGianKhongKhop  = errors.New("dấu thời gian không được cấp cho dữ liệu này")
+	ErrTSAKhongTinCay        = errors.New("chứng thư TSA không thuộc chuỗi tin cậy")
+)
+
+var (
+	oidTSTInfo = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidSHA256  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+)
+
+// Client xin dấu thời gian cho một digest SHA-256, trả về TimeStampToken (ContentInfo DER)
+type Client interface {
+	Timestamp(ctx context.Context, digest []byte) ([]byte, error)
+}
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type messageImprint struct {
+	HashAlgorithm algorithmIdentifier
+	HashedMessage []byte
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time     `asn1:"generalized"`
+	Accuracy       accuracy      `asn1:"optional"`
+	Ordering       bool          `asn1:"optional,default:false"`
+	Nonce          *big.Int      `asn1:"optional"`
+	TSA            asn1.RawValue `asn1:"optional,explicit,tag:0"`
+	Extensions     asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+func sha256Imprint(digest []byte) messageImprint {
+	return messageImprint{
+		HashAlgorithm: algorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.RawValue{Tag: asn1.TagNull}},
+		HashedMessage: digest,
+	}
+}
+
+// Token là thông tin đọc được từ một dấu thời gian đã kiểm tra
+type Token struct {
+	GenTime      time.Time
+	SerialNumber *big.Int
+	Policy       string
+	Certificate  *x509.Certificate // Chứng thư của TSA đã ký dấu thời gian
+}
+
+// parseToken kiểm tra chữ ký CMS của token và trả về TSTInfo bên trong (chưa kiểm tra chuỗi chứng thư)
+func parseToken(token []byte) (*tstInfo, *cms.SignerResult, error) {
+	contentType, content, signerInfo, err := cms.VerifyEncapsulated(token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrDauThoiGianKhongHopLe, err)
+	}
+	if !contentType.Equal(oidTSTInfo) {
+		return nil, nil, fmt.Errorf("%w: nội dung không phải TSTInfo", ErrDauThoiGianKhongHopLe)
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(content, &info); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrDauThoiGianKhongHopLe, err)
+	}
+	return &info, signerInfo, nil
+}
+
+// Stamper gắn một Client với tập trust anchor dùng để kiểm tra dấu thời gian
+type Stamper struct {
+	client Client
+	roots  *x509.CertPool
+}
+
+func NewStamper(client Client, roots []*x509.Certificate) *Stamper {
+	pool := x509.NewCertPool()
+	for _, c := range roots {
+		pool.AddCert(c)
+	}
+	return &Stamper{client: client, roots: pool}
+}
+
+// Stamp xin dấu thời gian cho SHA-256 của data
+func (s *Stamper) Stamp(ctx context.Context, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	token, err := s.client.Timestamp(ctx, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("lỗi lấy dấu thời gian: %w", err)
+	}
+	return token, nil
+}
+
+// Verify kiểm tra token được cấp cho SHA-256 của data, chữ ký CMS đúng và chứng thư TSA
+// (EKU timeStamping) chuỗi tới trust anchor tại thời điểm genTime
+func (s *Stamper) Verify(token, data []byte) (*Token, error) {
+	info, signerInfo, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(data)
+	if !info.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256) ||
+		!bytes.Equal(info.MessageImprint.HashedMessage, digest[:]) {
+		return nil, ErrDauThoiGianKhongKhop
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range signerInfo.Certificates {
+		intermediates.AddCert(c)
+	}
+	if _, err := signerInfo.Certificate.Verify(x509.VerifyOptions{
+		Roots:         s.roots,
+		Intermediates: intermediates,
+		CurrentTime:   info.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTSAKhongTinCay, err)
+	}
+
+	return &Token{
+		GenTime:      info.GenTime,
+		SerialNumber: info.SerialNumber,
+		Policy:       info.Policy.String(),
+		Certificate:  signerInfo.Certificate,
+	}, nil
+}
@@ -0,0 +1,134 @@
+package tsa
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vnkmasc/KmaERM/backend/internal/cms"
+	"github.com/vnkmasc/KmaERM/backend/internal/pkitest"
+)
+
+// chinhSachThu nằm dưới nhánh 2.999 dành cho ví dụ (ITU-T X.660), không dùng khi vận hành
+var chinhSachThu = asn1.ObjectIdentifier{2, 999, 1}
+
+// taoLocalTSA dựng TSA với chứng thư giống ca.EnsureTimestamping
+func taoLocalTSA(t *testing.T, ca *pkitest.ChungThu) (*LocalTSA, *pkitest.ChungThu) {
+	t.Helper()
+	tsaCert := pkitest.TSA(t, ca)
+	return NewLocalTSA(tsaCert.Signer(t), tsaCert.Cert, []*x509.Certificate{ca.Cert}, chinhSachThu), tsaCert
+}
+
+func TestLocalTSAStampVerify(t *testing.T) {
+	ca := pkitest.CA(t, "KmaERM Test CA")
+	local, tsaCert := taoLocalTSA(t, ca)
+	stamper := NewStamper(local, []*x509.Certificate{ca.Cert})
+
+	data := []byte("h1|h2|giay-phep-id")
+	truoc := time.Now().Add(-time.Second)
+	token, err := stamper.Stamp(context.Background(), data)
+	if err != nil {
+		t.Fatalf("Stamp: %v", err)
+	}
+
+	tok, err := stamper.Verify(token, data)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if tok.GenTime.Before(truoc.Truncate(time.Second)) || tok.GenTime.After(time.Now()) {
+		t.Errorf("GenTime = %s nằm ngoài thời điểm cấp", tok.GenTime)
+	}
+	if tok.Policy != chinhSachThu.String() {
+		t.Errorf("Policy = %s, muốn %s", tok.Policy, chinhSachThu)
+	}
+	if !tok.Certificate.Equal(tsaCert.Cert) {
+		t.Error("chứng thư TSA không khớp")
+	}
+
+	if _, err := stamper.Verify(token, []byte("dữ liệu khác")); !errors.Is(err, ErrDauThoiGianKhongKhop) {
+		t.Errorf("dữ liệu khác: err = %v, muốn ErrDauThoiGianKhongKhop", err)
+	}
+	khongTinCay := NewStamper(local, []*x509.Certificate{pkitest.CA(t, "CA khác").Cert})
+	if _, err := khongTinCay.Verify(token, data); !errors.Is(err, ErrTSAKhongTinCay) {
+		t.Errorf("trust anchor khác: err = %v, muốn ErrTSAKhongTinCay", err)
+	}
+	if _, err := stamper.Verify([]byte("không phải token"), data); !errors.Is(err, ErrDauThoiGianKhongHopLe) {
+		t.Errorf("token hỏng: err = %v, muốn ErrDauThoiGianKhongHopLe", err)
+	}
+	if _, err := local.Timestamp(context.Background(), []byte("ngắn")); err == nil {
+		t.Error("digest không phải SHA-256 phải bị từ chối")
+	}
+
+	digest := sha256.Sum256(data)
+	kiemTraTokenBangOpenSSL(t, token, digest[:], ca.Cert, tsaCert.Cert)
+}
+
+// Chữ ký CAdES-T: dấu thời gian trên giá trị chữ ký được nhúng vào CMS và kiểm tra lại được sau khi tách ra
+func TestCMSVoiDauThoiGian(t *testing.T) {
+	ca := pkitest.CA(t, "KmaERM Test CA")
+	local, _ := taoLocalTSA(t, ca)
+	stamper := NewStamper(local, []*x509.Certificate{ca.Cert})
+
+	nguoiKy := pkitest.NguoiKy(t, ca, "Nguyễn Văn A")
+	sgn := nguoiKy.Signer(t)
+
+	digest := sha256.Sum256([]byte("file giấy phép"))
+	der, err := cms.SignDigest(digest[:], sgn, nguoiKy.Cert, []*x509.Certificate{ca.Cert}, cms.SignOptions{
+		Timestamper: func(sig []byte) ([]byte, error) { return stamper.Stamp(context.Background(), sig) },
+	})
+	if err != nil {
+		t.Fatalf("cms.SignDigest: %v", err)
+	}
+	res, err := cms.VerifyDigest(der, digest[:])
+	if err != nil {
+		t.Fatalf("cms.VerifyDigest: %v", err)
+	}
+	if len(res.TimestampToken) == 0 {
+		t.Fatal("CMS không mang dấu thời gian")
+	}
+	if _, err := stamper.Verify(res.TimestampToken, res.Signature); err != nil {
+		t.Errorf("dấu thời gian trong CMS: %v", err)
+	}
+}
+
+// kiemTraTokenBangOpenSSL: token phải kiểm tra được bằng openssl ts -verify với CA làm trust anchor
+func kiemTraTokenBangOpenSSL(t *testing.T, token, digest []byte, ca, tsaCert *x509.Certificate) {
+	t.Helper()
+	pkitest.OpenSSL(t, map[string][]byte{
+		"token.tst": token,
+		"ca.pem":    pkitest.PEM(ca),
+		"tsa.pem":   pkitest.PEM(tsaCert),
+	}, "ts", "-verify", "-token_in",
+		"-in", "token.tst",
+		"-digest", hex.EncodeToString(digest),
+		"-CAfile", "ca.pem",
+		"-untrusted", "tsa.pem")
+}
+
+func TestPolicyFromEnv(t *testing.T) {
+	t.Setenv("TSA_POLICY_OID", "")
+	if _, err := policyFromEnv(); err == nil {
+		t.Error("thiếu TSA_POLICY_OID phải trả lỗi")
+	}
+	for v, hopLe := range map[string]bool{
+		"1.3.6.1.4.1.55555.1.1": true,
+		"2.999.1":               true,
+		"1":                     false,
+		"1.3.abc":               false,
+		"1.-3.6":                false,
+	} {
+		t.Setenv("TSA_POLICY_OID", v)
+		oid, err := policyFromEnv()
+		if hopLe && (err != nil || oid.String() != v) {
+			t.Errorf("%s: oid = %v, err = %v", v, oid, err)
+		}
+		if !hopLe && err == nil {
+			t.Errorf("%s: muốn lỗi", v)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+#!/bin/bash
+set -e  # Dừng script nếu gặp lỗi
+
+# Tạo token SoftHSM + cặp khóa RSA cho một cán bộ để thử signer PKCS#11 ở local.
+# Cần: softhsm2, opensc (pkcs11-tool)
+#
+#   ./scripts/softhsm-init.sh <nhãn-khóa>
+#
+# Sau đó đặt trong .env:
+#   PKCS11_MODULE=/usr/lib/softhsm/libsofthsm2.so
+#   PKCS11_TOKEN_LABEL=kmaerm
+#   PKCS11_PIN=123456
+# và gọi PUT /api/v1/admin/users/:id/signer {"signer_type":"pkcs11","hsm_key_label":"<nhãn-khóa>"}
+
+KEY_LABEL=${1:?"Thiếu nhãn khóa, ví dụ: ./scripts/softhsm-init.sh canbo-nguyenvana"}
+MODULE=${PKCS11_MODULE:-/usr/lib/softhsm/libsofthsm2.so}
+TOKEN_LABEL=${PKCS11_TOKEN_LABEL:-kmaerm}
+PIN=${PKCS11_PIN:-123456}
+SO_PIN=${PKCS11_SO_PIN:-12345678}
+
+if ! softhsm2-util --show-slots | grep -q "Label:.*$TOKEN_LABEL"; then
+    echo "🔐 Khởi tạo token '$TOKEN_LABEL'..."
+    softhsm2-util --init-token --free --label "$TOKEN_LABEL" --pin "$PIN" --so-pin "$SO_PIN"
+fi
+
+echo "🔑 Sinh cặp khóa RSA 2048 '$KEY_LABEL' (private key không thể xuất khỏi token)..."
+pkcs11-tool --module "$MODULE" --token-label "$TOKEN_LABEL" --login --pin "$PIN" \
+    --keypairgen --key-type rsa:2048 --label "$KEY_LABEL" --id "$(echo -n "$KEY_LABEL" | xxd -p | head -c 16)" \
+    --private --sensitive
+
+echo "✅ Xong."
@@ -1,14 +1,11 @@
 package utils
 
 import (
-	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
 	"crypto/x509"
-	"encoding/base64"
 	"encoding/pem"
 	"errors"
 	"fmt"
@@ -16,18 +13,8 @@ import (
 	"os"
 )
 
-var GlobalMasterKey = []byte("01234567890123456789012345678901")
-
 const DEFAULT_DEMO_KEY = "01234567890123456789012345678901"
 
-var FakeDB_EncryptedKEK []byte
-
-func init() {
-	realKEK := []byte("key_encryption_key_32_bytes_long")
-	encrypted, _ := EncryptAES(realKEK, GlobalMasterKey)
-	FakeDB_EncryptedKEK = encrypted
-}
-
 func GenerateRandomKey() ([]byte, error) {
 	key := make([]byte, 32)
 	if _, err := io.ReadFull(rand.Reader, key); err != nil {
@@ -75,15 +62,6 @@ func DecryptAES(encryptedData []byte, key []byte) ([]byte, error) {
 	return gcm.Open(nil, nonce, ciphertext, nil)
 }
 
-func GetKEK() ([]byte, error) {
-	encryptedKEK := FakeDB_EncryptedKEK
-	if len(encryptedKEK) == 0 {
-		return nil, errors.New("system kek not found")
-	}
-
-	return DecryptAES(encryptedKEK, GlobalMasterKey)
-}
-
 func GetSystemMasterKey() ([]byte, error) {
 
 	keyStr := os.Getenv("APP_MASTER_KEY")
@@ -101,30 +79,6 @@ func GetSystemMasterKey() ([]byte, error) {
 	return keyBytes, nil
 }
 
-func SignHash(dataHash string, privateKeyPEM string) (string, error) {
-	block, _ := pem.Decode([]byte(privateKeyPEM))
-	if block == nil {
-		return "", errors.New("private key không hợp lệ")
-	}
-	priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		return "", err
-	}
-
-	// Hash lại dataHash (để đảm bảo input an toàn cho RSA)
-	h := sha256.New()
-	h.Write([]byte(dataHash))
-	hashed := h.Sum(nil)
-
-	// Ký
-	signature, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, hashed)
-	if err != nil {
-		return "", err
-	}
-
-	return base64.StdEncoding.EncodeToString(signature), nil
-}
-
 func GenerateRSAKeyPair() (string, string, error) {
 	// 1. Sinh Private Key (2048 bit)
 	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
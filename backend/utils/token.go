@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// GenerateRefreshToken sinh refresh token ngẫu nhiên (trả cho client) và hash SHA-256 của nó (lưu DB).
+// DB chỉ giữ hash nên lộ bảng user_sessions cũng không dùng được token.
+func GenerateRefreshToken() (token string, tokenHash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken băm SHA-256 (hex) cho các bí mật ngẫu nhiên entropy cao: refresh token, mã khôi phục 2FA
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
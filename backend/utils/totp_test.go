@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// Secret SHA1 của RFC 6238 Phụ lục B ("12345678901234567890") ở dạng base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// Các vector SHA1 của RFC 6238 Phụ lục B. RFC dùng mã 8 chữ số; mã 6 chữ số là 6 chữ số cuối
+// vì cả hai cùng lấy phần dư của cùng một giá trị sau dynamic truncation.
+var rfc6238Vectors = []struct {
+	unix   int64
+	ma8So  string
+	buocTT int64
+}{
+	{unix: 59, ma8So: "94287082", buocTT: 0x1},
+	{unix: 1111111109, ma8So: "07081804", buocTT: 0x23523EC},
+	{unix: 1111111111, ma8So: "14050471", buocTT: 0x23523ED},
+	{unix: 1234567890, ma8So: "89005924", buocTT: 0x273EF07},
+	{unix: 2000000000, ma8So: "69279037", buocTT: 0x3F940AA},
+	{unix: 20000000000, ma8So: "65353130", buocTT: 0x27BC86AA},
+}
+
+func TestValidateTOTPRFC6238(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		at := time.Unix(v.unix, 0)
+		ma := v.ma8So[2:]
+
+		step, ok := ValidateTOTP(rfc6238Secret, ma, at)
+		if !ok || step != v.buocTT {
+			t.Errorf("T=%d: ValidateTOTP(%s) = %#x, %v; muốn %#x, true", v.unix, ma, step, ok, v.buocTT)
+		}
+
+		// Lệch một bước (±30s) vẫn nhận và trả về đúng bước của mã
+		if step, ok := ValidateTOTP(rfc6238Secret, ma, at.Add(totpPeriod*time.Second)); !ok || step != v.buocTT {
+			t.Errorf("T=%d+30s: = %#x, %v; muốn %#x, true", v.unix, step, ok, v.buocTT)
+		}
+		if _, ok := ValidateTOTP(rfc6238Secret, ma, at.Add(2*totpPeriod*time.Second)); ok {
+			t.Errorf("T=%d+60s: mã lệch 2 bước không được chấp nhận", v.unix)
+		}
+	}
+}
+
+func TestValidateTOTPKhongHopLe(t *testing.T) {
+	at := time.Unix(59, 0)
+	tests := []struct {
+		ten    string
+		secret string
+		ma     string
+		ok     bool
+	}{
+		{ten: "secret chữ thường, có khoảng trắng", secret: " gezdgnbvgy3tqojqgezdgnbvgy3tqojq ", ma: "287082", ok: true},
+		{ten: "sai mã", secret: rfc6238Secret, ma: "287083"},
+		{ten: "mã 8 chữ số", secret: rfc6238Secret, ma: "94287082"},
+		{ten: "mã rỗng", secret: rfc6238Secret, ma: ""},
+		{ten: "secret không phải base32", secret: "khong-phai-base32!", ma: "287082"},
+	}
+	for _, tc := range tests {
+		if _, ok := ValidateTOTP(tc.secret, tc.ma, at); ok != tc.ok {
+			t.Errorf("%s: ok = %v, muốn %v", tc.ten, ok, tc.ok)
+		}
+	}
+}